@@ -0,0 +1,51 @@
+package renderer
+
+import (
+	"path"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+// Annotations of the Application that change how it is rendered
+const (
+	// manifestGeneratePathsAnnotation lists the paths, separated by semicolons, the manifests
+	// of the Application are generated from. Absolute paths are relative to the repository root.
+	manifestGeneratePathsAnnotation = "argocd.argoproj.io/manifest-generate-paths"
+	// compareOptionsAnnotation configures how Argo CD compares the rendered resources
+	compareOptionsAnnotation = "argocd.argoproj.io/compare-options"
+)
+
+// applicationSettings are the settings of an Application that apply to all of its sources
+type applicationSettings struct {
+	IgnoreDifferences v1alpha1.IgnoreDifferences
+	ServerSideDiff    bool
+}
+
+// applyManifestGeneratePaths sets the path of a git source without one to the first path of
+// the manifest-generate-paths annotation
+func applyManifestGeneratePaths(source *v1alpha1.ApplicationSource, annotations map[string]string) {
+	if source.Path != "" || source.Chart != "" {
+		return
+	}
+
+	for _, generatePath := range strings.Split(annotations[manifestGeneratePathsAnnotation], ";") {
+		generatePath = strings.TrimSpace(generatePath)
+		if generatePath == "" {
+			continue
+		}
+		// Relative paths are relative to the source path, which is the repository root here
+		source.Path = path.Clean(strings.TrimPrefix(generatePath, "/"))
+		return
+	}
+}
+
+// serverSideDiffEnabled returns true if the compare-options annotation enables server-side diff
+func serverSideDiffEnabled(annotations map[string]string) bool {
+	for _, option := range strings.Split(annotations[compareOptionsAnnotation], ",") {
+		if strings.TrimSpace(option) == "ServerSideDiff=true" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package renderer
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestTemplateFromApplicationManifestGeneratePaths(t *testing.T) {
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+  annotations:
+    argocd.argoproj.io/manifest-generate-paths: /examples/directory/input;/examples/helm/input
+    argocd.argoproj.io/compare-options: IgnoreExtraneous,ServerSideDiff=true
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`)
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	SortManifests(result.Objects)
+	expected := []string{"Service/default/guestbook-ui", "Deployment/default/guestbook-ui"}
+	if !slices.Equal(objectKeys(result.Objects), expected) {
+		t.Errorf("Expected the manifests of the first generate path %v, got %v", expected, objectKeys(result.Objects))
+	}
+	if !result.ServerSideDiff {
+		t.Error("Expected server-side diff to be enabled by the compare-options annotation")
+	}
+}
@@ -0,0 +1,229 @@
+package renderer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+// invalidNameChars matches the characters that are not allowed in Kubernetes resource names
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]`)
+
+// templateParam matches a parameter reference in a template, {{key}} or {{ key }}
+var templateParam = regexp.MustCompile(`\{\{ ?([^{} ]+) ?\}\}`)
+
+// RenderApplicationSet renders every Application generated by the first generator of the
+// ApplicationSet. The results are in the order of the generated parameters.
+func RenderApplicationSet(ctx context.Context, appSetFile string, repoRoot string) ([]*TemplateResult, error) {
+	data, err := os.ReadFile(appSetFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading ApplicationSet file: %w", err)
+	}
+
+	var appSet v1alpha1.ApplicationSet
+	if err := yaml.Unmarshal(data, &appSet); err != nil {
+		return nil, fmt.Errorf("error parsing ApplicationSet: %w", err)
+	}
+	if appSet.Kind != "ApplicationSet" {
+		return nil, fmt.Errorf("expected kind ApplicationSet, got %s", appSet.Kind)
+	}
+	if appSet.Spec.GoTemplate {
+		return nil, fmt.Errorf("ApplicationSets with goTemplate are not supported")
+	}
+	if len(appSet.Spec.Generators) == 0 {
+		return nil, fmt.Errorf("ApplicationSet %s has no generators", appSet.Name)
+	}
+
+	params, err := generateParams(appSet.Spec.Generators[0], repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("error generating parameters for ApplicationSet %s: %w", appSet.Name, err)
+	}
+
+	template, err := json.Marshal(appSet.Spec.Template)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling ApplicationSet template: %w", err)
+	}
+
+	var results []*TemplateResult
+	for i, param := range params {
+		application, err := renderApplicationTemplate(template, param)
+		if err != nil {
+			return nil, fmt.Errorf("error rendering Application %d of ApplicationSet %s: %w", i, appSet.Name, err)
+		}
+
+		result, err := TemplateFromApplicationYAML(ctx, string(application), repoRoot)
+		if err != nil {
+			return nil, fmt.Errorf("error templating Application %d of ApplicationSet %s: %w", i, appSet.Name, err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// generateParams returns the template parameters of each Application the generator produces.
+// The git generator scans the local repository instead of cloning its repoURL.
+func generateParams(generator v1alpha1.ApplicationSetGenerator, repoRoot string) ([]map[string]string, error) {
+	switch {
+	case generator.List != nil:
+		return listGeneratorParams(generator.List)
+	case generator.Git != nil && len(generator.Git.Directories) > 0:
+		return gitDirectoryGeneratorParams(generator.Git, repoRoot)
+	default:
+		return nil, fmt.Errorf("unsupported generator, only the list and git directory generators are supported")
+	}
+}
+
+// listGeneratorParams returns the flattened parameters of each list element
+func listGeneratorParams(generator *v1alpha1.ListGenerator) ([]map[string]string, error) {
+	var params []map[string]string
+	for i, element := range generator.Elements {
+		var values map[string]interface{}
+		if err := json.Unmarshal(element.Raw, &values); err != nil {
+			return nil, fmt.Errorf("error parsing list element %d: %w", i, err)
+		}
+
+		param := map[string]string{}
+		if err := flattenParams(param, "", values); err != nil {
+			return nil, fmt.Errorf("error parsing list element %d: %w", i, err)
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// gitDirectoryGeneratorParams returns the parameters of each directory in the repository
+// matching the generator's directory patterns, in lexical order
+func gitDirectoryGeneratorParams(generator *v1alpha1.GitGenerator, repoRoot string) ([]map[string]string, error) {
+	if repoRoot == "" {
+		repoRoot = "."
+	}
+
+	var dirs []string
+	err := filepath.WalkDir(repoRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		relPath, err := filepath.Rel(repoRoot, path)
+		if err != nil {
+			return err
+		}
+		if relPath != "." && matchesDirectories(generator.Directories, filepath.ToSlash(relPath)) {
+			dirs = append(dirs, filepath.ToSlash(relPath))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning repository directories: %w", err)
+	}
+
+	pathPrefix := "path"
+	if generator.PathParamPrefix != "" {
+		pathPrefix = generator.PathParamPrefix + ".path"
+	}
+
+	var params []map[string]string
+	for _, dir := range dirs {
+		param := map[string]string{
+			pathPrefix:                         dir,
+			pathPrefix + ".basename":           path.Base(dir),
+			pathPrefix + ".basenameNormalized": normalizeName(path.Base(dir)),
+		}
+		for i, segment := range strings.Split(dir, "/") {
+			param[fmt.Sprintf("%s[%d]", pathPrefix, i)] = segment
+		}
+		for key, value := range generator.Values {
+			param["values."+key] = value
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// matchesDirectories reports whether the directory matches an included pattern and no excluded one
+func matchesDirectories(items []v1alpha1.GitDirectoryGeneratorItem, dir string) bool {
+	included := false
+	for _, item := range items {
+		if matched, _ := path.Match(item.Path, dir); !matched {
+			continue
+		}
+		if item.Exclude {
+			return false
+		}
+		included = true
+	}
+	return included
+}
+
+// normalizeName converts the name into a valid Kubernetes resource name, like Argo CD's path.basenameNormalized
+func normalizeName(name string) string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+}
+
+// flattenParams adds the values to params, joining the keys of nested objects with dots.
+// Lists have no string form in templates and are rejected.
+func flattenParams(params map[string]string, prefix string, values map[string]interface{}) error {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := flattenParams(params, key, v); err != nil {
+				return err
+			}
+		case []interface{}:
+			return fmt.Errorf("parameter %s is a list, which is not supported", key)
+		case float64:
+			// Formatted without an exponent, so 1000000 stays 1000000
+			params[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case nil:
+			params[key] = ""
+		default:
+			params[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+// renderApplicationTemplate substitutes the parameters into the JSON template and returns the
+// Application. The template is substituted in a single pass, so parameters in the values are
+// not substituted again, and unknown parameters are kept.
+func renderApplicationTemplate(template []byte, params map[string]string) ([]byte, error) {
+	rendered := templateParam.ReplaceAllStringFunc(string(template), func(match string) string {
+		value, found := params[templateParam.FindStringSubmatch(match)[1]]
+		if !found {
+			return match
+		}
+		// The values are substituted into JSON strings, so they have to be escaped. Marshaling
+		// a string cannot fail.
+		escaped, _ := json.Marshal(value)
+		return string(escaped[1 : len(escaped)-1])
+	})
+
+	var application map[string]interface{}
+	if err := json.Unmarshal([]byte(rendered), &application); err != nil {
+		return nil, fmt.Errorf("error parsing rendered template: %w", err)
+	}
+	application["apiVersion"] = "argoproj.io/v1alpha1"
+	application["kind"] = "Application"
+
+	return json.Marshal(application)
+}
@@ -0,0 +1,160 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+func TestRenderApplicationSetList(t *testing.T) {
+	results, err := RenderApplicationSet(context.Background(), "examples/applicationset-list/applicationset.yaml", ".")
+	if err != nil {
+		t.Fatalf("RenderApplicationSet failed: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	for i, result := range results {
+		compareGolden(t, fmt.Sprintf("examples/applicationset-list/expected-%d.yaml", i), formatOutput(result))
+	}
+}
+
+// compareGolden compares the output with the golden file, creating it if it does not exist
+func compareGolden(t *testing.T, expectedPath, output string) {
+	t.Helper()
+
+	expectedBytes, err := os.ReadFile(expectedPath)
+	if os.IsNotExist(err) {
+		if err := os.WriteFile(expectedPath, []byte(output), 0644); err != nil {
+			t.Fatalf("Failed to write expected output: %v", err)
+		}
+		t.Logf("Created golden file: %s", expectedPath)
+		return
+	}
+	if err != nil {
+		t.Fatalf("Failed to read expected output: %v", err)
+	}
+
+	if strings.TrimSpace(output) != strings.TrimSpace(string(expectedBytes)) {
+		dmp := diffmatchpatch.New()
+		diffs := dmp.DiffMain(string(expectedBytes), output, false)
+		t.Errorf("Output does not match golden file %s\nDiff:\n%s", expectedPath, dmp.DiffPrettyText(diffs))
+	}
+}
+
+func TestRenderApplicationSetGitDirectories(t *testing.T) {
+	repoRoot := t.TempDir()
+	for _, dir := range []string{"apps/frontend", "apps/backend", "apps/Payment_Service", "apps/ignored"} {
+		if err := os.MkdirAll(filepath.Join(repoRoot, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		manifest := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"
+		if err := os.WriteFile(filepath.Join(repoRoot, dir, "configmap.yaml"), []byte(manifest), 0644); err != nil {
+			t.Fatalf("Failed to write manifest: %v", err)
+		}
+	}
+
+	appSet := `apiVersion: argoproj.io/v1alpha1
+kind: ApplicationSet
+metadata:
+  name: apps
+spec:
+  generators:
+  - git:
+      repoURL: https://github.com/myorg/myrepo
+      revision: HEAD
+      directories:
+      - path: apps/*
+      - path: apps/ignored
+        exclude: true
+  template:
+    metadata:
+      name: '{{path.basenameNormalized}}'
+    spec:
+      project: default
+      source:
+        repoURL: https://github.com/myorg/myrepo
+        path: '{{path}}'
+      destination:
+        server: https://kubernetes.default.svc
+        namespace: '{{path.basename}}'
+`
+	if err := os.WriteFile(filepath.Join(repoRoot, "applicationset.yaml"), []byte(appSet), 0644); err != nil {
+		t.Fatalf("Failed to write ApplicationSet: %v", err)
+	}
+	t.Chdir(repoRoot)
+
+	results, err := RenderApplicationSet(context.Background(), "applicationset.yaml", ".")
+	if err != nil {
+		t.Fatalf("RenderApplicationSet failed: %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	expected := []struct{ instance, namespace string }{
+		{instance: "payment-service", namespace: "Payment_Service"},
+		{instance: "backend", namespace: "backend"},
+		{instance: "frontend", namespace: "frontend"},
+	}
+	for i, result := range results {
+		if len(result.Objects) != 1 {
+			t.Fatalf("Result %d: expected 1 object, got %d", i, len(result.Objects))
+		}
+		obj := result.Objects[0]
+		if instance := obj.GetLabels()["app.kubernetes.io/instance"]; instance != expected[i].instance {
+			t.Errorf("Result %d: expected instance %s, got %s", i, expected[i].instance, instance)
+		}
+		if obj.GetNamespace() != expected[i].namespace {
+			t.Errorf("Result %d: expected namespace %s, got %s", i, expected[i].namespace, obj.GetNamespace())
+		}
+	}
+}
+
+func TestFlattenParams(t *testing.T) {
+	params := map[string]string{}
+	values := map[string]interface{}{
+		"replicas": float64(1000000),
+		"ratio":    0.5,
+		"enabled":  true,
+		"empty":    nil,
+		"cluster":  map[string]interface{}{"name": "prod"},
+	}
+	if err := flattenParams(params, "", values); err != nil {
+		t.Fatalf("flattenParams failed: %v", err)
+	}
+	expected := map[string]string{"replicas": "1000000", "ratio": "0.5", "enabled": "true", "empty": "", "cluster.name": "prod"}
+	if fmt.Sprint(params) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, params)
+	}
+
+	err := flattenParams(map[string]string{}, "", map[string]interface{}{"cluster": map[string]interface{}{"zones": []interface{}{"a", "b"}}})
+	if err == nil || !strings.Contains(err.Error(), "parameter cluster.zones is a list") {
+		t.Errorf("Expected lists to be rejected, got %v", err)
+	}
+}
+
+func TestRenderApplicationTemplateSinglePass(t *testing.T) {
+	template := []byte(`{"metadata":{"name":"{{name}}","labels":{"a":"{{ first }}","b":"{{second}}","c":"{{unknown}}"}}}`)
+	params := map[string]string{"name": "app", "first": "{{second}}", "second": "{{first}}"}
+
+	// Map iteration order differs between runs, so render repeatedly
+	for range 20 {
+		data, err := renderApplicationTemplate(template, params)
+		if err != nil {
+			t.Fatalf("renderApplicationTemplate failed: %v", err)
+		}
+		expected := `{"apiVersion":"argoproj.io/v1alpha1","kind":"Application","metadata":{"labels":{"a":"{{second}}","b":"{{first}}","c":"{{unknown}}"},"name":"app"}}`
+		if string(data) != expected {
+			t.Fatalf("Expected the values to be substituted once:\n%s\ngot:\n%s", expected, data)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package renderer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultApplicationURLTimeout is how long fetching an Application from a URL may take
+const DefaultApplicationURLTimeout = 30 * time.Second
+
+// inClusterHost is the host of the Kubernetes API server from inside a Pod
+const inClusterHost = "kubernetes.default.svc"
+
+// serviceAccountDir contains the token and CA certificate of the Pod's service account,
+// replaceable in tests
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// IsApplicationURL reports whether the Application location is an http or https URL
+func IsApplicationURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// fetchApplication downloads an Application as YAML or JSON. Requests to the in-cluster
+// API server are authenticated with the service account token of the Pod.
+func fetchApplication(ctx context.Context, applicationURL string, timeout time.Duration) ([]byte, error) {
+	if timeout == 0 {
+		timeout = DefaultApplicationURLTimeout
+	}
+
+	parsed, err := url.Parse(applicationURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid application URL: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, applicationURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/yaml, application/json")
+
+	client := &http.Client{}
+	if parsed.Scheme == "https" && parsed.Hostname() == inClusterHost {
+		transport, err := inClusterTransport(req)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = transport
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch application: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch application: %s", resp.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "application/json":
+	default:
+		return nil, fmt.Errorf("unexpected content type %q, expected application/yaml or application/json", mediaType)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read application: %w", err)
+	}
+	return data, nil
+}
+
+// inClusterTransport adds the service account token to the request and returns a transport
+// that trusts the cluster CA
+func inClusterTransport(req *http.Request) (*http.Transport, error) {
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if ca, err := os.ReadFile(serviceAccountDir + "/ca.crt"); err == nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return transport, nil
+}
@@ -0,0 +1,97 @@
+package renderer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const applicationURLYAML = `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`
+
+func TestTemplateFromApplicationURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+		w.Write([]byte(applicationURLYAML))
+	}))
+	defer server.Close()
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationURL: server.URL + "/apis/argoproj.io/v1alpha1/namespaces/argocd/applications/guestbook",
+		RepoRoot:       ".",
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if len(result.Objects) != 2 {
+		t.Errorf("Expected 2 objects, got %d", len(result.Objects))
+	}
+}
+
+func TestFetchApplicationErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	if _, err := fetchApplication(context.Background(), server.URL+"/html", 0); err == nil {
+		t.Error("Expected an error for an unexpected content type")
+	}
+	if _, err := fetchApplication(context.Background(), server.URL+"/missing", 0); err == nil {
+		t.Error("Expected an error for a missing application")
+	}
+}
+
+func TestInClusterTransport(t *testing.T) {
+	serviceAccountDir = t.TempDir()
+	t.Cleanup(func() { serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount" })
+
+	req, _ := http.NewRequest(http.MethodGet, "https://kubernetes.default.svc/apis", nil)
+	if _, err := inClusterTransport(req); err == nil {
+		t.Error("Expected an error without a service account token")
+	}
+
+	if err := os.WriteFile(filepath.Join(serviceAccountDir, "token"), []byte("secret-token\n"), 0600); err != nil {
+		t.Fatalf("Failed to write token: %v", err)
+	}
+	if _, err := inClusterTransport(req); err != nil {
+		t.Fatalf("inClusterTransport failed: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer secret-token" {
+		t.Errorf("Expected the service account token, got %q", got)
+	}
+}
+
+func TestIsApplicationURL(t *testing.T) {
+	for location, expected := range map[string]bool{
+		"https://kubernetes.default.svc/apis": true,
+		"http://localhost:8080/app.yaml":      true,
+		"app.yaml":                            false,
+		"-":                                   false,
+	} {
+		if got := IsApplicationURL(location); got != expected {
+			t.Errorf("IsApplicationURL(%q) = %v, expected %v", location, got, expected)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package renderer
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// binaryInstallURLs are shown when a required binary is missing
+var binaryInstallURLs = map[string]string{
+	"helm":      "https://helm.sh",
+	"kustomize": "https://kustomize.io",
+	"kubectl":   "https://kubernetes.io/docs/tasks/tools",
+	"sops":      "https://getsops.io",
+}
+
+// checkBinaryExists returns an error explaining how to install the binary if it is not in PATH.
+// The error wraps exec.ErrNotFound.
+func checkBinaryExists(name string) error {
+	_, err := exec.LookPath(name)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		if url, ok := binaryInstallURLs[name]; ok {
+			return fmt.Errorf("%s binary not found in PATH; install %s from %s: %w", name, name, url, exec.ErrNotFound)
+		}
+		return fmt.Errorf("%s binary not found in PATH: %w", name, exec.ErrNotFound)
+	}
+	return fmt.Errorf("failed to look up %s binary: %w", name, err)
+}
@@ -0,0 +1,60 @@
+package renderer
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCheckBinaryExists(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	tests := map[string]string{
+		"helm":      "helm binary not found in PATH; install helm from https://helm.sh",
+		"kustomize": "kustomize binary not found in PATH; install kustomize from https://kustomize.io",
+		"other":     "other binary not found in PATH",
+	}
+	for name, expected := range tests {
+		err := checkBinaryExists(name)
+		if err == nil {
+			t.Fatalf("Expected an error for %s", name)
+		}
+		if !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("Expected error starting with %q, got %q", expected, err.Error())
+		}
+		if !errors.Is(err, exec.ErrNotFound) {
+			t.Errorf("Expected error for %s to wrap exec.ErrNotFound", name)
+		}
+	}
+}
+
+func TestTemplateFromApplicationMissingBinaries(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	tests := map[string]string{
+		"examples/helm/app.yaml":      "helm binary not found in PATH",
+		"examples/kustomize/app.yaml": "kustomize binary not found in PATH",
+	}
+	for appFile, expected := range tests {
+		_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+			ApplicationFile: appFile,
+			RepoRoot:        ".",
+		})
+		if err == nil {
+			t.Fatalf("Expected an error for %s", appFile)
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q for %s, got %v", expected, appFile, err)
+		}
+	}
+
+	// Directory sources do not need any binary
+	if _, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/directory/app.yaml",
+		RepoRoot:        ".",
+	}); err != nil {
+		t.Errorf("Expected directory sources to render without binaries, got %v", err)
+	}
+}
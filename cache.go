@@ -0,0 +1,174 @@
+package renderer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultCacheTTL is the time a downloaded Helm chart is reused before it is downloaded again
+const DefaultCacheTTL = 24 * time.Hour
+
+// ClearHelmCache removes all downloaded Helm charts and their checksums from the cache. An
+// empty cacheDir uses the same default location as TemplateOptions.CacheDir. Only the cache
+// entries are removed, so a cache directory shared with other files is safe to clear.
+func ClearHelmCache(cacheDir string) error {
+	helmCacheDir, err := resolveCacheDir(cacheDir)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(helmCacheDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read helm cache directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !isCacheEntry(entry) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(helmCacheDir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to remove cached chart %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// isCacheEntry reports whether the directory entry is a downloaded chart or its checksum,
+// named after the hex encoded SHA-256 of chartCacheKey
+func isCacheEntry(entry fs.DirEntry) bool {
+	name := entry.Name()
+	if !entry.IsDir() {
+		var found bool
+		if name, found = strings.CutSuffix(name, ".sha256"); !found || !entry.Type().IsRegular() {
+			return false
+		}
+	}
+	hash, found := strings.CutPrefix(name, "chart-")
+	if !found || len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// CachedChart is a Helm chart in the download cache
+type CachedChart struct {
+	// Hash identifies the repository, chart and version the chart was downloaded for
+	Hash    string
+	Name    string
+	Version string
+	// CachedAt is when the chart was downloaded
+	CachedAt  time.Time
+	SizeBytes int64
+}
+
+// ListCachedCharts returns the downloaded Helm charts in the cache, sorted by hash. An empty
+// cacheDir uses the same default location as TemplateOptions.CacheDir. The name and version
+// are empty if the chart has no readable Chart.yaml.
+func ListCachedCharts(cacheDir string) ([]CachedChart, error) {
+	helmCacheDir, err := resolveCacheDir(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(helmCacheDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read helm cache directory: %w", err)
+	}
+
+	var charts []CachedChart
+	for _, entry := range entries {
+		hash, found := strings.CutPrefix(entry.Name(), "chart-")
+		if !found || !entry.IsDir() {
+			continue
+		}
+		chartDir := filepath.Join(helmCacheDir, entry.Name())
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cached chart %s: %w", hash, err)
+		}
+		size, err := directorySize(chartDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cached chart %s: %w", hash, err)
+		}
+
+		chart := CachedChart{Hash: hash, CachedAt: info.ModTime(), SizeBytes: size}
+		if data, err := os.ReadFile(filepath.Join(chartDir, "Chart.yaml")); err == nil {
+			var metadata struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			}
+			if yaml.Unmarshal(data, &metadata) == nil {
+				chart.Name, chart.Version = metadata.Name, metadata.Version
+			}
+		}
+		charts = append(charts, chart)
+	}
+	return charts, nil
+}
+
+// directorySize returns the combined size of the files in the directory
+func directorySize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// resolveCacheDir returns the directory downloaded Helm charts are stored in. The precedence is
+// the given directory, the LOCAL_ARGOCD_RENDERER_CACHE_DIR environment variable and finally the
+// local-argocd-renderer subdirectory of the XDG cache directory.
+func resolveCacheDir(cacheDir string) (string, error) {
+	if cacheDir != "" {
+		return cacheDir, nil
+	}
+
+	if cacheDir := os.Getenv("LOCAL_ARGOCD_RENDERER_CACHE_DIR"); cacheDir != "" {
+		return cacheDir, nil
+	}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cache directory: %w", err)
+	}
+
+	return filepath.Join(cacheDir, "local-argocd-renderer"), nil
+}
+
+// getCacheDir returns the XDG cache directory
+func getCacheDir() (string, error) {
+	if cacheDir := os.Getenv("XDG_CACHE_HOME"); cacheDir != "" {
+		return cacheDir, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, ".cache"), nil
+}
@@ -0,0 +1,136 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClearHelmCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+	t.Setenv("LOCAL_ARGOCD_RENDERER_CACHE_DIR", "")
+
+	chartDir := filepath.Join(cacheDir, "local-argocd-renderer", "chart-"+strings.Repeat("ab", 32))
+	if err := os.MkdirAll(chartDir, 0755); err != nil {
+		t.Fatalf("Failed to create cached chart: %v", err)
+	}
+
+	if err := ClearHelmCache(""); err != nil {
+		t.Fatalf("ClearHelmCache failed: %v", err)
+	}
+
+	if _, err := os.Stat(chartDir); !os.IsNotExist(err) {
+		t.Errorf("Expected cached chart to be removed, got %v", err)
+	}
+
+	// Clearing an empty cache is not an error
+	if err := ClearHelmCache(""); err != nil {
+		t.Errorf("ClearHelmCache on empty cache failed: %v", err)
+	}
+}
+
+func TestClearHelmCacheKeepsOtherFiles(t *testing.T) {
+	// The cache directory can be any directory, e.g. the home directory with --cache-dir ~
+	cacheDir := t.TempDir()
+	chart := "chart-" + strings.Repeat("ab", 32)
+	for _, dir := range []string{chart, "projects", "chart-notes"} {
+		if err := os.MkdirAll(filepath.Join(cacheDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	for _, file := range []string{chart + ".sha256", ".bashrc", "chart.yaml", "projects/app.yaml"} {
+		if err := os.WriteFile(filepath.Join(cacheDir, file), nil, 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	if err := ClearHelmCache(cacheDir); err != nil {
+		t.Fatalf("ClearHelmCache failed: %v", err)
+	}
+
+	for _, removed := range []string{chart, chart + ".sha256"} {
+		if _, err := os.Stat(filepath.Join(cacheDir, removed)); !os.IsNotExist(err) {
+			t.Errorf("Expected cache entry %s to be removed, got %v", removed, err)
+		}
+	}
+	for _, kept := range []string{".bashrc", "chart.yaml", "chart-notes", "projects/app.yaml"} {
+		if _, err := os.Stat(filepath.Join(cacheDir, kept)); err != nil {
+			t.Errorf("Expected %s to survive clearing the cache: %v", kept, err)
+		}
+	}
+}
+
+func TestResolveCacheDir(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "/xdg")
+	t.Setenv("LOCAL_ARGOCD_RENDERER_CACHE_DIR", "")
+
+	dir, err := resolveCacheDir("")
+	if err != nil {
+		t.Fatalf("resolveCacheDir failed: %v", err)
+	}
+	if expected := filepath.Join("/xdg", "local-argocd-renderer"); dir != expected {
+		t.Errorf("Expected %s, got %s", expected, dir)
+	}
+
+	t.Setenv("LOCAL_ARGOCD_RENDERER_CACHE_DIR", "/env")
+	if dir, _ := resolveCacheDir(""); dir != "/env" {
+		t.Errorf("Expected environment variable to take precedence over XDG_CACHE_HOME, got %s", dir)
+	}
+
+	if dir, _ := resolveCacheDir("/option"); dir != "/option" {
+		t.Errorf("Expected option to take precedence over environment variable, got %s", dir)
+	}
+}
+
+func TestListCachedCharts(t *testing.T) {
+	cacheDir := t.TempDir()
+	cachedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for hash, chartYAML := range map[string]string{
+		"aaa": "apiVersion: v2\nname: nginx\nversion: 1.2.3\n",
+		"bbb": "apiVersion: v2\nname: redis\nversion: 18.0.0\n",
+	} {
+		chartDir := filepath.Join(cacheDir, "chart-"+hash)
+		if err := os.MkdirAll(filepath.Join(chartDir, "templates"), 0755); err != nil {
+			t.Fatalf("Failed to create cached chart: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(chartYAML), 0644); err != nil {
+			t.Fatalf("Failed to write Chart.yaml: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(chartDir, "templates", "deployment.yaml"), []byte("kind: Deployment\n"), 0644); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+		if err := os.WriteFile(chartDir+".sha256", []byte("checksum\n"), 0644); err != nil {
+			t.Fatalf("Failed to write checksum: %v", err)
+		}
+		if err := os.Chtimes(chartDir, cachedAt, cachedAt); err != nil {
+			t.Fatalf("Failed to set download time: %v", err)
+		}
+	}
+
+	charts, err := ListCachedCharts(cacheDir)
+	if err != nil {
+		t.Fatalf("ListCachedCharts failed: %v", err)
+	}
+	if len(charts) != 2 {
+		t.Fatalf("Expected 2 cached charts, got %+v", charts)
+	}
+
+	expected := []CachedChart{
+		{Hash: "aaa", Name: "nginx", Version: "1.2.3", CachedAt: cachedAt, SizeBytes: int64(len("apiVersion: v2\nname: nginx\nversion: 1.2.3\n") + len("kind: Deployment\n"))},
+		{Hash: "bbb", Name: "redis", Version: "18.0.0", CachedAt: cachedAt, SizeBytes: int64(len("apiVersion: v2\nname: redis\nversion: 18.0.0\n") + len("kind: Deployment\n"))},
+	}
+	for i, chart := range charts {
+		if chart.Hash != expected[i].Hash || chart.Name != expected[i].Name || chart.Version != expected[i].Version ||
+			!chart.CachedAt.Equal(expected[i].CachedAt) || chart.SizeBytes != expected[i].SizeBytes {
+			t.Errorf("Expected %+v, got %+v", expected[i], chart)
+		}
+	}
+
+	// A missing cache is empty
+	if charts, err := ListCachedCharts(filepath.Join(cacheDir, "missing")); err != nil || len(charts) != 0 {
+		t.Errorf("Expected no charts for a missing cache, got %v, %v", charts, err)
+	}
+}
@@ -0,0 +1,60 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application"
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	appclientset "github.com/argoproj/argo-cd/v3/pkg/client/clientset/versioned"
+)
+
+// ClusterAppRef is an Application in a cluster
+type ClusterAppRef struct {
+	Name      string
+	Namespace string
+	// KubeconfigPath is the kubeconfig used to read the Application, the default loading
+	// rules are used if empty
+	KubeconfigPath string
+}
+
+// newApplicationClient returns a client for the Applications in the cluster of the kubeconfig,
+// replaceable in tests
+var newApplicationClient = func(kubeconfigPath string) (appclientset.Interface, error) {
+	config, err := loadKubeconfig(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+	client, err := appclientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Application client: %w", err)
+	}
+	return client, nil
+}
+
+// fetchClusterApplication reads the Application from the cluster and returns it as YAML
+func fetchClusterApplication(ctx context.Context, ref ClusterAppRef) ([]byte, error) {
+	client, err := newApplicationClient(ref.KubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+	app, err := client.ArgoprojV1alpha1().Applications(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get application %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+
+	// Typed clients do not set the type of the objects they return
+	app.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: application.ApplicationKind}
+	// The status and the fields managed by the cluster are not part of the Application manifest
+	app.Status = v1alpha1.ApplicationStatus{}
+	app.ManagedFields = nil
+
+	data, err := yaml.Marshal(app)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal application %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,62 @@
+package renderer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	appclientset "github.com/argoproj/argo-cd/v3/pkg/client/clientset/versioned"
+	"github.com/argoproj/argo-cd/v3/pkg/client/clientset/versioned/fake"
+)
+
+func TestTemplateFromApplicationClusterApp(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "guestbook", Namespace: "argocd", ResourceVersion: "42"},
+		Spec: v1alpha1.ApplicationSpec{
+			Source: &v1alpha1.ApplicationSource{
+				RepoURL: "https://github.com/myorg/myrepo",
+				Path:    "examples/directory/input",
+			},
+			Destination: v1alpha1.ApplicationDestination{Namespace: "guestbook"},
+		},
+		Status: v1alpha1.ApplicationStatus{Sync: v1alpha1.SyncStatus{Status: v1alpha1.SyncStatusCodeSynced}},
+	})
+	var kubeconfigPath string
+	original := newApplicationClient
+	t.Cleanup(func() { newApplicationClient = original })
+	newApplicationClient = func(path string) (appclientset.Interface, error) {
+		kubeconfigPath = path
+		return client, nil
+	}
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ClusterApp: ClusterAppRef{Name: "guestbook", Namespace: "argocd", KubeconfigPath: "/tmp/kubeconfig"},
+		RepoRoot:   ".",
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if kubeconfigPath != "/tmp/kubeconfig" {
+		t.Errorf("Expected the kubeconfig of the reference to be used, got %q", kubeconfigPath)
+	}
+	if len(result.Objects) != 2 {
+		t.Fatalf("Expected 2 objects, got %d", len(result.Objects))
+	}
+	for _, obj := range result.Objects {
+		if obj.GetLabels()["app.kubernetes.io/instance"] != "guestbook" {
+			t.Errorf("Expected the tracking label of the cluster Application, got %v", obj.GetLabels())
+		}
+	}
+
+	_, err = TemplateFromApplication(context.Background(), TemplateOptions{
+		ClusterApp: ClusterAppRef{Name: "missing", Namespace: "argocd"},
+		RepoRoot:   ".",
+	})
+	renderErr, ok := AsRenderError(err)
+	if !ok || renderErr.Phase != RenderPhaseFetch || !strings.Contains(err.Error(), "failed to get application argocd/missing") {
+		t.Errorf("Expected a fetch error for the missing Application, got %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"sigs.k8s.io/yaml"
+)
+
+// defaultConfigFile is read from the current directory when no --config flag is given
+const defaultConfigFile = ".local-argocd-renderer.yaml"
+
+// Config is the content of the config file. Its options are used for flags that are
+// not set on the command line.
+type Config struct {
+	Application                     string   `json:"application,omitempty"`
+	AppFromCluster                  string   `json:"appFromCluster,omitempty"`
+	RepoRoot                        string   `json:"repoRoot,omitempty"`
+	RepoRootMarker                  string   `json:"repoRootMarker,omitempty"`
+	MaxManifestSize                 string   `json:"maxManifestSize,omitempty"`
+	HelmSet                         []string `json:"helmSet,omitempty"`
+	HelmSetJSON                     []string `json:"helmSetJSON,omitempty"`
+	HelmSetLiteral                  []string `json:"helmSetLiteral,omitempty"`
+	HelmSetFile                     []string `json:"helmSetFile,omitempty"`
+	HelmExpandEnv                   *bool    `json:"helmExpandEnv,omitempty"`
+	HelmSOPSDecrypt                 *bool    `json:"helmSopsDecrypt,omitempty"`
+	HelmBinaryPath                  string   `json:"helmBinaryPath,omitempty"`
+	HelmValuesFiles                 []string `json:"helmValuesFiles,omitempty"`
+	HelmUpdateDeps                  *bool    `json:"helmUpdateDeps,omitempty"`
+	HelmPostRenderer                string   `json:"helmPostRenderer,omitempty"`
+	HelmPostRendererArgs            []string `json:"helmPostRendererArgs,omitempty"`
+	HelmLint                        *bool    `json:"helmLint,omitempty"`
+	HelmAPIVersions                 []string `json:"helmAPIVersions,omitempty"`
+	HelmIncludeCrds                 *bool    `json:"helmIncludeCrds,omitempty"`
+	HelmGenerateName                *bool    `json:"helmGenerateName,omitempty"`
+	HelmForceString                 *bool    `json:"helmForceString,omitempty"`
+	KustomizeEnableAlphaPlugins     *bool    `json:"kustomizeEnableAlphaPlugins,omitempty"`
+	KustomizeEnableExec             *bool    `json:"kustomizeEnableExec,omitempty"`
+	KustomizeLoadRestrictor         string   `json:"kustomizeLoadRestrictor,omitempty"`
+	KustomizeReorder                string   `json:"kustomizeReorder,omitempty"`
+	KustomizeEnableHelm             *bool    `json:"kustomizeEnableHelm,omitempty"`
+	KustomizeHelmCommand            string   `json:"kustomizeHelmCommand,omitempty"`
+	KustomizeForceCommonLabels      []string `json:"kustomizeForceCommonLabels,omitempty"`
+	KustomizeForceCommonAnnotations []string `json:"kustomizeForceCommonAnnotations,omitempty"`
+	Directory                       string   `json:"directory,omitempty"`
+	DirectoryRecurse                *bool    `json:"directoryRecurse,omitempty"`
+	DirectoryInclude                string   `json:"directoryInclude,omitempty"`
+	DirectoryExclude                string   `json:"directoryExclude,omitempty"`
+	DirectoryMaxDepth               *int     `json:"directoryMaxDepth,omitempty"`
+	JsonnetExtVars                  []string `json:"jsonnetExtVars,omitempty"`
+	JsonnetTLAs                     []string `json:"jsonnetTLAs,omitempty"`
+	NamespaceOverride               string   `json:"namespaceOverride,omitempty"`
+	InjectLabels                    []string `json:"injectLabels,omitempty"`
+	InjectAnnotations               []string `json:"injectAnnotations,omitempty"`
+	InjectLabelOverwrite            *bool    `json:"injectLabelOverwrite,omitempty"`
+	OutputFormat                    string   `json:"outputFormat,omitempty"`
+	OutputDir                       string   `json:"outputDir,omitempty"`
+	OutputNaming                    string   `json:"outputNaming,omitempty"`
+	ExportKustomization             string   `json:"exportKustomization,omitempty"`
+	DiffAgainst                     string   `json:"diffAgainst,omitempty"`
+	PrintStats                      *bool    `json:"printStats,omitempty"`
+	KubeVersion                     string   `json:"kubeVersion,omitempty"`
+	AutoKubeVersion                 *bool    `json:"autoKubeVersion,omitempty"`
+	Kubeconfig                      string   `json:"kubeconfig,omitempty"`
+	StripManagedFields              *bool    `json:"stripManagedFields,omitempty"`
+	StripClusterFields              *bool    `json:"stripClusterFields,omitempty"`
+	CacheTTL                        string   `json:"cacheTTL,omitempty"`
+	CacheDir                        string   `json:"cacheDir,omitempty"`
+	VerifyCache                     *bool    `json:"verifyCache,omitempty"`
+	Concurrency                     *int     `json:"concurrency,omitempty"`
+	Timeout                         string   `json:"timeout,omitempty"`
+	HelmPullRetries                 *int     `json:"helmPullRetries,omitempty"`
+	Validate                        *bool    `json:"validate,omitempty"`
+	SchemaDir                       string   `json:"schemaDir,omitempty"`
+	KubeconformValidate             *bool    `json:"kubeconformValidate,omitempty"`
+	KubeconformStrict               *bool    `json:"kubeconformStrict,omitempty"`
+	KubeconformKubernetesVersion    string   `json:"kubeconformKubernetesVersion,omitempty"`
+	Watch                           *bool    `json:"watch,omitempty"`
+	SortManifests                   *bool    `json:"sortManifests,omitempty"`
+	SortBySyncWave                  *bool    `json:"sortBySyncWave,omitempty"`
+	Sort                            string   `json:"sort,omitempty"`
+	ApplyIgnoreDifferences          *bool    `json:"applyIgnoreDifferences,omitempty"`
+	Selector                        string   `json:"selector,omitempty"`
+	Kinds                           []string `json:"kinds,omitempty"`
+	MaxManifestCount                *int     `json:"maxManifestCount,omitempty"`
+	Verbose                         *bool    `json:"verbose,omitempty"`
+	VerboseJSON                     *bool    `json:"verboseJSON,omitempty"`
+	PrintArgs                       *bool    `json:"printArgs,omitempty"`
+	DryRun                          string   `json:"dryRun,omitempty"`
+	PruneByAnnotations              []string `json:"pruneByAnnotations,omitempty"`
+	ExcludeAnnotations              []string `json:"excludeAnnotations,omitempty"`
+	ExcludeKinds                    []string `json:"excludeKinds,omitempty"`
+	ExcludeHooks                    *bool    `json:"excludeHooks,omitempty"`
+	HooksOnly                       *bool    `json:"hooksOnly,omitempty"`
+	WarningsAsErrors                *bool    `json:"warningsAsErrors,omitempty"`
+	IgnoreWarnings                  []string `json:"ignoreWarnings,omitempty"`
+	AppNameOverride                 string   `json:"appNameOverride,omitempty"`
+	SourceNamespace                 string   `json:"sourceNamespace,omitempty"`
+	ControllerNamespace             string   `json:"controllerNamespace,omitempty"`
+	AllowEmptyRepoURL               *bool    `json:"allowEmptyRepoURL,omitempty"`
+	Revision                        string   `json:"revision,omitempty"`
+	ProjectFile                     string   `json:"projectFile,omitempty"`
+	HelmValuesSecrets               []string `json:"helmValuesSecrets,omitempty"`
+	HelmValuesConfigMaps            []string `json:"helmValuesConfigMaps,omitempty"`
+}
+
+// loadConfig reads the config file. Without a path the default config file is read if it exists.
+func loadConfig(path string) (*Config, error) {
+	if path == "" {
+		if _, err := os.Stat(defaultConfigFile); os.IsNotExist(err) {
+			return &Config{}, nil
+		}
+		path = defaultConfigFile
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config Config
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// flagValues returns the values of the options that are set, keyed by flag name
+func (c *Config) flagValues() map[string][]string {
+	values := map[string][]string{}
+	setString := func(name, value string) {
+		if value != "" {
+			values[name] = []string{value}
+		}
+	}
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			values[name] = []string{strconv.FormatBool(*value)}
+		}
+	}
+	setInt := func(name string, value *int) {
+		if value != nil {
+			values[name] = []string{strconv.Itoa(*value)}
+		}
+	}
+	setSlice := func(name string, value []string) {
+		if len(value) > 0 {
+			values[name] = value
+		}
+	}
+
+	setString("app", c.Application)
+	setString("app-from-cluster", c.AppFromCluster)
+	setString("repo-root", c.RepoRoot)
+	setString("repo-root-marker", c.RepoRootMarker)
+	setSlice("helm-set", c.HelmSet)
+	setSlice("helm-set-json", c.HelmSetJSON)
+	setSlice("helm-set-literal", c.HelmSetLiteral)
+	setSlice("helm-set-file", c.HelmSetFile)
+	setBool("helm-expand-env", c.HelmExpandEnv)
+	setBool("helm-sops-decrypt", c.HelmSOPSDecrypt)
+	setString("helm-binary-path", c.HelmBinaryPath)
+	setSlice("helm-values-file", c.HelmValuesFiles)
+	setBool("helm-update-deps", c.HelmUpdateDeps)
+	setString("helm-post-renderer", c.HelmPostRenderer)
+	setSlice("helm-post-renderer-args", c.HelmPostRendererArgs)
+	setBool("helm-lint", c.HelmLint)
+	setSlice("helm-api-version", c.HelmAPIVersions)
+	setBool("helm-include-crds", c.HelmIncludeCrds)
+	setBool("helm-generate-name", c.HelmGenerateName)
+	setBool("helm-force-string", c.HelmForceString)
+	setBool("kustomize-enable-alpha-plugins", c.KustomizeEnableAlphaPlugins)
+	setBool("kustomize-enable-exec", c.KustomizeEnableExec)
+	setString("kustomize-load-restrictor", c.KustomizeLoadRestrictor)
+	setString("kustomize-reorder", c.KustomizeReorder)
+	setBool("kustomize-enable-helm", c.KustomizeEnableHelm)
+	setString("kustomize-helm-command", c.KustomizeHelmCommand)
+	setSlice("kustomize-force-common-labels", c.KustomizeForceCommonLabels)
+	setSlice("kustomize-force-common-annotations", c.KustomizeForceCommonAnnotations)
+	setString("directory", c.Directory)
+	setBool("directory-recurse", c.DirectoryRecurse)
+	setString("directory-include", c.DirectoryInclude)
+	setString("directory-exclude", c.DirectoryExclude)
+	setInt("directory-max-depth", c.DirectoryMaxDepth)
+	setSlice("jsonnet-ext-var", c.JsonnetExtVars)
+	setSlice("jsonnet-tla", c.JsonnetTLAs)
+	setString("namespace-override", c.NamespaceOverride)
+	setSlice("inject-label", c.InjectLabels)
+	setSlice("inject-annotation", c.InjectAnnotations)
+	setBool("inject-label-overwrite", c.InjectLabelOverwrite)
+	setString("output-format", c.OutputFormat)
+	setString("output-dir", c.OutputDir)
+	setString("output-naming", c.OutputNaming)
+	setString("export-kustomization", c.ExportKustomization)
+	setString("diff-against", c.DiffAgainst)
+	setBool("print-stats", c.PrintStats)
+	setString("kube-version", c.KubeVersion)
+	setBool("auto-kube-version", c.AutoKubeVersion)
+	setString("kubeconfig", c.Kubeconfig)
+	setBool("strip-managed-fields", c.StripManagedFields)
+	setBool("strip-cluster-fields", c.StripClusterFields)
+	setString("cache-ttl", c.CacheTTL)
+	setString("cache-dir", c.CacheDir)
+	setBool("verify-cache", c.VerifyCache)
+	setInt("concurrency", c.Concurrency)
+	setString("timeout", c.Timeout)
+	setInt("helm-pull-retries", c.HelmPullRetries)
+	setBool("validate", c.Validate)
+	setString("schema-dir", c.SchemaDir)
+	setBool("kubeconform-validate", c.KubeconformValidate)
+	setBool("kubeconform-strict", c.KubeconformStrict)
+	setString("kubeconform-kubernetes-version", c.KubeconformKubernetesVersion)
+	setBool("watch", c.Watch)
+	setBool("sort-manifests", c.SortManifests)
+	setBool("sort-sync-wave", c.SortBySyncWave)
+	setString("sort", c.Sort)
+	setBool("apply-ignore-differences", c.ApplyIgnoreDifferences)
+	setString("selector", c.Selector)
+	setSlice("kind", c.Kinds)
+	setString("max-manifest-size", c.MaxManifestSize)
+	setInt("max-manifest-count", c.MaxManifestCount)
+	setBool("verbose", c.Verbose)
+	setBool("verbose-json", c.VerboseJSON)
+	setBool("print-args", c.PrintArgs)
+	setString("dry-run", c.DryRun)
+	setSlice("prune-by-annotation", c.PruneByAnnotations)
+	setSlice("exclude-annotation", c.ExcludeAnnotations)
+	setSlice("exclude-kind", c.ExcludeKinds)
+	setBool("exclude-hooks", c.ExcludeHooks)
+	setBool("hooks-only", c.HooksOnly)
+	setBool("warnings-as-errors", c.WarningsAsErrors)
+	setSlice("ignore-warning", c.IgnoreWarnings)
+	setString("app-name-override", c.AppNameOverride)
+	setString("source-namespace", c.SourceNamespace)
+	setString("controller-namespace", c.ControllerNamespace)
+	setBool("allow-empty-repo-url", c.AllowEmptyRepoURL)
+	setString("revision", c.Revision)
+	setString("project-file", c.ProjectFile)
+	setSlice("helm-values-secret", c.HelmValuesSecrets)
+	setSlice("helm-values-configmap", c.HelmValuesConfigMaps)
+	return values
+}
+
+// applyFlags sets the flags that were not set on the command line to the values of the config file
+func (c *Config) applyFlags(fs *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for name, values := range c.flagValues() {
+		if explicit[name] {
+			continue
+		}
+		for _, value := range values {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("invalid value %q for %s in config file: %w", value, name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseOptionsConfigFile(t *testing.T) {
+	config := `application: app.yaml
+repoRoot: /repo
+maxManifestSize: 20Mi
+namespaceOverride: from-config
+kubeVersion: "1.28"
+cacheTTL: 1h
+stripManagedFields: false
+injectLabels:
+- team=platform
+`
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cli, err := parseOptions(fs, []string{"--config", configPath, "--namespace-override", "from-flag"})
+	if err != nil {
+		t.Fatalf("parseOptions failed: %v", err)
+	}
+	opts := cli.Template
+
+	if opts.NamespaceOverride != "from-flag" {
+		t.Errorf("Expected command line flag to win, got namespace %q", opts.NamespaceOverride)
+	}
+	if opts.ApplicationFile != "app.yaml" || opts.RepoRoot != "/repo" || opts.MaxManifestSize != "20Mi" {
+		t.Errorf("Expected config file options to be used, got %+v", opts)
+	}
+	if opts.KubeVersion != "1.28" || opts.CacheTTL != time.Hour {
+		t.Errorf("Expected kube version and cache TTL from config file, got %q and %s", opts.KubeVersion, opts.CacheTTL)
+	}
+	if opts.StripManagedFields {
+		t.Error("Expected config file to override the flag default")
+	}
+	if opts.InjectLabels["team"] != "platform" {
+		t.Errorf("Expected labels from config file, got %v", opts.InjectLabels)
+	}
+}
+
+func TestParseOptionsWithoutConfigFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cli, err := parseOptions(fs, []string{"--app", "app.yaml"})
+	if err != nil {
+		t.Fatalf("parseOptions failed: %v", err)
+	}
+	if cli.Template.RepoRoot != "." || !cli.Template.StripManagedFields {
+		t.Errorf("Expected defaults without config file, got %+v", cli.Template)
+	}
+}
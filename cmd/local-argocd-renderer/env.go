@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix is the prefix of the environment variables that set flags
+const envPrefix = "LOCAL_ARGOCD_RENDERER_"
+
+// boolFlag is implemented by flags that do not take a value, like flag.Bool
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+// envOverrides sets the flags that were not set on the command line from environment
+// variables. The flag foo-bar is set by LOCAL_ARGOCD_RENDERER_FOO_BAR.
+func envOverrides(fs *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+
+		name := envVarName(f.Name)
+		value, found := os.LookupEnv(name)
+		if !found {
+			return
+		}
+		if b, ok := f.Value.(boolFlag); ok && b.IsBoolFlag() {
+			value = normalizeBool(value)
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %w", value, name, setErr)
+		}
+	})
+	return err
+}
+
+// envVarName returns the environment variable name of the flag
+func envVarName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// normalizeBool accepts yes and no in addition to the values strconv.ParseBool understands
+func normalizeBool(value string) string {
+	switch strings.ToLower(value) {
+	case "yes", "y", "on":
+		return "true"
+	case "no", "n", "off":
+		return "false"
+	default:
+		return value
+	}
+}
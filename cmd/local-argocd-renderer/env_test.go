@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestEnvOverrides(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("LOCAL_ARGOCD_RENDERER_APP", "app.yaml")
+	t.Setenv("LOCAL_ARGOCD_RENDERER_VALIDATE", "yes")
+	t.Setenv("LOCAL_ARGOCD_RENDERER_SORT_MANIFESTS", "1")
+	t.Setenv("LOCAL_ARGOCD_RENDERER_STRIP_MANAGED_FIELDS", "false")
+	t.Setenv("LOCAL_ARGOCD_RENDERER_CACHE_TTL", "30m")
+	t.Setenv("LOCAL_ARGOCD_RENDERER_NAMESPACE_OVERRIDE", "from-env")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cli, err := parseOptions(fs, []string{"--namespace-override", "from-flag"})
+	if err != nil {
+		t.Fatalf("parseOptions failed: %v", err)
+	}
+	opts := cli.Template
+
+	if opts.ApplicationFile != "app.yaml" {
+		t.Errorf("Expected application from environment, got %q", opts.ApplicationFile)
+	}
+	if !opts.Validate || !opts.SortManifests || opts.StripManagedFields {
+		t.Errorf("Expected booleans from environment, got validate=%v sort=%v strip=%v", opts.Validate, opts.SortManifests, opts.StripManagedFields)
+	}
+	if opts.CacheTTL != 30*time.Minute {
+		t.Errorf("Expected cache TTL of 30m, got %s", opts.CacheTTL)
+	}
+	if opts.NamespaceOverride != "from-flag" {
+		t.Errorf("Expected command line flag to win over environment, got %q", opts.NamespaceOverride)
+	}
+}
+
+func TestEnvOverridesInvalidValue(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("LOCAL_ARGOCD_RENDERER_CACHE_TTL", "soon")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if _, err := parseOptions(fs, nil); err == nil {
+		t.Error("Expected error for invalid duration")
+	}
+}
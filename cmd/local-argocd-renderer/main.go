@@ -4,45 +4,800 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	renderer "github.com/lorenzbischof/local-argocd-renderer"
 	"sigs.k8s.io/yaml"
 )
 
+// stringSliceFlag is a flag that can be repeated on the command line
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// commaSliceFlag is a flag of comma-separated values that can also be repeated on the command line
+type commaSliceFlag []string
+
+func (s *commaSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *commaSliceFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*s = append(*s, item)
+		}
+	}
+	return nil
+}
+
+// parseKeyValues parses key=value pairs into a map
+func parseKeyValues(values []string, what string) (map[string]string, error) {
+	result := make(map[string]string, len(values))
+	for _, value := range values {
+		key, val, found := strings.Cut(value, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid %s %q, expected key=value", what, value)
+		}
+		result[key] = val
+	}
+	return result, nil
+}
+
+// parseHelmParameters parses key=value pairs into Helm parameters
+func parseHelmParameters(values []string, configure func(*renderer.HelmParameter)) ([]renderer.HelmParameter, error) {
+	var params []renderer.HelmParameter
+	for _, value := range values {
+		name, val, found := strings.Cut(value, "=")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid Helm parameter %q, expected key=value", value)
+		}
+		param := renderer.HelmParameter{Name: name, Value: val}
+		configure(&param)
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// parseHelmFileParameters parses key=path pairs into Helm file parameters
+func parseHelmFileParameters(values []string) ([]renderer.HelmFileParameter, error) {
+	var params []renderer.HelmFileParameter
+	for _, value := range values {
+		name, path, found := strings.Cut(value, "=")
+		if !found || name == "" || path == "" {
+			return nil, fmt.Errorf("invalid Helm file parameter %q, expected key=path", value)
+		}
+		params = append(params, renderer.HelmFileParameter{Name: name, Path: path})
+	}
+	return params, nil
+}
+
+// parseSecretRefs parses name/namespace/key references to Secrets containing Helm values
+func parseSecretRefs(values []string, kubeconfigPath string) ([]renderer.SecretRef, error) {
+	var refs []renderer.SecretRef
+	for _, value := range values {
+		parts, err := splitKeyRef(value, "secret")
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, renderer.SecretRef{Name: parts[0], Namespace: parts[1], Key: parts[2], KubeconfigPath: kubeconfigPath})
+	}
+	return refs, nil
+}
+
+// parseConfigMapRefs parses name/namespace/key references to ConfigMaps containing Helm values
+func parseConfigMapRefs(values []string, kubeconfigPath string) ([]renderer.ConfigMapRef, error) {
+	var refs []renderer.ConfigMapRef
+	for _, value := range values {
+		parts, err := splitKeyRef(value, "configmap")
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, renderer.ConfigMapRef{Name: parts[0], Namespace: parts[1], Key: parts[2], KubeconfigPath: kubeconfigPath})
+	}
+	return refs, nil
+}
+
+// parseClusterAppRef parses the name/namespace reference of an Application in the cluster
+func parseClusterAppRef(value, kubeconfigPath string) (renderer.ClusterAppRef, error) {
+	name, namespace, found := strings.Cut(value, "/")
+	if !found || name == "" || namespace == "" || strings.Contains(namespace, "/") {
+		return renderer.ClusterAppRef{}, fmt.Errorf("invalid application %q, expected name/namespace", value)
+	}
+	return renderer.ClusterAppRef{Name: name, Namespace: namespace, KubeconfigPath: kubeconfigPath}, nil
+}
+
+// splitKeyRef splits a name/namespace/key reference
+func splitKeyRef(value, kind string) ([]string, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid Helm values %s %q, expected name/namespace/key", kind, value)
+	}
+	return parts, nil
+}
+
+// resolveRepoRoot returns the repository root. Without one it is searched for upwards from
+// the Application file, and the working directory is used for stdin and URLs.
+func resolveRepoRoot(repoRoot, applicationFile, marker string) (string, error) {
+	if repoRoot != "" {
+		return repoRoot, nil
+	}
+	if applicationFile == "" || applicationFile == "-" {
+		return ".", nil
+	}
+
+	root, err := renderer.FindRepoRoot(filepath.Dir(applicationFile), marker)
+	if err != nil {
+		return "", err
+	}
+	// Keep paths relative when rendering from the repository root
+	if cwd, err := os.Getwd(); err == nil && cwd == root {
+		return ".", nil
+	}
+	return root, nil
+}
+
+// useHelmBinary puts the helm binary first in PATH under the name helm, because Argo CD runs
+// helm template from PATH. The returned function restores PATH and removes the temporary
+// directory again.
+func useHelmBinary(binaryPath string) (func(), error) {
+	binary, err := exec.LookPath(binaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("helm binary %s not found: %w", binaryPath, err)
+	}
+	if binary, err = filepath.Abs(binary); err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "local-argocd-renderer-helm-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory for the helm binary: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	if err := os.Symlink(binary, filepath.Join(dir, "helm")); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to link the helm binary: %w", err)
+	}
+	path := os.Getenv("PATH")
+	if err := os.Setenv("PATH", dir+string(os.PathListSeparator)+path); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to put the helm binary in PATH: %w", err)
+	}
+	return func() {
+		os.Setenv("PATH", path)
+		cleanup()
+	}, nil
+}
+
+// parseNamingStrategy returns the naming strategy of the --output-naming value
+func parseNamingStrategy(value string) (renderer.NamingStrategy, error) {
+	switch value {
+	case "flat":
+		return renderer.FlatNaming{}, nil
+	case "wave":
+		return renderer.WaveNaming{}, nil
+	case "gvk":
+		return renderer.GVKNaming{}, nil
+	}
+	return nil, fmt.Errorf("unsupported output naming %q, expected flat, wave or gvk", value)
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal instead of a pipe or file
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runClearCache implements the clear-cache subcommand
+func runClearCache(args []string) {
+	fs := flag.NewFlagSet("clear-cache", flag.ExitOnError)
+	var cacheDir = fs.String("cache-dir", "", "Directory downloaded Helm charts are stored in")
+	fs.Parse(args)
+
+	if err := renderer.ClearHelmCache(*cacheDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runListCache implements the list-cache subcommand
+func runListCache(args []string) {
+	fs := flag.NewFlagSet("list-cache", flag.ExitOnError)
+	var cacheDir = fs.String("cache-dir", "", "Directory downloaded Helm charts are stored in")
+	fs.Parse(args)
+
+	charts, err := renderer.ListCachedCharts(*cacheDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "HASH\tCHART\tVERSION\tCACHED AT\tSIZE")
+	for _, chart := range charts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", chart.Hash[:min(len(chart.Hash), 12)], chart.Name, chart.Version,
+			chart.CachedAt.Local().Format("2006-01-02 15:04:05"), formatSize(chart.SizeBytes))
+	}
+	w.Flush()
+}
+
+// formatSize formats a size in bytes for humans
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGT"[exp])
+}
+
+// printDiffs writes the resource diffs, each starting with a === header line
+func printDiffs(w io.Writer, diffs []renderer.ResourceDiff) {
+	for _, diff := range diffs {
+		fmt.Fprintf(w, "=== %s (%s)\n", diff.Ref(), diff.Type)
+		fmt.Fprint(w, diff.Diff)
+	}
+}
+
+// cliOptions are the options parsed from the command line
+type cliOptions struct {
+	Template        renderer.TemplateOptions
+	Watch           bool
+	OutputFormat    string
+	AutoKubeVersion bool
+	Kubeconfig      string
+	// Directory is rendered as plain manifests instead of an Application
+	Directory string
+	// OutputDir is written to with OutputNaming instead of printing the manifests
+	OutputDir    string
+	OutputNaming renderer.NamingStrategy
+	// ExportKustomization is written to as a kustomization instead of printing the manifests
+	ExportKustomization string
+	// DiffAgainst is an Application file whose manifests are compared with the manifests
+	// of the Application instead of printing them
+	DiffAgainst string
+	// PrintStats writes the number of manifests of each kind to stderr
+	PrintStats bool
+}
+
+// parseOptions parses the command line arguments. Flags that are not set on the command
+// line are taken from the environment and then from the config file.
+func parseOptions(fs *flag.FlagSet, args []string) (*cliOptions, error) {
+	var configFile = fs.String("config", "", "Path to a config file (default "+defaultConfigFile+" if it exists)")
+	var applicationFile = fs.String("app", "", "Path or http(s) URL of the Application CRD YAML file (use '-' for stdin) (required)")
+	var appFromCluster = fs.String("app-from-cluster", "", "Read the Application from the cluster of --kubeconfig instead of a file, name/namespace")
+	var projectFile = fs.String("project-file", "", "Path to an AppProject YAML file the source repositories and destination are validated against")
+	var repoRoot = fs.String("repo-root", "", "Root of the repository the source paths are relative to (default: the first parent of the Application file containing .git or the marker file)")
+	var repoRootMarker = fs.String("repo-root-marker", renderer.DefaultRepoRootMarker, "File that marks the repository root in addition to .git")
+	var helmSet, helmSetJSON, helmSetLiteral, helmSetFile, helmValuesFiles stringSliceFlag
+	fs.Var(&helmSet, "helm-set", "Set a Helm value, key=value (can be repeated)")
+	fs.Var(&helmSetJSON, "helm-set-json", "Set a Helm value from JSON, key=json (can be repeated)")
+	fs.Var(&helmSetLiteral, "helm-set-literal", "Set a literal Helm string value, key=value (can be repeated)")
+	fs.Var(&helmSetFile, "helm-set-file", "Set a Helm value to the content of a file, key=path (can be repeated)")
+	var helmValuesSecrets stringSliceFlag
+	fs.Var(&helmValuesSecrets, "helm-values-secret", "Merge the Helm values in a key of a Secret in the cluster over the value files and under the inline values of every Helm source, name/namespace/key (can be repeated)")
+	var helmValuesConfigMaps stringSliceFlag
+	fs.Var(&helmValuesConfigMaps, "helm-values-configmap", "Merge the Helm values in a key of a ConfigMap in the cluster under the value files and inline values of every Helm source, name/namespace/key (can be repeated)")
+	fs.Var(&helmValuesFiles, "helm-values-file", "Merge a values file over the values of every Helm source (can be repeated)")
+	var helmSOPSDecrypt = fs.Bool("helm-sops-decrypt", false, "Decrypt SOPS encrypted Helm value files with sops before rendering")
+	var helmExpandEnv = fs.Bool("helm-expand-env", false, "Expand $VAR references to environment variables in the Helm value files and inline values")
+	var helmBinaryPath = fs.String("helm-binary-path", "", "Path of the helm binary to use instead of helm from PATH, it is put first in PATH for helm template")
+	var helmUpdateDeps = fs.Bool("helm-update-deps", false, "Run helm dependency update before rendering Helm charts, otherwise missing dependencies are only reported")
+	var helmPostRenderer = fs.String("helm-post-renderer", "", "Path to a program the rendered Helm manifests are piped through")
+	var helmAPIVersions stringSliceFlag
+	fs.Var(&helmAPIVersions, "helm-api-version", "API version available to Helm capabilities checks, apiGroup/version/kind (can be repeated)")
+	var helmGenerateName = fs.Bool("helm-generate-name", false, "Render Helm charts with a generated release name, like helm --generate-name")
+	var helmIncludeCrds = fs.Bool("helm-include-crds", false, "Render the CRDs of Helm charts even when the source sets skipCrds")
+	var helmForceString = fs.Bool("helm-force-string", false, "Pass every Helm parameter with --set-string, so values like 1.10 are not coerced to numbers")
+	var helmLint = fs.Bool("helm-lint", false, "Run helm lint with the values of each Helm source before rendering it")
+	var helmPostRendererArgs stringSliceFlag
+	fs.Var(&helmPostRendererArgs, "helm-post-renderer-args", "Argument passed to the Helm post-renderer (can be repeated)")
+	var kustomizeEnableAlphaPlugins = fs.Bool("kustomize-enable-alpha-plugins", false, "Enable kustomize alpha plugins")
+	var kustomizeEnableExec = fs.Bool("kustomize-enable-exec", false, "Enable kustomize exec plugins")
+	var kustomizeLoadRestrictor = fs.String("kustomize-load-restrictor", "", "Restrict the files kustomize loads: none or root-only (default: none for the temporary overlay)")
+	var kustomizeEnableHelm = fs.Bool("kustomize-enable-helm", false, "Let kustomize inflate the charts in the helmCharts field of kustomizations")
+	var kustomizeHelmCommand = fs.String("kustomize-helm-command", "", "Helm binary used by --kustomize-enable-helm (default: helm from PATH)")
+	var kustomizeReorder = fs.String("kustomize-reorder", "", "Resource order of kustomize build: legacy or none (requires kustomize v5 or later)")
+	var kustomizeForceLabels, kustomizeForceAnnotations stringSliceFlag
+	fs.Var(&kustomizeForceLabels, "kustomize-force-common-labels", "Set a label on every resource of Kustomize sources, key=value (can be repeated)")
+	fs.Var(&kustomizeForceAnnotations, "kustomize-force-common-annotations", "Set an annotation on every resource of Kustomize sources, key=value (can be repeated)")
+	var directoryInclude = fs.String("directory-include", "", "Glob patterns of files included from directory sources, comma-separated")
+	var directoryExclude = fs.String("directory-exclude", "", "Glob patterns of files excluded from directory sources, comma-separated")
+	var directoryRecurse = fs.Bool("directory-recurse", false, "Include files in subdirectories of directory sources")
+	var directoryMaxDepth = fs.Int("directory-max-depth", 0, "Maximum depth recursive directory sources descend into, 1 only includes the source path (0 for unlimited)")
+	var jsonnetExtVars, jsonnetTLAs stringSliceFlag
+	fs.Var(&jsonnetExtVars, "jsonnet-ext-var", "Set a Jsonnet external variable of directory sources, key=value (can be repeated)")
+	fs.Var(&jsonnetTLAs, "jsonnet-tla", "Set a Jsonnet top-level argument of directory sources, key=value (can be repeated)")
+	var directory = fs.String("directory", "", "Render a directory of plain manifests instead of an Application, only the --directory-* options apply")
+	var allowEmptyRepoURL = fs.Bool("allow-empty-repo-url", false, "Render sources without a repoURL from the local repository instead of failing")
+	var sourceNamespace = fs.String("source-namespace", "", "Namespace the Application is in instead of metadata.namespace, checked against the sourceNamespaces of --project-file")
+	var controllerNamespace = fs.String("controller-namespace", renderer.DefaultControllerNamespace, "Namespace Argo CD is installed in, whose Applications --project-file permits regardless of its sourceNamespaces")
+	var appNameOverride = fs.String("app-name-override", "", "Use this name instead of the Application name in the tracking label and as the default Helm release name")
+	var revision = fs.String("revision", "", "Use this revision instead of the targetRevision of every source")
+	var namespaceOverride = fs.String("namespace-override", "", "Set the namespace of every namespaced resource")
+	var injectLabels, injectAnnotations stringSliceFlag
+	fs.Var(&injectLabels, "inject-label", "Add a label to every resource, key=value (can be repeated)")
+	fs.Var(&injectAnnotations, "inject-annotation", "Add an annotation to every resource, key=value (can be repeated)")
+	var injectOverwrite = fs.Bool("inject-label-overwrite", false, "Overwrite injected labels and annotations that are already set on a resource")
+	var outputFormat = fs.String("output-format", "yaml", "Output format: yaml for a multi-document stream, list for a single v1 List")
+	var outputDir = fs.String("output-dir", "", "Write every manifest to its own file in this directory instead of stdout")
+	var outputNaming = fs.String("output-naming", "flat", "File names of --output-dir: flat for <kind>_<name>.yaml, wave to prefix them with the sync wave, gvk for <group>/<version>/<kind>/<namespace>/<name>.yaml")
+	var diffAgainst = fs.String("diff-against", "", "Render this Application file too and print the differences to the manifests of --app instead of the manifests")
+	var exportKustomization = fs.String("export-kustomization", "", "Write every manifest to <kind>-<name>.yaml and a kustomization.yaml listing them in this directory instead of stdout")
+	var printStats = fs.Bool("print-stats", false, "Print the number of manifests of each kind to stderr")
+	var kubeVersion = fs.String("kube-version", "", "Kubernetes version passed to Helm and Kustomize, e.g. 1.29")
+	var autoKubeVersion = fs.Bool("auto-kube-version", false, "Detect the Kubernetes version from the current kubeconfig context when --kube-version is not set")
+	var kubeconfig = fs.String("kubeconfig", "", "Path to the kubeconfig used by --auto-kube-version, --app-from-cluster, --helm-values-secret, --helm-values-configmap and --dry-run")
+	var stripManagedFields = fs.Bool("strip-managed-fields", true, "Remove metadata.managedFields from the manifests")
+	var stripClusterFields = fs.Bool("strip-cluster-fields", true, "Remove resourceVersion, uid, generation, creationTimestamp and status from the manifests")
+	var cacheTTL = fs.Duration("cache-ttl", renderer.DefaultCacheTTL, "How long downloaded Helm charts are reused (0 downloads on every run)")
+	var cacheDir = fs.String("cache-dir", "", "Directory downloaded Helm charts are stored in")
+	var verifyCache = fs.Bool("verify-cache", false, "Download cached Helm charts again when their content changed since they were downloaded")
+	var helmPullRetries = fs.Int("helm-pull-retries", renderer.DefaultHelmPullRetries, "How often a helm pull that failed with a network error is retried")
+	var timeout = fs.Duration("timeout", 0, "Stop rendering after this duration, e.g. 5m (0 for no limit)")
+	var concurrency = fs.Int("concurrency", 1, "Number of sources rendered in parallel (-1 for unlimited)")
+	var validate = fs.Bool("validate", false, "Validate the rendered manifests against JSON schemas")
+	var schemaDir = fs.String("schema-dir", "", "Directory of Kubernetes JSON schemas used by --validate")
+	var kubeconformValidate = fs.Bool("kubeconform-validate", false, "Validate the rendered manifests with kubeconform")
+	var kubeconformStrict = fs.Bool("kubeconform-strict", false, "Reject resources with fields that are not part of the schema when using --kubeconform-validate")
+	var kubeconformKubernetesVersion = fs.String("kubeconform-kubernetes-version", "", "Kubernetes version of the schemas used by --kubeconform-validate, e.g. 1.29.0")
+	var watch = fs.Bool("watch", false, "Render again whenever a file in the repository or the Application file changes")
+	var sortManifests = fs.Bool("sort-manifests", false, "Sort the manifests in the order Helm installs them")
+	var sortBySyncWave = fs.Bool("sort-sync-wave", false, "Sort the manifests by the argocd.argoproj.io/sync-wave annotation, then in the order Helm installs them")
+	var sortOrder = fs.String("sort", "", "Sort the manifests: gvk by group, version, kind, namespace and name. Takes precedence over --sort-manifests and --sort-sync-wave")
+	var selector = fs.String("selector", "", "Only output resources matching the label selector, e.g. app=frontend,tier in (web,api)")
+	var includeKinds, excludeKinds commaSliceFlag
+	fs.Var(&includeKinds, "kind", "Only output resources of these kinds, comma-separated and case-insensitive")
+	fs.Var(&excludeKinds, "exclude-kind", "Do not output resources of these kinds, comma-separated and case-insensitive")
+	var excludeHooks = fs.Bool("exclude-hooks", false, "Do not output resources with an argocd.argoproj.io/hook annotation")
+	var hooksOnly = fs.Bool("hooks-only", false, "Only output resources with an argocd.argoproj.io/hook annotation")
+	var pruneByAnnotations, excludeAnnotations stringSliceFlag
+	fs.Var(&pruneByAnnotations, "prune-by-annotation", "Only output resources with the annotation, key=value (can be repeated, all must match)")
+	fs.Var(&excludeAnnotations, "exclude-annotation", "Do not output resources with the annotation, key=value (can be repeated)")
+	var verbose = fs.Bool("verbose", false, "Print the source type, path and equivalent command of every source to stderr")
+	var verboseJSON = fs.Bool("verbose-json", false, "Print the events of every source to stderr as JSON objects, one per line")
+	var kubectlDryRun = fs.String("dry-run", "none", "Pass the manifests to kubectl apply --dry-run: none, client or server")
+	var printArgs = fs.Bool("print-args", false, "Print the equivalent command of every source to stdout instead of rendering it")
+	var maxManifestSize = fs.String("max-manifest-size", "", "Fail when the rendered manifests are larger in total, e.g. 20Mi (default 10Mi)")
+	var maxManifestCount = fs.Int("max-manifest-count", 0, "Fail when more manifests are rendered (0 for unlimited)")
+	var warningsAsErrors = fs.Bool("warnings-as-errors", false, "Fail when rendering produces warnings")
+	var ignoreWarnings stringSliceFlag
+	fs.Var(&ignoreWarnings, "ignore-warning", "Suppress warnings containing this text (can be repeated)")
+	var applyIgnoreDifferences = fs.Bool("apply-ignore-differences", false, "Remove the fields listed in the ignoreDifferences of the Application from the manifests")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := envOverrides(fs); err != nil {
+		return nil, err
+	}
+
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.applyFlags(fs); err != nil {
+		return nil, err
+	}
+
+	params, err := parseHelmParameters(helmSet, func(p *renderer.HelmParameter) {})
+	if err != nil {
+		return nil, err
+	}
+	jsonParams, err := parseHelmParameters(helmSetJSON, func(p *renderer.HelmParameter) { p.ForceJSON = true })
+	if err != nil {
+		return nil, err
+	}
+	literalParams, err := parseHelmParameters(helmSetLiteral, func(p *renderer.HelmParameter) { p.ForceLiteral = true })
+	if err != nil {
+		return nil, err
+	}
+
+	fileParams, err := parseHelmFileParameters(helmSetFile)
+	if err != nil {
+		return nil, err
+	}
+	valueSecrets, err := parseSecretRefs(helmValuesSecrets, *kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	valueConfigMaps, err := parseConfigMapRefs(helmValuesConfigMaps, *kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	var clusterApp renderer.ClusterAppRef
+	if *appFromCluster != "" {
+		if *applicationFile != "" {
+			return nil, fmt.Errorf("--app-from-cluster cannot be combined with --app")
+		}
+		clusterApp, err = parseClusterAppRef(*appFromCluster, *kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	extVars, err := parseKeyValues(jsonnetExtVars, "Jsonnet external variable")
+	if err != nil {
+		return nil, err
+	}
+	tlas, err := parseKeyValues(jsonnetTLAs, "Jsonnet top-level argument")
+	if err != nil {
+		return nil, err
+	}
+	labels, err := parseKeyValues(injectLabels, "label")
+	if err != nil {
+		return nil, err
+	}
+	annotations, err := parseKeyValues(injectAnnotations, "annotation")
+	if err != nil {
+		return nil, err
+	}
+
+	includeAnnotations, err := parseKeyValues(pruneByAnnotations, "annotation")
+	if err != nil {
+		return nil, err
+	}
+	excludedAnnotations, err := parseKeyValues(excludeAnnotations, "annotation")
+	if err != nil {
+		return nil, err
+	}
+
+	forceLabels, err := parseKeyValues(kustomizeForceLabels, "label")
+	if err != nil {
+		return nil, err
+	}
+	forceAnnotations, err := parseKeyValues(kustomizeForceAnnotations, "annotation")
+	if err != nil {
+		return nil, err
+	}
+
+	if *outputFormat != "yaml" && *outputFormat != "list" {
+		return nil, fmt.Errorf("unsupported output format %q, expected yaml or list", *outputFormat)
+	}
+	naming, err := parseNamingStrategy(*outputNaming)
+	if err != nil {
+		return nil, err
+	}
+	if *outputDir != "" && *outputFormat == "list" {
+		return nil, fmt.Errorf("--output-format list cannot be combined with --output-dir")
+	}
+	if *exportKustomization != "" && (*outputDir != "" || *outputFormat == "list") {
+		return nil, fmt.Errorf("--export-kustomization cannot be combined with --output-dir or --output-format list")
+	}
+	if *diffAgainst != "" && (*directory != "" || *watch || *outputDir != "" || *exportKustomization != "") {
+		return nil, fmt.Errorf("--diff-against cannot be combined with --directory, --watch, --output-dir or --export-kustomization")
+	}
+
+	var logger renderer.Logger
+	if *verboseJSON {
+		if *verbose {
+			return nil, fmt.Errorf("--verbose-json cannot be combined with --verbose")
+		}
+		logger = renderer.JSONLogger{}
+	}
+
+	// A TTL of zero means the library default, so disable the cache explicitly
+	ttl := *cacheTTL
+	if ttl == 0 {
+		ttl = -1
+	}
+	// Likewise zero retries means the library default
+	retries := *helmPullRetries
+	if retries == 0 {
+		retries = -1
+	}
+
+	var applicationURL string
+	if renderer.IsApplicationURL(*applicationFile) {
+		applicationURL = *applicationFile
+		*applicationFile = ""
+	}
+
+	root, err := resolveRepoRoot(*repoRoot, *applicationFile, *repoRootMarker)
+	if err != nil {
+		return nil, err
+	}
+
+	var project *v1alpha1.AppProject
+	if *projectFile != "" {
+		project, err = renderer.ReadAppProject(*projectFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var dryRun string
+	if *printArgs {
+		dryRun = renderer.DryRunPrint
+	}
+	dryRunMode := *kubectlDryRun
+	switch dryRunMode {
+	case "none":
+		dryRunMode = ""
+	case renderer.DryRunModeClient, renderer.DryRunModeServer:
+	default:
+		return nil, fmt.Errorf("unsupported dry run %q, expected none, client or server", dryRunMode)
+	}
+
+	return &cliOptions{
+		Watch:           *watch,
+		OutputFormat:    *outputFormat,
+		OutputDir:       *outputDir,
+		OutputNaming:    naming,
+		DiffAgainst:     *diffAgainst,
+		PrintStats:      *printStats,
+		AutoKubeVersion: *autoKubeVersion,
+		Kubeconfig:      *kubeconfig,
+		Directory:       *directory,
+		Template: renderer.TemplateOptions{
+			ApplicationFile:        *applicationFile,
+			ApplicationURL:         applicationURL,
+			ClusterApp:             clusterApp,
+			RepoRoot:               root,
+			MaxManifestSize:        *maxManifestSize,
+			MaxManifestCount:       *maxManifestCount,
+			Verbose:                *verbose,
+			Logger:                 logger,
+			DryRun:                 dryRun,
+			DryRunMode:             dryRunMode,
+			KubeconfigPath:         *kubeconfig,
+			CacheTTL:               ttl,
+			CacheDir:               *cacheDir,
+			VerifyCache:            *verifyCache,
+			Concurrency:            *concurrency,
+			Timeout:                *timeout,
+			HelmPullRetries:        retries,
+			Validate:               *validate,
+			SchemaDir:              *schemaDir,
+			KubeconformValidate:    *kubeconformValidate,
+			SortManifests:          *sortManifests,
+			SortBySyncWave:         *sortBySyncWave,
+			SortOrder:              *sortOrder,
+			ApplyIgnoreDifferences: *applyIgnoreDifferences,
+			WarningsAsErrors:       *warningsAsErrors,
+			IgnoreWarnings:         ignoreWarnings,
+			Selector:               *selector,
+			IncludeKinds:           includeKinds,
+			ExcludeKinds:           excludeKinds,
+			ExcludeHookResources:   *excludeHooks,
+			HooksOnly:              *hooksOnly,
+			IncludeAnnotations:     includeAnnotations,
+			ExcludeAnnotations:     excludedAnnotations,
+			NamespaceOverride:      *namespaceOverride,
+			AppNameOverride:        *appNameOverride,
+			SourceNamespace:        *sourceNamespace,
+			ControllerNamespace:    *controllerNamespace,
+			AllowEmptyRepoURL:      *allowEmptyRepoURL,
+			Revision:               *revision,
+			Project:                project,
+			ValueSecrets:           valueSecrets,
+			ValueConfigMaps:        valueConfigMaps,
+			KubeVersion:            *kubeVersion,
+			StripManagedFields:     *stripManagedFields,
+			StripClusterFields:     *stripClusterFields,
+			InjectLabels:           labels,
+			InjectAnnotations:      annotations,
+			InjectOverwrite:        *injectOverwrite,
+			Helm: renderer.HelmOptions{
+				Parameters:            append(append(params, jsonParams...), literalParams...),
+				ExtraValueFiles:       helmValuesFiles,
+				ExtraFileParameters:   fileParams,
+				UpdateDependencies:    *helmUpdateDeps,
+				PostRenderer:          *helmPostRenderer,
+				PostRendererArgs:      helmPostRendererArgs,
+				LintBeforeRender:      *helmLint,
+				APIVersions:           helmAPIVersions,
+				IncludeCrds:           *helmIncludeCrds,
+				GenerateName:          *helmGenerateName,
+				ExpandEnvInValues:     *helmExpandEnv,
+				SOPSDecrypt:           *helmSOPSDecrypt,
+				BinaryPath:            *helmBinaryPath,
+				ForceStringParameters: *helmForceString,
+			},
+			Kustomize: renderer.KustomizeOptions{
+				EnableAlphaPlugins:     *kustomizeEnableAlphaPlugins,
+				EnableExec:             *kustomizeEnableExec,
+				LoadRestrictor:         *kustomizeLoadRestrictor,
+				Reorder:                *kustomizeReorder,
+				EnableHelm:             *kustomizeEnableHelm,
+				HelmCommand:            *kustomizeHelmCommand,
+				ForceCommonLabels:      forceLabels,
+				ForceCommonAnnotations: forceAnnotations,
+			},
+			Directory: renderer.DirectoryOptions{
+				Include:        *directoryInclude,
+				Exclude:        *directoryExclude,
+				MaxDepth:       *directoryMaxDepth,
+				Recurse:        *directoryRecurse,
+				JsonnetExtVars: extVars,
+				JsonnetTLAs:    tlas,
+			},
+			KubeconformOptions: renderer.KubeconformOptions{
+				KubernetesVersion: *kubeconformKubernetesVersion,
+				Strict:            *kubeconformStrict,
+			},
+		},
+		ExportKustomization: *exportKustomization,
+	}, nil
+}
+
 func main() {
-	var applicationFile = flag.String("app", "", "Path to Application CRD YAML file (use '-' for stdin) (required)")
-	flag.Parse()
+	os.Exit(run())
+}
+
+// run runs the CLI and returns the exit code, so deferred functions run before the process exits
+func run() int {
+	if len(os.Args) > 1 && os.Args[1] == "clear-cache" {
+		runClearCache(os.Args[2:])
+		return 0
+	}
+	if len(os.Args) > 1 && os.Args[1] == "list-cache" {
+		runListCache(os.Args[2:])
+		return 0
+	}
+
+	cli, err := parseOptions(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	opts := cli.Template
 
-	if *applicationFile == "" {
-		fmt.Fprintf(os.Stderr, "Error: --application flag is required\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s --application <file> | --application -\n", os.Args[0])
+	if opts.ApplicationFile == "" && opts.ApplicationURL == "" && opts.ClusterApp.Name == "" && cli.Directory == "" {
+		fmt.Fprintf(os.Stderr, "Error: --app flag is required\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s --app <file> | --app -\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Examples:\n")
-		fmt.Fprintf(os.Stderr, "  %s --application app.yaml\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  cat app.yaml | %s --application -\n", os.Args[0])
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "  %s --app app.yaml\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  cat app.yaml | %s --app -\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --directory manifests/\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --app-from-cluster my-app/argocd\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --app https://kubernetes.default.svc/apis/argoproj.io/v1alpha1/namespaces/argocd/applications/my-app\n", os.Args[0])
+		return 1
+	}
+
+	if opts.Helm.BinaryPath != "" {
+		cleanup, err := useHelmBinary(opts.Helm.BinaryPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer cleanup()
+	}
+
+	if opts.ApplicationFile == "-" && stdinIsTerminal() {
+		fmt.Fprintf(os.Stderr, "Reading Application from stdin...\n")
+	}
+
+	if opts.Kustomize.EnableAlphaPlugins && opts.Kustomize.EnableExec {
+		fmt.Fprintf(os.Stderr, "Warning: kustomize exec plugins are enabled and can run arbitrary programs\n")
 	}
 
 	ctx := context.Background()
-	opts := renderer.TemplateOptions{
-		ApplicationFile: *applicationFile,
-		RepoRoot:        ".",
+
+	if opts.KubeVersion == "" && cli.AutoKubeVersion {
+		detected, err := renderer.DetectKubeVersion(ctx, cli.Kubeconfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to detect Kubernetes version: %v\n", err)
+		} else {
+			opts.KubeVersion = detected
+		}
 	}
 
-	result, err := renderer.TemplateFromApplication(ctx, opts)
+	if cli.Watch {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := renderer.Watch(ctx, opts, renderer.WatchOptions{}, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	var result *renderer.TemplateResult
+	if cli.Directory != "" {
+		result, err = renderer.TemplateFromDirectory(ctx, cli.Directory, opts.Directory)
+	} else {
+		result, err = renderer.TemplateFromApplication(ctx, opts)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return 1
+	}
+
+	if opts.DryRun == renderer.DryRunPrint {
+		for _, command := range result.Commands {
+			fmt.Println(command)
+		}
+		return 0
 	}
 
 	// Report any warnings
 	for _, warning := range result.Warnings {
-		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning.Message)
+	}
+
+	if cli.PrintStats {
+		renderer.FormatStats(renderer.ComputeStats(result.Objects), os.Stderr)
+	}
+
+	if cli.DiffAgainst != "" {
+		diffOpts := opts
+		diffOpts.ApplicationFile = cli.DiffAgainst
+		diffOpts.ApplicationURL = ""
+		against, err := renderer.TemplateFromApplication(ctx, diffOpts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to render %s: %v\n", cli.DiffAgainst, err)
+			return 1
+		}
+		diffs, err := renderer.DiffResults(against.Objects, result.Objects)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		printDiffs(os.Stdout, diffs)
+		if len(diffs) > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	if cli.OutputDir != "" {
+		if err := renderer.WriteManifests(cli.OutputDir, result.Objects, cli.OutputNaming); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d manifests to %s\n", len(result.Objects), cli.OutputDir)
+		return 0
+	}
+
+	if cli.ExportKustomization != "" {
+		if err := renderer.ToKustomization(result, cli.ExportKustomization); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Wrote a kustomization of %d manifests to %s\n", len(result.Objects), cli.ExportKustomization)
+		return 0
 	}
 
 	fmt.Printf("# Generated %d manifests\n", len(result.Objects))
 	fmt.Println("---")
 
+	if cli.OutputFormat == "list" {
+		list, err := renderer.WrapInList(result.Objects)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+
+		yamlBytes, err := yaml.Marshal(list.Object)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+
+		fmt.Printf("%s", yamlBytes)
+		return 0
+	}
+
 	// Parse and output manifests
 	for i, object := range result.Objects {
 		if i > 0 {
@@ -52,9 +807,10 @@ func main() {
 		yamlBytes, err := yaml.Marshal(object)
 		if err != nil {
 			fmt.Println(err)
-			os.Exit(1)
+			return 1
 		}
 
 		fmt.Printf("%s", yamlBytes)
 	}
+	return 0
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The test binary runs main with the arguments of runMainArgsEnv when runMainEnv is set, so
+// tests can run the command in a subprocess
+const (
+	runMainEnv     = "RENDERER_TEST_RUN_MAIN"
+	runMainArgsEnv = "RENDERER_TEST_ARGS"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		os.Args = append([]string{os.Args[0]}, strings.Fields(os.Getenv(runMainArgsEnv))...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestMainReadsApplicationFromStdin(t *testing.T) {
+	application, err := os.ReadFile("../../examples/directory/app.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read application: %v", err)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.Write(application)
+		writer.Close()
+	}()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(os.Args[0])
+	cmd.Dir = "../.."
+	cmd.Env = append(os.Environ(), runMainEnv+"=1", runMainArgsEnv+"=--app -")
+	cmd.Stdin = reader
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Rendering from stdin failed: %v\n%s", err, stderr.String())
+	}
+
+	if !strings.Contains(stdout.String(), "kind: Deployment") {
+		t.Errorf("Expected the rendered manifests on stdout, got:\n%s", stdout.String())
+	}
+	if strings.Contains(stderr.String(), "Reading Application from stdin") {
+		t.Errorf("Expected no diagnostic when stdin is a pipe, got:\n%s", stderr.String())
+	}
+}
+
+func TestParseOptionsFindsRepoRoot(t *testing.T) {
+	root := t.TempDir()
+	appDir := filepath.Join(root, "apps", "frontend")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+	t.Chdir(filepath.Join(root, "apps"))
+
+	cli, err := parseOptions(flag.NewFlagSet("test", flag.ContinueOnError), []string{"--app", "frontend/app.yaml"})
+	if err != nil {
+		t.Fatalf("parseOptions failed: %v", err)
+	}
+	if cli.Template.RepoRoot != root {
+		t.Errorf("Expected repository root %s, got %s", root, cli.Template.RepoRoot)
+	}
+
+	cli, err = parseOptions(flag.NewFlagSet("test", flag.ContinueOnError), []string{"--app", "frontend/app.yaml", "--repo-root", "/repo"})
+	if err != nil {
+		t.Fatalf("parseOptions failed: %v", err)
+	}
+	if cli.Template.RepoRoot != "/repo" {
+		t.Errorf("Expected the explicit repository root, got %s", cli.Template.RepoRoot)
+	}
+}
+
+func TestUseHelmBinaryRestoresPath(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "my-helm")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write helm binary: %v", err)
+	}
+	t.Setenv("PATH", os.Getenv("PATH"))
+	path := os.Getenv("PATH")
+
+	cleanup, err := useHelmBinary(binary)
+	if err != nil {
+		t.Fatalf("useHelmBinary failed: %v", err)
+	}
+	helm, err := exec.LookPath("helm")
+	if err != nil {
+		t.Fatalf("Expected helm in PATH: %v", err)
+	}
+	if target, _ := os.Readlink(helm); target != binary {
+		t.Errorf("Expected helm to link to %s, got %s", binary, target)
+	}
+
+	cleanup()
+	if got := os.Getenv("PATH"); got != path {
+		t.Errorf("Expected PATH to be restored to %q, got %q", path, got)
+	}
+	if _, err := os.Stat(filepath.Dir(helm)); !os.IsNotExist(err) {
+		t.Errorf("Expected the temporary directory to be removed, got %v", err)
+	}
+}
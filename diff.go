@@ -0,0 +1,138 @@
+package renderer
+
+import (
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	kubeutil "github.com/argoproj/gitops-engine/pkg/utils/kube"
+)
+
+// ResourceDiffType is the kind of change of a resource between two renders
+type ResourceDiffType string
+
+const (
+	ResourceAdded   ResourceDiffType = "added"
+	ResourceRemoved ResourceDiffType = "removed"
+	ResourceChanged ResourceDiffType = "changed"
+)
+
+// ResourceDiff is a resource that differs between two renders
+type ResourceDiff struct {
+	Group     string
+	Version   string
+	Kind      string
+	Namespace string
+	Name      string
+	Type      ResourceDiffType
+	// Diff is the YAML of the resource with every line prefixed by "+ " if it was added,
+	// "- " if it was removed or "  " if it is unchanged
+	Diff string
+}
+
+// Ref returns the reference of the resource, e.g. apps/v1/Deployment/default/web
+func (d ResourceDiff) Ref() string {
+	return resourceRef(d.Group, d.Version, d.Kind, d.Namespace, d.Name)
+}
+
+// DiffResults compares the objects of two renders resource by resource. Resources only in b
+// are added, resources only in a are removed. The diffs are in the order of a, followed by
+// the resources added in b.
+func DiffResults(a, b []*unstructured.Unstructured) ([]ResourceDiff, error) {
+	before, beforeKeys, err := yamlByResourceKey(a)
+	if err != nil {
+		return nil, err
+	}
+	after, afterKeys, err := yamlByResourceKey(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []ResourceDiff
+	for _, key := range beforeKeys {
+		afterYAML, found := after[key]
+		switch {
+		case !found:
+			diffs = append(diffs, newResourceDiff(key, before[key].version, ResourceRemoved, prefixLines(before[key].yaml, "- ")))
+		case afterYAML.yaml != before[key].yaml:
+			diffs = append(diffs, newResourceDiff(key, afterYAML.version, ResourceChanged, diffLines(before[key].yaml, afterYAML.yaml)))
+		}
+	}
+	for _, key := range afterKeys {
+		if _, found := before[key]; !found {
+			diffs = append(diffs, newResourceDiff(key, after[key].version, ResourceAdded, prefixLines(after[key].yaml, "+ ")))
+		}
+	}
+	return diffs, nil
+}
+
+type renderedResource struct {
+	version string
+	yaml    string
+}
+
+// yamlByResourceKey marshals the objects to YAML and returns them by resource key, along
+// with the keys in the order of the objects. Later objects replace earlier ones with the same key.
+func yamlByResourceKey(objects []*unstructured.Unstructured) (map[kubeutil.ResourceKey]renderedResource, []kubeutil.ResourceKey, error) {
+	resources := make(map[kubeutil.ResourceKey]renderedResource)
+	var keys []kubeutil.ResourceKey
+	for _, obj := range objects {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, nil, err
+		}
+		key := kubeutil.GetResourceKey(obj)
+		if _, found := resources[key]; !found {
+			keys = append(keys, key)
+		}
+		resources[key] = renderedResource{version: obj.GroupVersionKind().Version, yaml: string(data)}
+	}
+	return resources, keys, nil
+}
+
+func newResourceDiff(key kubeutil.ResourceKey, version string, diffType ResourceDiffType, diff string) ResourceDiff {
+	return ResourceDiff{
+		Group:     key.Group,
+		Version:   version,
+		Kind:      key.Kind,
+		Namespace: key.Namespace,
+		Name:      key.Name,
+		Type:      diffType,
+		Diff:      diff,
+	}
+}
+
+// diffLines returns a line based diff of the two texts
+func diffLines(before, after string) string {
+	dmp := diffmatchpatch.New()
+	beforeChars, afterChars, lines := dmp.DiffLinesToChars(before, after)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(beforeChars, afterChars, false), lines)
+
+	var sb strings.Builder
+	for _, diff := range diffs {
+		switch diff.Type {
+		case diffmatchpatch.DiffInsert:
+			sb.WriteString(prefixLines(diff.Text, "+ "))
+		case diffmatchpatch.DiffDelete:
+			sb.WriteString(prefixLines(diff.Text, "- "))
+		default:
+			sb.WriteString(prefixLines(diff.Text, "  "))
+		}
+	}
+	return sb.String()
+}
+
+// prefixLines adds the prefix to every line of the text
+func prefixLines(text, prefix string) string {
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		sb.WriteString(prefix)
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
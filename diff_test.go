@@ -0,0 +1,54 @@
+package renderer
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDiffResults(t *testing.T) {
+	before := []*unstructured.Unstructured{
+		newObject("apps/v1", "Deployment", "default", "web"),
+		newObject("v1", "Service", "default", "web"),
+		newObject("v1", "ConfigMap", "default", "removed"),
+	}
+	before[0].Object["spec"] = map[string]interface{}{"replicas": int64(1)}
+
+	after := []*unstructured.Unstructured{
+		newObject("v1", "Service", "default", "web"),
+		newObject("apps/v1", "Deployment", "default", "web"),
+		newObject("v1", "Namespace", "", "added"),
+	}
+	after[1].Object["spec"] = map[string]interface{}{"replicas": int64(2)}
+
+	diffs, err := DiffResults(before, after)
+	if err != nil {
+		t.Fatalf("DiffResults failed: %v", err)
+	}
+	if len(diffs) != 3 {
+		t.Fatalf("Expected 3 diffs, got %d: %+v", len(diffs), diffs)
+	}
+
+	expected := []struct {
+		ref      string
+		diffType ResourceDiffType
+		diff     string
+	}{
+		{"apps/v1/Deployment/default/web", ResourceChanged, "  apiVersion: apps/v1\n  kind: Deployment\n  metadata:\n    name: web\n    namespace: default\n  spec:\n-   replicas: 1\n+   replicas: 2\n"},
+		{"v1/ConfigMap/default/removed", ResourceRemoved, "- apiVersion: v1\n- kind: ConfigMap\n- metadata:\n-   name: removed\n-   namespace: default\n"},
+		{"v1/Namespace/added", ResourceAdded, "+ apiVersion: v1\n+ kind: Namespace\n+ metadata:\n+   name: added\n"},
+	}
+	for i, e := range expected {
+		if diffs[i].Ref() != e.ref || diffs[i].Type != e.diffType {
+			t.Errorf("Expected diff %d to be %s %s, got %s %s", i, e.diffType, e.ref, diffs[i].Type, diffs[i].Ref())
+		}
+		if diffs[i].Diff != e.diff {
+			t.Errorf("Unexpected diff of %s:\n%s", e.ref, diffs[i].Diff)
+		}
+	}
+
+	diffs, err = DiffResults(before, before)
+	if err != nil || len(diffs) != 0 {
+		t.Errorf("Expected no diffs between identical renders, got %+v, %v", diffs, err)
+	}
+}
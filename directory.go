@@ -0,0 +1,160 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+)
+
+// DirectoryOptions contains overrides for directory sources
+type DirectoryOptions struct {
+	// Include overrides the include pattern of the source, e.g. "*.yaml,*.yml"
+	Include string
+	// Exclude overrides the exclude pattern of the source
+	Exclude string
+	// MaxDepth limits how deep recursive sources descend into subdirectories. A depth of 1
+	// only includes files directly in the source path, zero is unlimited.
+	MaxDepth int
+	// Recurse includes files in subdirectories even if the source does not recurse
+	Recurse bool
+	// JsonnetExtVars set Jsonnet external variables of .jsonnet files, replacing the
+	// variables of the source with the same name
+	JsonnetExtVars map[string]string
+	// JsonnetTLAs set Jsonnet top-level arguments of .jsonnet files, replacing the arguments
+	// of the source with the same name
+	JsonnetTLAs map[string]string
+}
+
+// TemplateFromDirectory renders a directory of plain manifests without an Application
+func TemplateFromDirectory(ctx context.Context, dir string, opts DirectoryOptions) (*TemplateResult, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	// Setting the directory options makes Argo CD render the source as a directory, even
+	// next to a Chart.yaml or kustomization.yaml. The path is relative to the repository root.
+	source := &v1alpha1.ApplicationSource{
+		Path: ".",
+		Directory: &v1alpha1.ApplicationSourceDirectory{
+			Recurse: opts.Recurse,
+			Include: opts.Include,
+			Exclude: opts.Exclude,
+		},
+	}
+	templateOpts := TemplateOptions{RepoRoot: dir, Directory: DirectoryOptions{
+		MaxDepth:       opts.MaxDepth,
+		JsonnetExtVars: opts.JsonnetExtVars,
+		JsonnetTLAs:    opts.JsonnetTLAs,
+	}}
+
+	// Without an application name Argo CD does not add the tracking label
+	request := newManifestRequest(&v1alpha1.Application{}, source, false, templateOpts)
+	return templateFromRequests(ctx, []*apiclient.ManifestRequest{request}, applicationSettings{}, templateOpts)
+}
+
+// applyDirectoryOptions applies the overrides to the source and rewrites the include and
+// exclude patterns into globs Argo CD understands
+func applyDirectoryOptions(source *v1alpha1.ApplicationSource, opts DirectoryOptions) {
+	if opts.Include == "" && opts.Exclude == "" && opts.MaxDepth == 0 && !opts.Recurse && len(opts.JsonnetExtVars) == 0 && len(opts.JsonnetTLAs) == 0 && source.Directory == nil {
+		return
+	}
+
+	if source.Directory == nil {
+		source.Directory = &v1alpha1.ApplicationSourceDirectory{}
+	}
+	if opts.Include != "" {
+		source.Directory.Include = opts.Include
+	}
+	if opts.Exclude != "" {
+		source.Directory.Exclude = opts.Exclude
+	}
+	if opts.Recurse {
+		source.Directory.Recurse = true
+	}
+	jsonnet := &source.Directory.Jsonnet
+	jsonnet.ExtVars = setJsonnetVars(jsonnet.ExtVars, opts.JsonnetExtVars)
+	jsonnet.TLAs = setJsonnetVars(jsonnet.TLAs, opts.JsonnetTLAs)
+
+	exclude := source.Directory.Exclude
+	if opts.MaxDepth > 0 && source.Directory.Recurse {
+		exclude = strings.Join(append(splitPatterns(exclude), depthPattern(opts.MaxDepth)), ",")
+	}
+
+	source.Directory.Include = globPattern(source.Directory.Include)
+	source.Directory.Exclude = globPattern(exclude)
+}
+
+// setJsonnetVars sets the string variables, replacing variables with the same name and
+// adding the others sorted by name
+func setJsonnetVars(vars []v1alpha1.JsonnetVar, values map[string]string) []v1alpha1.JsonnetVar {
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		index := slices.IndexFunc(vars, func(v v1alpha1.JsonnetVar) bool { return v.Name == name })
+		if index == -1 {
+			vars = append(vars, v1alpha1.JsonnetVar{Name: name, Value: values[name]})
+			continue
+		}
+		vars[index] = v1alpha1.JsonnetVar{Name: name, Value: values[name]}
+	}
+	return vars
+}
+
+// depthPattern returns a pattern matching files nested deeper than maxDepth. Argo CD matches
+// the path relative to the source path and * also matches separators, so a pattern with
+// maxDepth separators matches every file in a subdirectory at that depth or below.
+func depthPattern(maxDepth int) string {
+	return strings.Repeat("*/", maxDepth) + "*"
+}
+
+// globPattern converts a comma-separated list of patterns into a single brace pattern.
+// Argo CD matches a file against the pattern without path separators, so a leading **/
+// would require the file to be in a subdirectory; it is rewritten to also match files
+// at the top level.
+func globPattern(patterns string) string {
+	var parts []string
+	for _, pattern := range splitPatterns(patterns) {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		parts = append(parts, strings.ReplaceAll(pattern, "**/", "{,**/}"))
+	}
+
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
+		return parts[0]
+	default:
+		return "{" + strings.Join(parts, ",") + "}"
+	}
+}
+
+// splitPatterns splits the patterns on commas that are not inside braces
+func splitPatterns(patterns string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range patterns {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, patterns[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, patterns[start:])
+}
@@ -0,0 +1,303 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+func TestTemplateFromDirectoryPatterns(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"deploy.yaml", "deploy.json", "deploy.yml", "secret-db.yaml", "nested/dir/deploy.yaml", "nested/deploy.json", "test/deploy.yaml"} {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		name := strings.NewReplacer("/", "-", ".", "-").Replace(path)
+		manifest := fmt.Sprintf(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": %q}}`, name)
+		if err := os.WriteFile(fullPath, []byte(manifest), 0644); err != nil {
+			t.Fatalf("Failed to write manifest: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		include  string
+		exclude  string
+		expected []string
+	}{
+		// Argo CD matches the relative path and * also matches separators
+		{name: "brace include", include: "{*.yaml,*.json}", expected: []string{"deploy-json", "deploy-yaml", "nested-deploy-json", "nested-dir-deploy-yaml", "secret-db-yaml", "test-deploy-yaml"}},
+		{name: "comma include", include: "*.yml, secret-*.yaml", expected: []string{"deploy-yml", "secret-db-yaml"}},
+		{name: "double star matches top level and nested", include: "**/*.yaml", expected: []string{"deploy-yaml", "nested-dir-deploy-yaml", "secret-db-yaml", "test-deploy-yaml"}},
+		{name: "exclude shadows include", include: "*.yaml", exclude: "secret-*.yaml,test/*", expected: []string{"deploy-yaml", "nested-dir-deploy-yaml"}},
+		{name: "no patterns", expected: []string{"deploy-json", "deploy-yaml", "deploy-yml", "nested-deploy-json", "nested-dir-deploy-yaml", "secret-db-yaml", "test-deploy-yaml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := TemplateFromDirectory(context.Background(), dir, DirectoryOptions{Include: tt.include, Exclude: tt.exclude, Recurse: true})
+			if err != nil {
+				t.Fatalf("TemplateFromDirectory failed: %v", err)
+			}
+			var names []string
+			for _, obj := range result.Objects {
+				names = append(names, obj.GetName())
+			}
+			sort.Strings(names)
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestApplyDirectoryOptions(t *testing.T) {
+	source := &v1alpha1.ApplicationSource{
+		Directory: &v1alpha1.ApplicationSourceDirectory{Include: "*.yaml", Exclude: "*.json"},
+	}
+
+	applyDirectoryOptions(source, DirectoryOptions{Include: "*.yaml,*.yml"})
+
+	if source.Directory.Include != "{*.yaml,*.yml}" {
+		t.Errorf("Expected include override to be converted to a brace pattern, got %q", source.Directory.Include)
+	}
+	if source.Directory.Exclude != "*.json" {
+		t.Errorf("Expected exclude to be preserved, got %q", source.Directory.Exclude)
+	}
+}
+
+func TestTemplateFromApplicationMaxDepth(t *testing.T) {
+	repoRoot := t.TempDir()
+	files := map[string]string{
+		"app/top.yaml":          "top",
+		"app/one/middle.yaml":   "middle",
+		"app/one/two/deep.yaml": "deep",
+	}
+	for path, name := range files {
+		fullPath := filepath.Join(repoRoot, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		manifest := fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\n", name)
+		if err := os.WriteFile(fullPath, []byte(manifest), 0644); err != nil {
+			t.Fatalf("Failed to write manifest: %v", err)
+		}
+	}
+
+	application := `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: depth-app
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: app
+    directory:
+      recurse: true
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`
+	if err := os.WriteFile(filepath.Join(repoRoot, "application.yaml"), []byte(application), 0644); err != nil {
+		t.Fatalf("Failed to write application: %v", err)
+	}
+	t.Chdir(repoRoot)
+
+	tests := []struct {
+		maxDepth int
+		expected []string
+	}{
+		{maxDepth: 0, expected: []string{"deep", "middle", "top"}},
+		{maxDepth: 1, expected: []string{"top"}},
+		{maxDepth: 2, expected: []string{"middle", "top"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("depth %d", tt.maxDepth), func(t *testing.T) {
+			result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+				ApplicationFile: "application.yaml",
+				RepoRoot:        ".",
+				Directory:       DirectoryOptions{MaxDepth: tt.maxDepth},
+			})
+			if err != nil {
+				t.Fatalf("TemplateFromApplication failed: %v", err)
+			}
+
+			var names []string
+			for _, obj := range result.Objects {
+				names = append(names, obj.GetName())
+			}
+			sort.Strings(names)
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestTemplateFromDirectory(t *testing.T) {
+	result, err := TemplateFromDirectory(context.Background(), "examples/directory/input", DirectoryOptions{})
+	if err != nil {
+		t.Fatalf("TemplateFromDirectory failed: %v", err)
+	}
+
+	if len(result.Objects) != 2 {
+		t.Fatalf("Expected 2 objects, got %d", len(result.Objects))
+	}
+	for _, obj := range result.Objects {
+		if _, found := obj.GetLabels()["app.kubernetes.io/instance"]; found {
+			t.Errorf("Expected no tracking label on %s", obj.GetName())
+		}
+	}
+	if len(result.SourceTypes) != 1 || result.SourceTypes[0] != v1alpha1.ApplicationSourceTypeDirectory {
+		t.Errorf("Expected a directory source, got %v", result.SourceTypes)
+	}
+
+	result, err = TemplateFromDirectory(context.Background(), "examples/directory/input", DirectoryOptions{Include: "*-svc.yaml"})
+	if err != nil {
+		t.Fatalf("TemplateFromDirectory failed: %v", err)
+	}
+	if len(result.Objects) != 1 || result.Objects[0].GetKind() != "Service" {
+		t.Errorf("Expected only the Service, got %v", objectKeys(result.Objects))
+	}
+
+	if _, err := TemplateFromDirectory(context.Background(), "examples/directory/app.yaml", DirectoryOptions{}); err == nil {
+		t.Error("Expected an error for a file")
+	}
+}
+
+func TestTemplateFromApplicationMultiDocumentFile(t *testing.T) {
+	repoRoot := t.TempDir()
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: second
+spec:
+  ports:
+  - port: 80
+`
+	if err := os.MkdirAll(filepath.Join(repoRoot, "app"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoRoot, "app", "resources.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: multi
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: app
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`)
+	t.Chdir(repoRoot)
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	keys := objectKeys(result.Objects)
+	expected := []string{"ConfigMap/default/first", "Service/default/second"}
+	if strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+}
+
+func TestTemplateFromDirectoryMixedFormats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+		"configmap.json":  `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings"}, "data": {"mode": "json"}}`,
+		"service.json":    "{\n  \"apiVersion\": \"v1\",\n  \"kind\": \"Service\",\n  \"metadata\": {\"name\": \"web\"}\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	result, err := TemplateFromDirectory(context.Background(), dir, DirectoryOptions{})
+	if err != nil {
+		t.Fatalf("TemplateFromDirectory failed: %v", err)
+	}
+
+	keys := objectKeys(result.Objects)
+	sort.Strings(keys)
+	expected := []string{"ConfigMap/settings", "Deployment/web", "Service/web"}
+	if strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+	if output := formatOutput(result); !strings.Contains(output, "mode: json") || strings.Contains(output, `"mode"`) {
+		t.Errorf("Expected the JSON resources as YAML, got:\n%s", output)
+	}
+}
+
+func TestTemplateFromDirectoryJsonnet(t *testing.T) {
+	dir := t.TempDir()
+	deployment := `function(replicas=1) {
+  apiVersion: "apps/v1",
+  kind: "Deployment",
+  metadata: { name: "web" },
+  spec: {
+    replicas: std.parseInt(replicas),
+    template: { spec: { containers: [{ name: "web", image: std.extVar("image") }] } },
+  },
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "deployment.jsonnet"), []byte(deployment), 0644); err != nil {
+		t.Fatalf("Failed to write jsonnet: %v", err)
+	}
+
+	result, err := TemplateFromDirectory(context.Background(), dir, DirectoryOptions{
+		JsonnetExtVars: map[string]string{"image": "nginx:1.27"},
+		JsonnetTLAs:    map[string]string{"replicas": "3"},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromDirectory failed: %v", err)
+	}
+
+	if len(result.Objects) != 1 || result.Objects[0].GetKind() != "Deployment" {
+		t.Fatalf("Expected the Deployment, got %v", objectKeys(result.Objects))
+	}
+	output := formatOutput(result)
+	if !strings.Contains(output, "replicas: 3") || !strings.Contains(output, "image: nginx:1.27") {
+		t.Errorf("Expected the external variable and top-level argument in the output, got:\n%s", output)
+	}
+}
+
+func TestSetJsonnetVars(t *testing.T) {
+	vars := []v1alpha1.JsonnetVar{{Name: "env", Value: "prod"}, {Name: "config", Value: "{}", Code: true}}
+	vars = setJsonnetVars(vars, map[string]string{"env": "dev", "region": "eu", "cluster": "local"})
+
+	expected := []v1alpha1.JsonnetVar{
+		{Name: "env", Value: "dev"},
+		{Name: "config", Value: "{}", Code: true},
+		{Name: "cluster", Value: "local"},
+		{Name: "region", Value: "eu"},
+	}
+	if fmt.Sprint(vars) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, vars)
+	}
+}
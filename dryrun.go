@@ -0,0 +1,58 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+)
+
+// DryRunPrint returns the equivalent command of every source instead of rendering it
+const DryRunPrint = "print"
+
+// dryRunCommands returns the command equivalent to rendering each source, one per source.
+// Nothing is executed and remote Helm charts are not downloaded.
+func dryRunCommands(ctx context.Context, requests []*apiclient.ManifestRequest, opts TemplateOptions) ([]string, error) {
+	repoRoot := opts.RepoRoot
+	if repoRoot == "" {
+		repoRoot = "."
+	}
+
+	commands := make([]string, 0, len(requests))
+	for sourceIndex, q := range requests {
+		source := q.ApplicationSource
+		if source.Chart != "" {
+			commands = append(commands, remoteChartCommand(q, opts))
+			continue
+		}
+
+		appPath := sourcePath(source.Path, repoRoot)
+		appSourceType, err := DetectSourceType(ctx, source, appPath, repoRoot, q.AppName)
+		if err != nil {
+			return nil, &RenderError{SourceIndex: sourceIndex, Phase: RenderPhaseDetect, Err: fmt.Errorf("error getting app source type: %w", err)}
+		}
+		commands = append(commands, equivalentCommand(ctx, appSourceType, appPath, q, opts))
+	}
+	return commands, nil
+}
+
+// remoteChartCommand returns the helm template command of a chart that has not been downloaded
+func remoteChartCommand(q *apiclient.ManifestRequest, opts TemplateOptions) string {
+	source := q.ApplicationSource
+	chartRef := source.Chart
+	var repoArgs []string
+	if isOCIRepo(source.RepoURL) {
+		chartRef = strings.TrimSuffix(source.RepoURL, "/") + "/" + source.Chart
+	} else {
+		repoArgs = append(repoArgs, "--repo", source.RepoURL)
+	}
+	if version := opts.Helm.chartVersion(*source); version != "" {
+		repoArgs = append(repoArgs, "--version", version)
+	}
+
+	// The repository arguments follow the chart, before a post-renderer pipe
+	args := slices.Insert(helmTemplateCommand(chartRef, q, opts), 3, repoArgs...)
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,102 @@
+package renderer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTemplateFromApplicationDryRunPrint(t *testing.T) {
+	// A dry run must not download the chart, so no helm binary is available
+	t.Setenv("PATH", t.TempDir())
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+spec:
+  project: default
+  sources:
+  - repoURL: https://charts.example.com
+    chart: guestbook
+    targetRevision: 1.2.3
+    helm:
+      releaseName: my-release
+      valueFiles:
+      - values-prod.yaml
+      parameters:
+      - name: replicaCount
+        value: "2"
+      - name: image.tag
+        value: "1.0"
+        forceString: true
+  - repoURL: oci://registry.example.com/charts
+    chart: redis
+    targetRevision: 18.0.0
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: guestbook
+`)
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		KubeVersion:     "1.29",
+		DryRun:          DryRunPrint,
+		Helm: HelmOptions{
+			ExtraValueFiles: []string{"local.yaml"},
+			Parameters:      []HelmParameter{{Name: "debug", Value: "true"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if len(result.Objects) != 0 {
+		t.Errorf("Expected no objects in a dry run, got %d", len(result.Objects))
+	}
+	if len(result.Commands) != 3 {
+		t.Fatalf("Expected 3 commands, got %d: %v", len(result.Commands), result.Commands)
+	}
+
+	expected := [][]string{
+		{
+			"helm template guestbook --repo https://charts.example.com --version 1.2.3",
+			"--name-template my-release",
+			"--namespace guestbook",
+			"--kube-version 1.29",
+			"--include-crds",
+			"--values values-prod.yaml",
+			"--set replicaCount=2",
+			"--set-string image.tag=1.0",
+			"--values local.yaml",
+			"--set debug=true",
+		},
+		{
+			"helm template oci://registry.example.com/charts/redis --version 18.0.0",
+			"--name-template guestbook",
+		},
+		{
+			"read manifests from examples/directory/input",
+		},
+	}
+	for i, flags := range expected {
+		for _, flag := range flags {
+			if !strings.Contains(result.Commands[i], flag) {
+				t.Errorf("Expected command %d to contain %q, got:\n%s", i, flag, result.Commands[i])
+			}
+		}
+	}
+}
+
+func TestTemplateFromApplicationDryRunInvalid(t *testing.T) {
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/directory/app.yaml",
+		RepoRoot:        ".",
+		DryRun:          "server",
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid dry run mode") {
+		t.Errorf("Expected an invalid dry run mode error, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package renderer
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	kubeutil "github.com/argoproj/gitops-engine/pkg/utils/kube"
+)
+
+// DuplicateResource is a resource that was rendered more than once
+type DuplicateResource struct {
+	Group     string
+	Version   string
+	Kind      string
+	Namespace string
+	Name      string
+	// SourceIndices are the indices of the sources that rendered the resource, once per occurrence
+	SourceIndices []int
+}
+
+// findDuplicates returns the resources reported by the deduplication conditions, in the order
+// they were first rendered. objectSources holds the source index of each object. The objects
+// must already have been deduplicated so their namespaces are normalized.
+func findDuplicates(objects []*unstructured.Unstructured, objectSources []int, conditions []v1alpha1.ApplicationCondition) []DuplicateResource {
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	var keys []kubeutil.ResourceKey
+	sourcesByKey := make(map[kubeutil.ResourceKey][]int)
+	versionByKey := make(map[kubeutil.ResourceKey]string)
+	for i, obj := range objects {
+		if obj.GetName() == "" {
+			continue
+		}
+		key := kubeutil.GetResourceKey(obj)
+		if _, found := sourcesByKey[key]; !found {
+			keys = append(keys, key)
+		}
+		sourcesByKey[key] = append(sourcesByKey[key], objectSources[i])
+		versionByKey[key] = obj.GroupVersionKind().Version
+	}
+
+	var duplicates []DuplicateResource
+	for _, key := range keys {
+		if len(sourcesByKey[key]) < 2 || !hasRepeatedResourceCondition(conditions, key) {
+			continue
+		}
+		duplicates = append(duplicates, DuplicateResource{
+			Group:         key.Group,
+			Version:       versionByKey[key],
+			Kind:          key.Kind,
+			Namespace:     key.Namespace,
+			Name:          key.Name,
+			SourceIndices: sourcesByKey[key],
+		})
+	}
+	return duplicates
+}
+
+// hasRepeatedResourceCondition reports whether a condition reports the resource as repeated
+func hasRepeatedResourceCondition(conditions []v1alpha1.ApplicationCondition, key kubeutil.ResourceKey) bool {
+	for _, condition := range conditions {
+		if condition.Type == v1alpha1.ApplicationConditionRepeatedResourceWarning &&
+			strings.HasPrefix(condition.Message, "Resource "+key.String()+" ") {
+			return true
+		}
+	}
+	return false
+}
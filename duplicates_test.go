@@ -0,0 +1,122 @@
+package renderer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemplateFromApplicationDuplicates(t *testing.T) {
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+spec:
+  project: default
+  sources:
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+    directory:
+      include: guestbook-ui-deployment.yaml
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`)
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	expected := []DuplicateResource{
+		{
+			Group:         "apps",
+			Version:       "v1",
+			Kind:          "Deployment",
+			Namespace:     "default",
+			Name:          "guestbook-ui",
+			SourceIndices: []int{0, 1},
+		},
+	}
+	if !reflect.DeepEqual(result.Duplicates, expected) {
+		t.Errorf("Expected duplicates %+v, got %+v", expected, result.Duplicates)
+	}
+	if len(result.Warnings) != 1 {
+		t.Fatalf("Expected one duplicate warning, got %v", result.Warnings)
+	}
+	warning := result.Warnings[0]
+	if warning.Severity != WarningSeverityWarning || warning.SourceIndex != -1 || warning.ResourceRef != "apps/v1/Deployment/default/guestbook-ui" {
+		t.Errorf("Unexpected duplicate warning %+v", warning)
+	}
+	if messages := result.WarningStrings(); len(messages) != 1 || messages[0] != warning.Message {
+		t.Errorf("Expected the warning message, got %v", messages)
+	}
+}
+
+func TestTemplateFromApplicationWithoutDuplicates(t *testing.T) {
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/directory/app.yaml",
+		RepoRoot:        ".",
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if len(result.Duplicates) != 0 {
+		t.Errorf("Expected no duplicates, got %+v", result.Duplicates)
+	}
+}
+
+func TestTemplateFromApplicationWarningsAsErrors(t *testing.T) {
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+spec:
+  project: default
+  sources:
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+    directory:
+      include: guestbook-ui-svc.yaml
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`)
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile:  appFile,
+		RepoRoot:         ".",
+		WarningsAsErrors: true,
+	})
+	var warningsErr *WarningsAsErrorsError
+	if !errors.As(err, &warningsErr) {
+		t.Fatalf("Expected a WarningsAsErrorsError, got %v", err)
+	}
+	if len(warningsErr.Warnings) != 1 || !strings.Contains(warningsErr.Error(), "guestbook-ui") {
+		t.Errorf("Expected the duplicate Service warning, got %v", warningsErr.Warnings)
+	}
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile:  appFile,
+		RepoRoot:         ".",
+		WarningsAsErrors: true,
+		IgnoreWarnings:   []string{"guestbook-ui"},
+	})
+	if err != nil {
+		t.Fatalf("Expected the ignored warning not to fail, got %v", err)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("Expected the warning to be removed, got %v", result.Warnings)
+	}
+}
@@ -0,0 +1,91 @@
+package renderer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+// Phases in which rendering an Application can fail
+const (
+	// RenderPhaseParse is reading and parsing the Application
+	RenderPhaseParse = "parse"
+	// RenderPhaseFetch is downloading remote Helm charts
+	RenderPhaseFetch = "fetch"
+	// RenderPhaseDetect is detecting the type of a source
+	RenderPhaseDetect = "detect"
+	// RenderPhaseRender is generating the manifests of a source
+	RenderPhaseRender = "render"
+)
+
+// RenderError is returned when rendering an Application fails
+type RenderError struct {
+	// SourceIndex is the zero-based index of the failed source, or -1 if the error is not
+	// specific to a source
+	SourceIndex int
+	// SourceType is the detected type of the failed source, if it is known
+	SourceType v1alpha1.ApplicationSourceType
+	// Phase is the phase rendering failed in, one of the RenderPhase constants
+	Phase string
+	Err   error
+}
+
+func (e *RenderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// AsRenderError returns the RenderError in the error chain of err
+func AsRenderError(err error) (*RenderError, bool) {
+	var renderErr *RenderError
+	if errors.As(err, &renderErr) {
+		return renderErr, true
+	}
+	return nil, false
+}
+
+// ManifestSizeLimitError is returned when the rendered manifests exceed MaxManifestSize
+type ManifestSizeLimitError struct {
+	// Actual is the combined size of the rendered manifests
+	Actual resource.Quantity
+	// Limit is the maximum combined size
+	Limit resource.Quantity
+}
+
+func (e *ManifestSizeLimitError) Error() string {
+	return fmt.Sprintf("rendered manifests of %s exceed the size limit of %s", e.Actual.String(), e.Limit.String())
+}
+
+// WarningsAsErrorsError is returned when rendering produced warnings and WarningsAsErrors is set
+type WarningsAsErrorsError struct {
+	Warnings []Warning
+}
+
+func (e *WarningsAsErrorsError) Error() string {
+	return strings.Join(warningMessages(e.Warnings), "\n")
+}
+
+// ignoreWarnings returns the warnings that contain none of the ignored substrings
+func ignoreWarnings(warnings []Warning, ignored []string) []Warning {
+	if len(ignored) == 0 {
+		return warnings
+	}
+
+	var kept []Warning
+	for _, warning := range warnings {
+		if !slices.ContainsFunc(ignored, func(substring string) bool {
+			return strings.Contains(warning.Message, substring)
+		}) {
+			kept = append(kept, warning)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,144 @@
+package renderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+)
+
+func writeApplication(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write application: %v", err)
+	}
+	return path
+}
+
+func TestRenderErrorPhases(t *testing.T) {
+	renderFailure := errors.New("template failed")
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		return nil, renderFailure
+	})
+	t.Setenv("PATH", t.TempDir())
+
+	tests := []struct {
+		name        string
+		application string
+		phase       string
+		sourceIndex int
+		sourceType  v1alpha1.ApplicationSourceType
+	}{
+		{
+			name:        "parse",
+			application: "apiVersion: v1\nkind: ConfigMap\n",
+			phase:       RenderPhaseParse,
+			sourceIndex: -1,
+		},
+		{
+			name: "fetch",
+			application: `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: fetch
+spec:
+  source:
+    repoURL: https://charts.example.com
+    chart: nginx
+    targetRevision: 1.0.0
+`,
+			phase:       RenderPhaseFetch,
+			sourceIndex: 0,
+			sourceType:  v1alpha1.ApplicationSourceTypeHelm,
+		},
+		{
+			name: "detect",
+			application: `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: detect
+spec:
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+    helm: {}
+    kustomize: {}
+`,
+			phase:       RenderPhaseDetect,
+			sourceIndex: 0,
+		},
+		{
+			name: "render",
+			application: `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: render
+spec:
+  sources:
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+`,
+			phase:       RenderPhaseRender,
+			sourceIndex: 0,
+			sourceType:  v1alpha1.ApplicationSourceTypeDirectory,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+				ApplicationFile: writeApplication(t, tt.application),
+				RepoRoot:        ".",
+				CacheDir:        t.TempDir(),
+			})
+
+			var renderErr *RenderError
+			if !errors.As(err, &renderErr) {
+				t.Fatalf("Expected RenderError, got %v", err)
+			}
+			if renderErr.Phase != tt.phase {
+				t.Errorf("Expected phase %s, got %s (%v)", tt.phase, renderErr.Phase, err)
+			}
+			if renderErr.SourceIndex != tt.sourceIndex {
+				t.Errorf("Expected source index %d, got %d", tt.sourceIndex, renderErr.SourceIndex)
+			}
+			if renderErr.SourceType != tt.sourceType {
+				t.Errorf("Expected source type %q, got %q", tt.sourceType, renderErr.SourceType)
+			}
+		})
+	}
+
+	t.Run("unwrap", func(t *testing.T) {
+		_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+			ApplicationFile: writeApplication(t, tests[3].application),
+			RepoRoot:        ".",
+		})
+		if !errors.Is(err, renderFailure) {
+			t.Errorf("Expected the original error to be unwrapped, got %v", err)
+		}
+	})
+}
+
+func TestAsRenderError(t *testing.T) {
+	wrapped := fmt.Errorf("outer: %w", &RenderError{SourceIndex: 1, Phase: RenderPhaseRender, Err: errors.New("inner")})
+
+	renderErr, ok := AsRenderError(wrapped)
+	if !ok || renderErr.SourceIndex != 1 {
+		t.Errorf("Expected RenderError for source 1, got %v", renderErr)
+	}
+	if wrapped.Error() != "outer: inner" {
+		t.Errorf("Expected the message of the original error, got %q", wrapped.Error())
+	}
+
+	if _, ok := AsRenderError(errors.New("plain")); ok {
+		t.Error("Expected no RenderError for a plain error")
+	}
+}
@@ -0,0 +1,55 @@
+package renderer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// kustomizationNaming names files <kind>-<name>.yaml for the export as a kustomization
+type kustomizationNaming struct{}
+
+func (kustomizationNaming) FileName(obj *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s-%s.yaml", fileNamePart(strings.ToLower(obj.GetKind())), fileNamePart(obj.GetName()))
+}
+
+// ToKustomization writes every rendered object to <kind>-<name>.yaml in the output directory
+// and a kustomization.yaml that lists the files as resources, so the render can be used as the
+// base of another kustomize layer. Objects of the same kind and name, e.g. in different
+// namespaces, are written to the same file.
+func ToKustomization(result *TemplateResult, outputDir string) error {
+	naming := kustomizationNaming{}
+	if err := WriteManifests(outputDir, result.Objects, naming); err != nil {
+		return err
+	}
+
+	resources := []string{}
+	seen := map[string]bool{}
+	for _, obj := range result.Objects {
+		name := naming.FileName(obj)
+		if !seen[name] {
+			seen[name] = true
+			resources = append(resources, name)
+		}
+	}
+	data, err := yaml.Marshal(kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  resources,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal kustomization: %w", err)
+	}
+	// WriteManifests does not create the directory if nothing was rendered
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "kustomization.yaml"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write kustomization: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package renderer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestToKustomization(t *testing.T) {
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/directory/app.yaml",
+		RepoRoot:        ".",
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	// Objects of the same kind and name share a file
+	result.Objects = append(result.Objects, newObject("v1", "ConfigMap", "other", "shared"), newObject("v1", "ConfigMap", "default", "shared"))
+
+	dir := filepath.Join(t.TempDir(), "export")
+	if err := ToKustomization(result, dir); err != nil {
+		t.Fatalf("ToKustomization failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read kustomization: %v", err)
+	}
+	var k kustomization
+	if err := yaml.Unmarshal(data, &k); err != nil {
+		t.Fatalf("Failed to parse kustomization: %v", err)
+	}
+	if k.APIVersion != "kustomize.config.k8s.io/v1beta1" || k.Kind != "Kustomization" {
+		t.Errorf("Unexpected kustomization type:\n%s", data)
+	}
+	if len(k.Resources) != len(result.Objects)-1 {
+		t.Errorf("Expected %d resources, got %v", len(result.Objects)-1, k.Resources)
+	}
+	for _, resource := range k.Resources {
+		if _, err := os.Stat(filepath.Join(dir, resource)); err != nil {
+			t.Errorf("Expected resource %s to be written: %v", resource, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "configmap-shared.yaml")); err != nil {
+		t.Errorf("Expected configmap-shared.yaml to be written: %v", err)
+	}
+
+	if _, err := exec.LookPath("kustomize"); err != nil {
+		t.Skip("kustomize is required to build the exported kustomization")
+	}
+	output, err := exec.Command("kustomize", "build", dir).Output()
+	if err != nil {
+		t.Fatalf("kustomize build failed: %v", err)
+	}
+	documents, err := decodeObjects(bytes.NewReader(output))
+	if err != nil {
+		t.Fatalf("Failed to parse kustomize output: %v", err)
+	}
+	// kustomize may reorder the resources, so compare them by reference
+	built := map[string]map[string]interface{}{}
+	for _, document := range documents {
+		built[objectRef(&unstructured.Unstructured{Object: document})] = document
+	}
+	if len(built) != len(result.Objects) {
+		t.Errorf("Expected %d objects from kustomize, got %d", len(result.Objects), len(built))
+	}
+	for _, obj := range result.Objects {
+		expected, _ := yaml.Marshal(obj.Object)
+		actual, _ := yaml.Marshal(built[objectRef(obj)])
+		if string(actual) != string(expected) {
+			t.Errorf("Expected kustomize to build %s unchanged, got:\n%s", objectRef(obj), actual)
+		}
+	}
+}
+
+func TestToKustomizationEmpty(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "export")
+	if err := ToKustomization(&TemplateResult{}, dir); err != nil {
+		t.Fatalf("ToKustomization failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read kustomization: %v", err)
+	}
+	if string(data) != "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources: []\n" {
+		t.Errorf("Unexpected kustomization:\n%s", data)
+	}
+}
@@ -0,0 +1,122 @@
+package renderer
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// FilterBySelector returns the objects whose labels match the Kubernetes label selector,
+// e.g. "app=frontend,tier!=cache" or "environment in (production,staging)".
+// An empty selector matches every object.
+func FilterBySelector(objects []*unstructured.Unstructured, selector string) ([]*unstructured.Unstructured, error) {
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %w", selector, err)
+	}
+
+	var filtered []*unstructured.Unstructured
+	for _, obj := range objects {
+		if parsed.Matches(labels.Set(obj.GetLabels())) {
+			filtered = append(filtered, obj)
+		}
+	}
+	return filtered, nil
+}
+
+// kindAliases are short names accepted for kinds in addition to the kind itself
+var kindAliases = map[string]string{
+	"crd": "customresourcedefinition",
+}
+
+// FilterByKind returns the objects of the given kinds, compared case-insensitively.
+// No kinds matches every object.
+func FilterByKind(objects []*unstructured.Unstructured, kinds []string) []*unstructured.Unstructured {
+	if len(kinds) == 0 {
+		return objects
+	}
+	return filterKinds(objects, kinds, true)
+}
+
+// ExcludeByKind returns the objects that are not of the given kinds, compared case-insensitively
+func ExcludeByKind(objects []*unstructured.Unstructured, kinds []string) []*unstructured.Unstructured {
+	if len(kinds) == 0 {
+		return objects
+	}
+	return filterKinds(objects, kinds, false)
+}
+
+// filterKinds keeps the objects whose kind is in kinds when include is set, and the others otherwise
+func filterKinds(objects []*unstructured.Unstructured, kinds []string, include bool) []*unstructured.Unstructured {
+	set := make(map[string]bool, len(kinds))
+	for _, kind := range kinds {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if alias, ok := kindAliases[kind]; ok {
+			kind = alias
+		}
+		set[kind] = true
+	}
+
+	var filtered []*unstructured.Unstructured
+	for _, obj := range objects {
+		if set[strings.ToLower(obj.GetKind())] == include {
+			filtered = append(filtered, obj)
+		}
+	}
+	return filtered
+}
+
+// FilterByAnnotation returns the objects that have the annotation set to the value
+func FilterByAnnotation(objects []*unstructured.Unstructured, key, value string) []*unstructured.Unstructured {
+	return filterAnnotation(objects, key, value, true)
+}
+
+// ExcludeByAnnotation returns the objects that do not have the annotation set to the value
+func ExcludeByAnnotation(objects []*unstructured.Unstructured, key, value string) []*unstructured.Unstructured {
+	return filterAnnotation(objects, key, value, false)
+}
+
+// filterAnnotations returns the objects having all include annotations and none of the exclude annotations
+func filterAnnotations(objects []*unstructured.Unstructured, include, exclude map[string]string) []*unstructured.Unstructured {
+	for key, value := range include {
+		objects = FilterByAnnotation(objects, key, value)
+	}
+	for key, value := range exclude {
+		objects = ExcludeByAnnotation(objects, key, value)
+	}
+	return objects
+}
+
+// filterAnnotation keeps the objects with the annotation value when include is set, and the others otherwise
+func filterAnnotation(objects []*unstructured.Unstructured, key, value string, include bool) []*unstructured.Unstructured {
+	var filtered []*unstructured.Unstructured
+	for _, obj := range objects {
+		actual, found := obj.GetAnnotations()[key]
+		if (found && actual == value) == include {
+			filtered = append(filtered, obj)
+		}
+	}
+	return filtered
+}
+
+// hookAnnotation marks resources Argo CD runs as sync hooks instead of syncing them
+const hookAnnotation = "argocd.argoproj.io/hook"
+
+// IsHookResource reports whether the object is an Argo CD hook, whatever the hook type
+func IsHookResource(obj *unstructured.Unstructured) bool {
+	_, found := obj.GetAnnotations()[hookAnnotation]
+	return found
+}
+
+// filterHooks keeps the hook resources when hooks is set, and the others otherwise
+func filterHooks(objects []*unstructured.Unstructured, hooks bool) []*unstructured.Unstructured {
+	var filtered []*unstructured.Unstructured
+	for _, obj := range objects {
+		if IsHookResource(obj) == hooks {
+			filtered = append(filtered, obj)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,301 @@
+package renderer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestFilterBySelector(t *testing.T) {
+	newLabeled := func(name string, labels map[string]string) *unstructured.Unstructured {
+		obj := newObject("v1", "ConfigMap", "default", name)
+		obj.SetLabels(labels)
+		return obj
+	}
+	objects := []*unstructured.Unstructured{
+		newLabeled("frontend-prod", map[string]string{"app": "frontend", "environment": "production"}),
+		newLabeled("frontend-dev", map[string]string{"app": "frontend", "environment": "development"}),
+		newLabeled("backend-staging", map[string]string{"app": "backend", "environment": "staging"}),
+		newLabeled("unlabeled", nil),
+	}
+
+	tests := []struct {
+		name     string
+		selector string
+		expected []string
+	}{
+		{
+			name:     "empty selector",
+			selector: "",
+			expected: []string{"ConfigMap/default/frontend-prod", "ConfigMap/default/frontend-dev", "ConfigMap/default/backend-staging", "ConfigMap/default/unlabeled"},
+		},
+		{
+			name:     "exact match",
+			selector: "app=frontend,environment=production",
+			expected: []string{"ConfigMap/default/frontend-prod"},
+		},
+		{
+			name:     "set based",
+			selector: "environment in (production,staging)",
+			expected: []string{"ConfigMap/default/frontend-prod", "ConfigMap/default/backend-staging"},
+		},
+		{
+			name:     "negation",
+			selector: "app!=frontend",
+			expected: []string{"ConfigMap/default/backend-staging", "ConfigMap/default/unlabeled"},
+		},
+		{
+			name:     "does not exist",
+			selector: "!app",
+			expected: []string{"ConfigMap/default/unlabeled"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, err := FilterBySelector(objects, tt.selector)
+			if err != nil {
+				t.Fatalf("FilterBySelector failed: %v", err)
+			}
+			if keys := objectKeys(filtered); !reflect.DeepEqual(keys, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, keys)
+			}
+		})
+	}
+
+	t.Run("invalid selector", func(t *testing.T) {
+		if _, err := FilterBySelector(objects, "app in (frontend"); err == nil {
+			t.Error("Expected an error for an invalid selector")
+		}
+	})
+}
+
+func TestFilterByKind(t *testing.T) {
+	objects := []*unstructured.Unstructured{
+		newObject("apps/v1", "Deployment", "default", "web"),
+		newObject("v1", "Service", "default", "web"),
+		newObject("v1", "Secret", "default", "web"),
+		newObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "widgets.example.com"),
+	}
+
+	if filtered := FilterByKind(objects, nil); len(filtered) != len(objects) {
+		t.Errorf("Expected no kinds to keep all %d objects, got %v", len(objects), objectKeys(filtered))
+	}
+
+	expected := []string{"Deployment/default/web", "Service/default/web"}
+	if keys := objectKeys(FilterByKind(objects, []string{"deployment", "SERVICE"})); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+
+	expected = []string{"Deployment/default/web", "Service/default/web"}
+	if keys := objectKeys(ExcludeByKind(objects, []string{"CRD", "Secret"})); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+}
+
+func TestTemplateFromApplicationKinds(t *testing.T) {
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`)
+
+	tests := []struct {
+		name         string
+		includeKinds []string
+		excludeKinds []string
+		expected     []string
+	}{
+		{name: "all kinds", expected: []string{"Deployment", "Service"}},
+		{name: "include", includeKinds: []string{"service"}, expected: []string{"Service"}},
+		{name: "exclude", excludeKinds: []string{"Service"}, expected: []string{"Deployment"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+				ApplicationFile: appFile,
+				RepoRoot:        ".",
+				IncludeKinds:    tt.includeKinds,
+				ExcludeKinds:    tt.excludeKinds,
+			})
+			if err != nil {
+				t.Fatalf("TemplateFromApplication failed: %v", err)
+			}
+
+			var kinds []string
+			for _, obj := range result.Objects {
+				kinds = append(kinds, obj.GetKind())
+			}
+			if !reflect.DeepEqual(kinds, tt.expected) {
+				t.Errorf("Expected kinds %v, got %v", tt.expected, kinds)
+			}
+		})
+	}
+}
+
+func TestFilterAnnotations(t *testing.T) {
+	newAnnotated := func(name string, annotations map[string]string) *unstructured.Unstructured {
+		obj := newObject("batch/v1", "Job", "default", name)
+		obj.SetAnnotations(annotations)
+		return obj
+	}
+	objects := []*unstructured.Unstructured{
+		newAnnotated("migrate", map[string]string{"argocd.argoproj.io/hook": "PreSync", "argocd.argoproj.io/hook-delete-policy": "HookSucceeded"}),
+		newAnnotated("backup", map[string]string{"argocd.argoproj.io/hook": "PreSync"}),
+		newAnnotated("notify", map[string]string{"argocd.argoproj.io/hook": "PostSync"}),
+		newAnnotated("worker", nil),
+	}
+
+	tests := []struct {
+		name     string
+		include  map[string]string
+		exclude  map[string]string
+		expected []string
+	}{
+		{
+			name:     "no filters",
+			expected: []string{"Job/default/migrate", "Job/default/backup", "Job/default/notify", "Job/default/worker"},
+		},
+		{
+			name:     "include",
+			include:  map[string]string{"argocd.argoproj.io/hook": "PreSync"},
+			expected: []string{"Job/default/migrate", "Job/default/backup"},
+		},
+		{
+			name:     "include all must match",
+			include:  map[string]string{"argocd.argoproj.io/hook": "PreSync", "argocd.argoproj.io/hook-delete-policy": "HookSucceeded"},
+			expected: []string{"Job/default/migrate"},
+		},
+		{
+			name:     "exclude",
+			exclude:  map[string]string{"argocd.argoproj.io/hook": "PreSync"},
+			expected: []string{"Job/default/notify", "Job/default/worker"},
+		},
+		{
+			name:     "exclude any",
+			exclude:  map[string]string{"argocd.argoproj.io/hook": "PreSync", "argocd.argoproj.io/hook-delete-policy": "HookSucceeded"},
+			expected: []string{"Job/default/notify", "Job/default/worker"},
+		},
+		{
+			name:     "include and exclude",
+			include:  map[string]string{"argocd.argoproj.io/hook": "PreSync"},
+			exclude:  map[string]string{"argocd.argoproj.io/hook-delete-policy": "HookSucceeded"},
+			expected: []string{"Job/default/backup"},
+		},
+		{
+			name:     "value must match",
+			include:  map[string]string{"argocd.argoproj.io/hook": "Sync"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if keys := objectKeys(filterAnnotations(objects, tt.include, tt.exclude)); !reflect.DeepEqual(keys, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, keys)
+			}
+		})
+	}
+}
+
+func TestTemplateFromApplicationHooks(t *testing.T) {
+	repoRoot := t.TempDir()
+	manifests := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    argocd.argoproj.io/hook: PreSync
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: smoke-test
+  annotations:
+    argocd.argoproj.io/hook: PostSync
+    argocd.argoproj.io/hook-delete-policy: HookSucceeded
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  annotations:
+    argocd.argoproj.io/sync-wave: "1"
+`
+	if err := os.Mkdir(filepath.Join(repoRoot, "app"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoRoot, "app", "resources.yaml"), []byte(manifests), 0644); err != nil {
+		t.Fatalf("Failed to write manifests: %v", err)
+	}
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: hooks
+spec:
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: app
+  destination:
+    namespace: default
+`)
+
+	tests := []struct {
+		name         string
+		excludeHooks bool
+		hooksOnly    bool
+		expected     []string
+	}{
+		{name: "all resources", expected: []string{"Job", "Pod", "ConfigMap"}},
+		{name: "exclude hooks", excludeHooks: true, expected: []string{"ConfigMap"}},
+		{name: "hooks only", hooksOnly: true, expected: []string{"Job", "Pod"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+				ApplicationFile:      appFile,
+				RepoRoot:             repoRoot,
+				ExcludeHookResources: tt.excludeHooks,
+				HooksOnly:            tt.hooksOnly,
+			})
+			if err != nil {
+				t.Fatalf("TemplateFromApplication failed: %v", err)
+			}
+
+			var kinds []string
+			for _, obj := range result.Objects {
+				kinds = append(kinds, obj.GetKind())
+			}
+			if !reflect.DeepEqual(kinds, tt.expected) {
+				t.Errorf("Expected kinds %v, got %v", tt.expected, kinds)
+			}
+		})
+	}
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile:      appFile,
+		RepoRoot:             repoRoot,
+		ExcludeHookResources: true,
+		HooksOnly:            true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Errorf("Expected an error when both hook options are set, got %v", err)
+	}
+}
@@ -0,0 +1,1066 @@
+package renderer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+// HelmOptions contains overrides that are applied to every Helm source of the Application
+type HelmOptions struct {
+	// Parameters are added after the parameters defined in the Application
+	Parameters []HelmParameter
+	// UpdateDependencies runs helm dependency update before rendering. Without it, charts whose
+	// dependencies are missing or older than Chart.lock are rendered with a warning.
+	UpdateDependencies bool
+	// PostRenderer is a program the rendered manifests are piped through, like helm --post-renderer
+	PostRenderer string
+	// PostRendererArgs are passed to the post-renderer program
+	PostRendererArgs []string
+	// ExtraValueFiles are merged over the values of the source, after all values from the
+	// Application. Relative paths are resolved against the current working directory.
+	ExtraValueFiles []string
+	// ExtraFileParameters set values to the content of a file, like helm --set-file.
+	// Relative paths are resolved against the current working directory.
+	ExtraFileParameters []HelmFileParameter
+	// APIVersions are added to the API versions passed to helm template with --api-versions,
+	// e.g. networking.k8s.io/v1/Ingress
+	APIVersions []string
+	// IncludeCrds renders the CRDs of the chart even when the source sets skipCrds
+	IncludeCrds bool
+	// LintBeforeRender runs helm lint with the values of the source before rendering it
+	LintBeforeRender bool
+	// SOPSDecrypt decrypts the SOPS encrypted value files of the source with sops from PATH
+	// before rendering
+	SOPSDecrypt bool
+	// ExpandEnvInValues expands $VAR and ${VAR} references to environment variables in the
+	// value files and the inline values of the source
+	ExpandEnvInValues bool
+	// GenerateName renders charts with a generated release name like helm --generate-name,
+	// instead of the release name of the source or the Application name
+	GenerateName bool
+	// ChartDigest pins the remote chart to an OCI digest like sha256:<hex> instead of the
+	// targetRevision of the source. Applications with more than one chart source are rejected.
+	ChartDigest string
+	// ForceStringParameters passes every parameter of the source and of the options with
+	// --set-string, so values like 1.10 are not coerced to numbers
+	ForceStringParameters bool
+	// BinaryPath is the helm binary to use, "helm" from PATH if empty. The binary must also be
+	// first in PATH under the name helm: it is run directly to pull, lint and update the
+	// dependencies of charts, but Argo CD runs helm template from PATH, which the library does
+	// not change. Rendering a Helm source fails with an error if helm in PATH is another binary.
+	// The CLI puts the binary of --helm-binary-path in PATH before rendering.
+	BinaryPath string
+
+	// secretValues are values read from the cluster, merged over the value files and under the
+	// inline values of the source
+	secretValues [][]byte
+	// defaultValues are values read from the cluster, merged under the value files of the source
+	defaultValues [][]byte
+}
+
+// chartVersion returns the version a remote chart source is pulled in
+func (opts HelmOptions) chartVersion(source v1alpha1.ApplicationSource) string {
+	if opts.ChartDigest != "" {
+		return opts.ChartDigest
+	}
+	return source.TargetRevision
+}
+
+// countHelmCharts returns the number of sources that reference a chart in a Helm repository
+func countHelmCharts(sources v1alpha1.ApplicationSources) int {
+	count := 0
+	for _, source := range sources {
+		if source.IsHelm() {
+			count++
+		}
+	}
+	return count
+}
+
+// chartDigestPattern matches an OCI digest like sha256:<hex>
+var chartDigestPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// isChartDigest reports whether the chart version is an OCI digest
+func isChartDigest(version string) bool {
+	return chartDigestPattern.MatchString(version)
+}
+
+// validateChartVersion returns an error if the chart version looks like a digest but is not
+// a valid one. The digest is used as the name of the cache entry.
+func validateChartVersion(version string) error {
+	if strings.HasPrefix(version, "sha256:") && !isChartDigest(version) {
+		return fmt.Errorf("invalid chart digest %q, expected sha256: followed by 64 lowercase hex characters", version)
+	}
+	return nil
+}
+
+// helmBinary returns the helm binary to run
+func (opts HelmOptions) helmBinary() string {
+	if opts.BinaryPath != "" {
+		return opts.BinaryPath
+	}
+	return "helm"
+}
+
+// checkHelmTemplateBinary returns an error if helm in PATH, which Argo CD runs helm template
+// with, is not the helm binary of the options. PATH is shared by the whole process, so it is
+// left to the caller to put the binary in PATH.
+func (opts HelmOptions) checkHelmTemplateBinary() error {
+	if opts.BinaryPath == "" {
+		return nil
+	}
+	binary, err := resolveBinary(opts.BinaryPath)
+	if err != nil {
+		return fmt.Errorf("helm binary %s not found: %w", opts.BinaryPath, err)
+	}
+	if helm, err := resolveBinary("helm"); err != nil || helm != binary {
+		return fmt.Errorf("helm template runs helm from PATH, which is not the helm binary %s; put it first in PATH under the name helm", opts.BinaryPath)
+	}
+	return nil
+}
+
+// resolveBinary returns the absolute path of the binary with all symlinks resolved
+func resolveBinary(binary string) (string, error) {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return "", err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(path)
+}
+
+// HelmFileParameter is a Helm parameter whose value is read from a file
+type HelmFileParameter struct {
+	Name string
+	Path string
+}
+
+// HelmLintError is returned when helm lint reports errors for a chart
+type HelmLintError struct {
+	Chart  string
+	Output string
+}
+
+func (e *HelmLintError) Error() string {
+	return fmt.Sprintf("helm lint failed for chart %s:\n%s", e.Chart, e.Output)
+}
+
+// HelmParameter is a Helm parameter passed on the command line
+type HelmParameter struct {
+	Name  string
+	Value string
+	// ForceString passes the value with --set-string instead of --set
+	ForceString bool
+	// ForceJSON parses the value as JSON, like --set-json
+	ForceJSON bool
+	// ForceLiteral passes the value verbatim without parsing commas or dots, like --set-literal
+	ForceLiteral bool
+}
+
+// applyHelmOptions merges the Helm overrides into the given source
+func applyHelmOptions(source *v1alpha1.ApplicationSource, opts HelmOptions) error {
+	if opts.ForceStringParameters && source.Helm != nil {
+		for i := range source.Helm.Parameters {
+			source.Helm.Parameters[i].ForceString = true
+		}
+	}
+	if len(opts.Parameters) == 0 && len(opts.ExtraValueFiles) == 0 && len(opts.ExtraFileParameters) == 0 && len(opts.APIVersions) == 0 && !opts.IncludeCrds {
+		return nil
+	}
+
+	if source.Helm == nil {
+		source.Helm = &v1alpha1.ApplicationSourceHelm{}
+	}
+
+	source.Helm.APIVersions = append(source.Helm.APIVersions, opts.APIVersions...)
+
+	// Argo CD passes --include-crds to helm template unless CRDs are skipped
+	if opts.IncludeCrds {
+		source.Helm.SkipCrds = false
+	}
+
+	// Argo CD only reads value files from inside the repository, so the extra value files are
+	// merged into the inline values, which helm applies after the value files of the source
+	for _, valueFile := range opts.ExtraValueFiles {
+		data, err := os.ReadFile(valueFile)
+		if err != nil {
+			return fmt.Errorf("failed to read values file: %w", err)
+		}
+		var overrides map[string]interface{}
+		if err := yaml.Unmarshal(data, &overrides); err != nil {
+			return fmt.Errorf("failed to parse values file %s: %w", valueFile, err)
+		}
+		if err := mergeHelmValues(source.Helm, overrides); err != nil {
+			return err
+		}
+	}
+
+	// Argo CD only reads file parameters from inside the repository as well, so the
+	// file content is set as a literal value instead
+	for _, param := range opts.ExtraFileParameters {
+		data, err := os.ReadFile(param.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read file for parameter %s: %w", param.Name, err)
+		}
+		if err := setHelmValue(source.Helm, param.Name, string(data)); err != nil {
+			return err
+		}
+	}
+
+	for _, param := range opts.Parameters {
+		switch {
+		case param.ForceJSON:
+			var value interface{}
+			if err := json.Unmarshal([]byte(param.Value), &value); err != nil {
+				return fmt.Errorf("failed to parse JSON value for parameter %s: %w", param.Name, err)
+			}
+			if err := setHelmValue(source.Helm, param.Name, value); err != nil {
+				return err
+			}
+		case param.ForceLiteral:
+			if err := setHelmValue(source.Helm, param.Name, param.Value); err != nil {
+				return err
+			}
+		default:
+			source.Helm.Parameters = append(source.Helm.Parameters, v1alpha1.HelmParameter{
+				Name:        param.Name,
+				Value:       param.Value,
+				ForceString: param.ForceString || opts.ForceStringParameters,
+			})
+		}
+	}
+
+	return nil
+}
+
+// setHelmValue sets a dot-separated key in the inline values of the Helm source.
+// Argo CD has no equivalent of --set-json and --set-literal, so these values are
+// written to valuesObject, which is passed to helm as an additional values file.
+// Helm applies the parameters after the values files, so the parameters of the source
+// that set the key or a key below it are removed to let the override win.
+func setHelmValue(helm *v1alpha1.ApplicationSourceHelm, key string, value interface{}) error {
+	if strings.ContainsAny(key, "[]") {
+		return fmt.Errorf("failed to set Helm value %s: list indexes are only supported by plain parameters", key)
+	}
+	values, err := helmValues(helm)
+	if err != nil {
+		return err
+	}
+
+	if err := unstructured.SetNestedField(values, value, strings.Split(key, ".")...); err != nil {
+		return fmt.Errorf("failed to set Helm value %s: %w", key, err)
+	}
+
+	helm.Parameters = slices.DeleteFunc(helm.Parameters, func(param v1alpha1.HelmParameter) bool {
+		return param.Name == key || strings.HasPrefix(param.Name, key+".") || strings.HasPrefix(param.Name, key+"[")
+	})
+	return setHelmValues(helm, values)
+}
+
+// mergeHelmValues merges the overrides into the inline values of the Helm source like
+// helm merges values files: nested maps are merged and all other values are replaced
+func mergeHelmValues(helm *v1alpha1.ApplicationSourceHelm, overrides map[string]interface{}) error {
+	values, err := helmValues(helm)
+	if err != nil {
+		return err
+	}
+
+	return setHelmValues(helm, mergeValues(values, overrides))
+}
+
+// mergeValues merges src into dst and returns dst
+func mergeValues(dst, src map[string]interface{}) map[string]interface{} {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			dst[key] = mergeValues(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+	return dst
+}
+
+// helmValues returns the inline values of the Helm source
+func helmValues(helm *v1alpha1.ApplicationSourceHelm) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if !helm.ValuesIsEmpty() {
+		if err := yaml.Unmarshal(helm.ValuesYAML(), &values); err != nil {
+			return nil, fmt.Errorf("failed to parse Helm values: %w", err)
+		}
+	}
+	return values, nil
+}
+
+// setHelmValues replaces the inline values of the Helm source
+func setHelmValues(helm *v1alpha1.ApplicationSourceHelm, values map[string]interface{}) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Helm values: %w", err)
+	}
+
+	helm.Values = ""
+	helm.ValuesObject = &runtime.RawExtension{Raw: data}
+	return nil
+}
+
+// applySecretHelmValues merges the secret values over the value files and under the inline
+// values of the source, like value files appended to the value files of the source. It runs
+// before value files are merged into the inline values, which keeps those below them.
+func applySecretHelmValues(source *v1alpha1.ApplicationSource, secretValues [][]byte) error {
+	if len(secretValues) == 0 {
+		return nil
+	}
+	if source.Helm == nil {
+		source.Helm = &v1alpha1.ApplicationSourceHelm{}
+	}
+
+	values := map[string]interface{}{}
+	for _, data := range secretValues {
+		var secrets map[string]interface{}
+		if err := yaml.Unmarshal(data, &secrets); err != nil {
+			return fmt.Errorf("failed to parse values read from the cluster: %w", err)
+		}
+		values = mergeValues(values, secrets)
+	}
+	inline, err := helmValues(source.Helm)
+	if err != nil {
+		return err
+	}
+	return setHelmValues(source.Helm, mergeValues(values, inline))
+}
+
+// applyDefaultHelmValues merges the default values under the value files and inline values of
+// the source. Helm has no values below the value files but those of the chart, so the defaults
+// set by any value file are dropped and the rest is merged under the inline values, which
+// leaves the value files to Argo CD. Missing value files set nothing.
+func applyDefaultHelmValues(ctx context.Context, source *v1alpha1.ApplicationSource, defaultValues [][]byte, files helmValueFiles) error {
+	if len(defaultValues) == 0 {
+		return nil
+	}
+	if source.Helm == nil {
+		source.Helm = &v1alpha1.ApplicationSourceHelm{}
+	}
+
+	values := map[string]interface{}{}
+	for _, data := range defaultValues {
+		var defaults map[string]interface{}
+		if err := yaml.Unmarshal(data, &defaults); err != nil {
+			return fmt.Errorf("failed to parse values read from the cluster: %w", err)
+		}
+		values = mergeValues(values, defaults)
+	}
+	for _, valueFile := range source.Helm.ValueFiles {
+		data, err := files.read(ctx, valueFile)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var fileValues map[string]interface{}
+		if err := yaml.Unmarshal(data, &fileValues); err != nil {
+			return fmt.Errorf("failed to parse values file %s: %w", valueFile, err)
+		}
+		values = pruneValues(values, fileValues)
+	}
+
+	inline, err := helmValues(source.Helm)
+	if err != nil {
+		return err
+	}
+	return setHelmValues(source.Helm, mergeValues(values, inline))
+}
+
+// pruneValues removes the values that merging the overrides over them would replace and
+// returns values
+func pruneValues(values, overrides map[string]interface{}) map[string]interface{} {
+	for key, override := range overrides {
+		overrideMap, overrideIsMap := override.(map[string]interface{})
+		valueMap, valueIsMap := values[key].(map[string]interface{})
+		if overrideIsMap && valueIsMap {
+			values[key] = pruneValues(valueMap, overrideMap)
+			continue
+		}
+		delete(values, key)
+	}
+	return values
+}
+
+// helmValueFiles locates the value files of a Helm source on disk
+type helmValueFiles struct {
+	chartPath string
+	repoRoot  string
+	// refs are the sources the value files can reference with $ref, keyed like RefSources
+	refs       map[string]*v1alpha1.RefTarget
+	sourceRefs map[string]string
+}
+
+// path returns the location of a local value file. Value files are relative to the chart,
+// absolute paths are relative to the repository root and $ref/ paths are relative to the
+// referenced source, like Argo CD resolves them.
+func (f helmValueFiles) path(valueFile string) string {
+	if refVar, rest, found := strings.Cut(valueFile, "/"); found && strings.HasPrefix(refVar, "$") {
+		if _, referenced := f.refs[refVar]; referenced {
+			return filepath.Join(refDir(refVar, f.repoRoot, f.sourceRefs), rest)
+		}
+	}
+	if filepath.IsAbs(valueFile) {
+		return filepath.Join(f.repoRoot, valueFile)
+	}
+	return filepath.Join(f.chartPath, valueFile)
+}
+
+// read returns the content of a value file, downloading remote value files
+func (f helmValueFiles) read(ctx context.Context, valueFile string) ([]byte, error) {
+	if isRemoteValueFile(valueFile) {
+		return fetchValueFile(ctx, valueFile)
+	}
+	data, err := os.ReadFile(f.path(valueFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file %s: %w", valueFile, err)
+	}
+	return data, nil
+}
+
+// isRemoteValueFile reports whether the value file is a URL helm downloads
+func isRemoteValueFile(valueFile string) bool {
+	return strings.Contains(valueFile, "://")
+}
+
+// fetchValueFile downloads a remote value file
+func fetchValueFile(ctx context.Context, valueFile string) ([]byte, error) {
+	if !strings.HasPrefix(valueFile, "http://") && !strings.HasPrefix(valueFile, "https://") {
+		return nil, fmt.Errorf("failed to fetch values file %s: only http and https URLs are supported", valueFile)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, valueFile, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for values file %s: %w", valueFile, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch values file %s: %w", valueFile, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch values file %s: %s", valueFile, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file %s: %w", valueFile, err)
+	}
+	return data, nil
+}
+
+// valuesTransform returns the transformed content of a value file and whether it changed. path
+// is the location of the value file on disk.
+type valuesTransform func(valueFile, path string, data []byte) ([]byte, bool, error)
+
+// transformHelmValueFiles applies the transform to the local value files of the source. Argo CD
+// only reads value files from inside the repository, so the transformed content is kept in
+// memory: helm applies the value files in order and the inline values last, so the value files
+// from the first changed one on are merged in order below the inline values and removed from
+// the value files. Remote value files among them are downloaded to keep their place in the
+// order. Missing value files are left to Argo CD, which reports or ignores them.
+func transformHelmValueFiles(ctx context.Context, helm *v1alpha1.ApplicationSourceHelm, files helmValueFiles, transform valuesTransform) error {
+	if helm == nil {
+		return nil
+	}
+
+	var valueFiles []string
+	var values map[string]interface{}
+	for _, valueFile := range helm.ValueFiles {
+		if values == nil && isRemoteValueFile(valueFile) {
+			valueFiles = append(valueFiles, valueFile)
+			continue
+		}
+		data, err := files.read(ctx, valueFile)
+		if errors.Is(err, os.ErrNotExist) {
+			valueFiles = append(valueFiles, valueFile)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !isRemoteValueFile(valueFile) {
+			var changed bool
+			data, changed, err = transform(valueFile, files.path(valueFile), data)
+			if err != nil {
+				return err
+			}
+			if values == nil && !changed {
+				valueFiles = append(valueFiles, valueFile)
+				continue
+			}
+		}
+
+		var fileValues map[string]interface{}
+		if err := yaml.Unmarshal(data, &fileValues); err != nil {
+			return fmt.Errorf("failed to parse values file %s: %w", valueFile, err)
+		}
+		if values == nil {
+			values = map[string]interface{}{}
+		}
+		values = mergeValues(values, fileValues)
+	}
+	if values == nil {
+		return nil
+	}
+
+	inline, err := helmValues(helm)
+	if err != nil {
+		return err
+	}
+	helm.ValueFiles = valueFiles
+	return setHelmValues(helm, mergeValues(values, inline))
+}
+
+// expandEnvInHelmValues expands environment variables in the value files and inline values of
+// the source. A warning is returned for every variable that expands to an empty string.
+func expandEnvInHelmValues(ctx context.Context, helm *v1alpha1.ApplicationSourceHelm, files helmValueFiles) ([]string, error) {
+	if helm == nil {
+		return nil, nil
+	}
+
+	var warnings []string
+	// The inline values are expanded first, the expanded value files are merged below them
+	if !helm.ValuesIsEmpty() {
+		expanded, empty := expandEnv(string(helm.ValuesYAML()))
+		for _, name := range empty {
+			warnings = append(warnings, fmt.Sprintf("inline values: environment variable %s is empty", name))
+		}
+		if err := helm.SetValuesString(expanded); err != nil {
+			return nil, fmt.Errorf("failed to set expanded values: %w", err)
+		}
+	}
+
+	err := transformHelmValueFiles(ctx, helm, files, func(valueFile, path string, data []byte) ([]byte, bool, error) {
+		expanded, empty := expandEnv(string(data))
+		for _, name := range empty {
+			warnings = append(warnings, fmt.Sprintf("values file %s: environment variable %s is empty", valueFile, name))
+		}
+		return []byte(expanded), true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// expandEnv expands environment variables like os.ExpandEnv and returns the names of the
+// variables that expanded to an empty string
+func expandEnv(content string) (string, []string) {
+	var empty []string
+	expanded := os.Expand(content, func(name string) string {
+		value := os.Getenv(name)
+		if value == "" && !slices.Contains(empty, name) {
+			empty = append(empty, name)
+		}
+		return value
+	})
+	return expanded, empty
+}
+
+// checkHelmDependencies returns a warning if the dependencies of the chart are missing or
+// stale, which helm template silently omits. Errors are left to Argo CD, which reports charts
+// without a Chart.yaml.
+func checkHelmDependencies(chartPath string) []string {
+	needsUpdate, err := helmDependenciesNeedUpdate(chartPath)
+	if err != nil || !needsUpdate {
+		return nil
+	}
+	return []string{fmt.Sprintf("the dependencies of chart %s are missing or older than Chart.lock, run helm dependency update to render its sub-charts", chartPath)}
+}
+
+// helmDependenciesNeedUpdate reports whether the chart declares dependencies and
+// the charts/ directory is missing or older than Chart.lock
+func helmDependenciesNeedUpdate(chartPath string) (bool, error) {
+	data, err := os.ReadFile(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		return false, fmt.Errorf("failed to read Chart.yaml: %w", err)
+	}
+
+	var chart struct {
+		Dependencies []interface{} `json:"dependencies"`
+	}
+	if err := yaml.Unmarshal(data, &chart); err != nil {
+		return false, fmt.Errorf("failed to parse Chart.yaml: %w", err)
+	}
+	if len(chart.Dependencies) == 0 {
+		return false, nil
+	}
+
+	chartsInfo, err := os.Stat(filepath.Join(chartPath, "charts"))
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat charts directory: %w", err)
+	}
+
+	lockInfo, err := os.Stat(filepath.Join(chartPath, "Chart.lock"))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat Chart.lock: %w", err)
+	}
+
+	return lockInfo.ModTime().After(chartsInfo.ModTime()), nil
+}
+
+// runHelmDependencyUpdate downloads the dependencies of the chart into its charts/ directory
+func runHelmDependencyUpdate(ctx context.Context, helmBinary, chartPath string) error {
+	if err := checkBinaryExists(helmBinary); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, helmBinary, "dependency", "update", chartPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("helm dependency update failed: %w\nOutput: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// runHelmLint lints the chart with the same values, parameters and namespace it is rendered with
+func runHelmLint(ctx context.Context, helmBinary string, files helmValueFiles, namespace string, source *v1alpha1.ApplicationSource) error {
+	if err := checkBinaryExists(helmBinary); err != nil {
+		return err
+	}
+
+	args, cleanup, err := helmLintArgs(files, namespace, source.Helm)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	cmd := exec.CommandContext(ctx, helmBinary, args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &HelmLintError{Chart: files.chartPath, Output: string(output)}
+		}
+		return fmt.Errorf("failed to run helm lint: %w", err)
+	}
+
+	return nil
+}
+
+// helmLintArgs builds the arguments for helm lint. Inline values are written to a temporary
+// values file, which is removed by the returned cleanup function. Missing value files are
+// skipped if the source ignores them, like Argo CD does.
+func helmLintArgs(files helmValueFiles, namespace string, helm *v1alpha1.ApplicationSourceHelm) ([]string, func(), error) {
+	chartPath := files.chartPath
+	args := []string{"lint", chartPath}
+	cleanup := func() {}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+	if helm == nil {
+		return args, cleanup, nil
+	}
+
+	for _, valueFile := range helm.ValueFiles {
+		if !isRemoteValueFile(valueFile) {
+			valueFile = files.path(valueFile)
+			if _, err := os.Stat(valueFile); os.IsNotExist(err) && helm.IgnoreMissingValueFiles {
+				continue
+			}
+		}
+		args = append(args, "--values", valueFile)
+	}
+
+	if !helm.ValuesIsEmpty() {
+		valuesFile, err := os.CreateTemp("", "helm-lint-values-*.yaml")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create values file: %w", err)
+		}
+		cleanup = func() { os.Remove(valuesFile.Name()) }
+		_, err = valuesFile.Write(helm.ValuesYAML())
+		valuesFile.Close()
+		if err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to write values file: %w", err)
+		}
+		args = append(args, "--values", valuesFile.Name())
+	}
+
+	for _, param := range helm.Parameters {
+		flag := "--set"
+		if param.ForceString {
+			flag = "--set-string"
+		}
+		args = append(args, flag, fmt.Sprintf("%s=%s", param.Name, param.Value))
+	}
+	for _, param := range helm.FileParameters {
+		args = append(args, "--set-file", fmt.Sprintf("%s=%s", param.Name, filepath.Join(chartPath, param.Path)))
+	}
+
+	return args, cleanup, nil
+}
+
+// runPostRenderer pipes the manifests through the post-renderer program as a multi-document
+// YAML stream and parses its output. Unlike helm, the post-renderer runs after Argo CD has
+// added its tracking labels.
+func runPostRenderer(ctx context.Context, postRenderer string, args []string, manifests []string) ([]string, error) {
+	var input bytes.Buffer
+	for _, manifest := range manifests {
+		doc, err := yaml.JSONToYAML([]byte(manifest))
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert manifest to YAML: %w", err)
+		}
+		input.WriteString("---\n")
+		input.Write(doc)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, postRenderer, args...)
+	cmd.Stdin = &input
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("post-renderer %s failed: %w\nOutput: %s", postRenderer, err, stderr.String())
+	}
+
+	objects, err := decodeObjects(&stdout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse post-renderer output: %w", err)
+	}
+	return marshalObjects(objects)
+}
+
+// decodeObjects decodes a stream of YAML or JSON documents, skipping empty documents
+func decodeObjects(r io.Reader) ([]map[string]interface{}, error) {
+	var objects []map[string]interface{}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var obj map[string]interface{}
+		if err := decoder.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// marshalObjects returns the objects as JSON manifests
+func marshalObjects(objects []map[string]interface{}) ([]string, error) {
+	result := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		manifest, err := json.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+		}
+		result = append(result, string(manifest))
+	}
+	return result, nil
+}
+
+// DefaultHelmPullRetries is how often a helm pull that failed with a network error is retried
+const DefaultHelmPullRetries = 3
+
+// helmPullRetryDelay is the delay before the first retry of helm pull, doubled for every retry
+var helmPullRetryDelay = time.Second
+
+// downloadHelmChart downloads a remote Helm chart to the cache directory with reproducible naming.
+// Cached charts older than cacheTTL are downloaded again. When verify is set, cached charts whose
+// content does not match the checksum recorded at download time are downloaded again. Pulls that
+// fail with a network error are retried up to retries times, logging every retry to retryLog if
+// it is not nil. Canceling ctx stops the download.
+func downloadHelmChart(ctx context.Context, helmCacheDir, repoURL, chartName, version string, cacheTTL time.Duration, verify bool, auths []HelmRegistryAuth, helmBinary string, retries int, retryLog io.Writer) (string, error) {
+	if err := validateChartVersion(version); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(helmCacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create helm cache directory: %w", err)
+	}
+
+	chartDir := filepath.Join(helmCacheDir, fmt.Sprintf("chart-%s", chartCacheKey(repoURL, chartName, version)))
+
+	checksumFile := chartDir + ".sha256"
+
+	// Check if chart is already cached and still fresh
+	if info, err := os.Stat(chartDir); err == nil {
+		if cacheTTL > 0 && time.Since(info.ModTime()) < cacheTTL {
+			valid := true
+			if verify {
+				if valid, err = verifyChecksum(chartDir, checksumFile); err != nil {
+					return "", err
+				}
+			}
+			if valid {
+				return chartDir, nil
+			}
+		}
+		if err := os.RemoveAll(chartDir); err != nil {
+			return "", fmt.Errorf("failed to remove expired chart from cache: %w", err)
+		}
+	}
+
+	if err := checkBinaryExists(helmBinary); err != nil {
+		return "", err
+	}
+
+	pullArgs := helmPullArgs(repoURL, chartName, version, helmCacheDir)
+	if isOCIRepo(repoURL) {
+		auth, err := findRegistryCredentials(repoURL, auths)
+		if err != nil {
+			return "", err
+		}
+		if auth != nil {
+			// Logging in to a temporary registry config keeps the logins of the user untouched
+			// and concurrent downloads apart
+			registryConfigDir, err := os.MkdirTemp("", "helm-registry-*")
+			if err != nil {
+				return "", fmt.Errorf("failed to create helm registry config directory: %w", err)
+			}
+			defer os.RemoveAll(registryConfigDir)
+			registryConfig := filepath.Join(registryConfigDir, "config.json")
+			if err := ociLogin(ctx, helmBinary, ociRegistry(repoURL), registryConfig, *auth); err != nil {
+				return "", err
+			}
+			pullArgs = append(pullArgs, "--registry-config", registryConfig)
+			if auth.CACert != "" {
+				pullArgs = append(pullArgs, "--ca-file", auth.CACert)
+			}
+		}
+	}
+
+	// Find the extracted chart directory (helm pull creates a directory with the chart name)
+	extractedDir := filepath.Join(helmCacheDir, chartName)
+
+	// Download the chart, retrying network errors with exponential backoff
+	delay := helmPullRetryDelay
+	for attempt := 0; ; attempt++ {
+		output, err := exec.CommandContext(ctx, helmBinary, pullArgs...).CombinedOutput()
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			os.RemoveAll(extractedDir)
+			return "", fmt.Errorf("helm pull of %s canceled: %w", chartName, ctx.Err())
+		}
+		if attempt == retries || !isTransientPullError(string(output)) {
+			return "", fmt.Errorf("helm pull failed: %w\nOutput: %s", err, string(output))
+		}
+		if retryLog != nil {
+			fmt.Fprintf(retryLog, "helm pull of %s failed, retrying in %s (%d/%d): %s\n", chartName, delay, attempt+1, retries, strings.TrimSpace(string(output)))
+		}
+		// A partially extracted chart would make the next pull fail
+		os.RemoveAll(extractedDir)
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("helm pull of %s canceled: %w", chartName, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	// Rename to our reproducible name
+	if err := os.Rename(extractedDir, chartDir); err != nil {
+		return "", fmt.Errorf("failed to rename chart directory: %w", err)
+	}
+
+	if _, err := readChartName(chartDir); err != nil {
+		os.RemoveAll(chartDir)
+		return "", err
+	}
+
+	// Record the checksum of the chart, which is used to verify the cache entry
+	checksum, err := computeDirectoryHash(chartDir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(checksumFile, []byte(checksum+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write chart checksum: %w", err)
+	}
+
+	// Record the download time, which is used to expire the cache entry
+	now := time.Now()
+	if err := os.Chtimes(chartDir, now, now); err != nil {
+		return "", fmt.Errorf("failed to update chart directory time: %w", err)
+	}
+
+	return chartDir, nil
+}
+
+// isTransientPullError reports whether the output of a failed helm pull points to a network
+// error that may not happen again
+func isTransientPullError(output string) bool {
+	output = strings.ToLower(output)
+	for _, message := range []string{"connection refused", "timeout", "no such host"} {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// chartCacheKey returns the name of the cache entry of a chart. A chart pinned to a digest
+// is identified by the digest alone, other charts by the repository, name and version.
+func chartCacheKey(repoURL, chartName, version string) string {
+	if isChartDigest(version) {
+		return strings.TrimPrefix(version, "sha256:")
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s", repoURL, chartName, version)))
+	return hex.EncodeToString(hash[:])
+}
+
+// readChartName returns the name in the Chart.yaml of the chart
+func readChartName(chartDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(chartDir, "Chart.yaml"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read Chart.yaml: %w", err)
+	}
+	var metadata struct {
+		Name string `json:"name"`
+	}
+	if err := yaml.Unmarshal(data, &metadata); err != nil {
+		return "", fmt.Errorf("failed to parse Chart.yaml in %s: %w", chartDir, err)
+	}
+	return metadata.Name, nil
+}
+
+// generateReleaseName returns a release name like helm --generate-name does, the chart name
+// followed by the current Unix time
+func generateReleaseName(chartDir string) string {
+	name, err := readChartName(chartDir)
+	if err != nil || name == "" {
+		name = "chart"
+	}
+	return fmt.Sprintf("%s-%d", name, time.Now().Unix())
+}
+
+// verifyChecksum reports whether the content of the chart directory matches the recorded checksum
+func verifyChecksum(chartDir, checksumFile string) (bool, error) {
+	expected, err := os.ReadFile(checksumFile)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read chart checksum: %w", err)
+	}
+
+	hash, err := computeDirectoryHash(chartDir)
+	if err != nil {
+		return false, err
+	}
+	return hash == strings.TrimSpace(string(expected)), nil
+}
+
+// computeDirectoryHash returns the SHA256 of the paths and contents of all files in the
+// directory, in lexicographic path order
+func computeDirectoryHash(dir string) (string, error) {
+	hash := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		// Include the path and length so moving content between files changes the hash
+		fmt.Fprintf(hash, "%s\x00%d\x00", filepath.ToSlash(relPath), len(data))
+		hash.Write(data)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash directory %s: %w", dir, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// helmPullArgs builds the arguments for helm pull. Charts in OCI registries are
+// referenced as oci://<registry>/<path>/<chart>, while classic Helm repositories
+// are referenced by their index URL followed by the chart name. A digest is passed
+// as the version like any other.
+func helmPullArgs(repoURL, chartName, version, destination string) []string {
+	var chartRef string
+	if isOCIRepo(repoURL) {
+		chartRef = fmt.Sprintf("oci://%s/%s", strings.TrimSuffix(strings.TrimPrefix(repoURL, "oci://"), "/"), chartName)
+	} else {
+		chartRef = fmt.Sprintf("%s/%s", strings.TrimSuffix(repoURL, "/"), chartName)
+	}
+
+	args := []string{"pull", chartRef}
+	if version != "" {
+		args = append(args, "--version", version)
+	}
+	args = append(args, "--destination", destination)
+	args = append(args, "--untar")
+	return args
+}
+
+// isOCIRepo reports whether the repository URL points to an OCI registry
+func isOCIRepo(repoURL string) bool {
+	return strings.HasPrefix(repoURL, "oci://")
+}
+
+// ociRegistry returns the registry host of an OCI repository URL
+func ociRegistry(repoURL string) string {
+	registry, _, _ := strings.Cut(strings.TrimPrefix(repoURL, "oci://"), "/")
+	return registry
+}
+
+// ociLogin authenticates helm against an OCI registry, storing the credentials in the
+// registry config file. The password is passed on stdin so it does not show up in the
+// process list.
+func ociLogin(ctx context.Context, helmBinary, registry, registryConfig string, auth HelmRegistryAuth) error {
+	args := []string{"registry", "login", registry, "--registry-config", registryConfig, "--username", auth.Username, "--password-stdin"}
+	if auth.CACert != "" {
+		args = append(args, "--ca-file", auth.CACert)
+	}
+	cmd := exec.CommandContext(ctx, helmBinary, args...)
+	cmd.Stdin = strings.NewReader(auth.Password)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("helm registry login to %s failed: %w\nOutput: %s", registry, err, string(output))
+	}
+	return nil
+}
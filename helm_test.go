@@ -0,0 +1,1219 @@
+package renderer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+)
+
+func TestApplyHelmOptions(t *testing.T) {
+	source := &v1alpha1.ApplicationSource{
+		Helm: &v1alpha1.ApplicationSourceHelm{
+			Values: "replicaCount: 2\n",
+		},
+	}
+
+	opts := HelmOptions{
+		Parameters: []HelmParameter{
+			{Name: "image.tag", Value: "1.21"},
+			{Name: "image.pullSecrets", Value: `[{"name":"reg"}]`, ForceJSON: true},
+			{Name: "config.message", Value: "hello, world.", ForceLiteral: true},
+		},
+	}
+
+	if err := applyHelmOptions(source, opts); err != nil {
+		t.Fatalf("applyHelmOptions failed: %v", err)
+	}
+
+	if len(source.Helm.Parameters) != 1 || source.Helm.Parameters[0].Name != "image.tag" {
+		t.Errorf("Expected image.tag to be passed as parameter, got %v", source.Helm.Parameters)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(source.Helm.ValuesYAML(), &values); err != nil {
+		t.Fatalf("Failed to parse values: %v", err)
+	}
+
+	if values["replicaCount"] != float64(2) {
+		t.Errorf("Expected existing values to be preserved, got %v", values["replicaCount"])
+	}
+
+	image := values["image"].(map[string]interface{})
+	pullSecrets, ok := image["pullSecrets"].([]interface{})
+	if !ok || len(pullSecrets) != 1 {
+		t.Fatalf("Expected pullSecrets to be a list with one entry, got %v", image["pullSecrets"])
+	}
+	if name := pullSecrets[0].(map[string]interface{})["name"]; name != "reg" {
+		t.Errorf("Expected pull secret name 'reg', got %v", name)
+	}
+
+	config := values["config"].(map[string]interface{})
+	if config["message"] != "hello, world." {
+		t.Errorf("Expected literal value to be preserved, got %v", config["message"])
+	}
+}
+
+func TestApplyHelmOptionsInvalidJSON(t *testing.T) {
+	source := &v1alpha1.ApplicationSource{}
+	opts := HelmOptions{
+		Parameters: []HelmParameter{
+			{Name: "image", Value: "{invalid", ForceJSON: true},
+		},
+	}
+
+	if err := applyHelmOptions(source, opts); err == nil {
+		t.Error("Expected error for invalid JSON value")
+	}
+}
+
+func TestApplyHelmOptionsOverrideSourceParameters(t *testing.T) {
+	source := &v1alpha1.ApplicationSource{
+		Helm: &v1alpha1.ApplicationSourceHelm{
+			Parameters: []v1alpha1.HelmParameter{
+				{Name: "name", Value: "app"},
+				{Name: "replicas", Value: "1"},
+				{Name: "image.tag", Value: "app"},
+				{Name: "config.message", Value: "app"},
+			},
+		},
+	}
+	opts := HelmOptions{
+		Parameters: []HelmParameter{
+			{Name: "name", Value: "cli"},
+			{Name: "replicas", Value: "3", ForceJSON: true},
+			{Name: "image", Value: `{"tag":"cli"}`, ForceJSON: true},
+			{Name: "config.message", Value: "hello, world.", ForceLiteral: true},
+		},
+	}
+	if err := applyHelmOptions(source, opts); err != nil {
+		t.Fatalf("applyHelmOptions failed: %v", err)
+	}
+
+	// Helm applies the parameters after the values, so only the plain parameters may remain,
+	// with the override last
+	expected := []v1alpha1.HelmParameter{{Name: "name", Value: "app"}, {Name: "name", Value: "cli"}}
+	if !reflect.DeepEqual(source.Helm.Parameters, expected) {
+		t.Errorf("Expected the overridden parameters of the source to be removed, got %v", source.Helm.Parameters)
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(source.Helm.ValuesYAML(), &values); err != nil {
+		t.Fatalf("Failed to parse values: %v", err)
+	}
+	if values["replicas"] != float64(3) || values["image"].(map[string]interface{})["tag"] != "cli" || values["config"].(map[string]interface{})["message"] != "hello, world." {
+		t.Errorf("Expected the overrides in the values, got %v", values)
+	}
+}
+
+func TestApplyHelmOptionsListIndex(t *testing.T) {
+	for _, param := range []HelmParameter{
+		{Name: "servers[0].port", Value: "80", ForceJSON: true},
+		{Name: "servers[0].name", Value: "web", ForceLiteral: true},
+	} {
+		source := &v1alpha1.ApplicationSource{}
+		err := applyHelmOptions(source, HelmOptions{Parameters: []HelmParameter{param}})
+		if err == nil || !strings.Contains(err.Error(), "list indexes are only supported by plain parameters") {
+			t.Errorf("Expected list indexes in %s to be rejected, got %v", param.Name, err)
+		}
+	}
+
+	// Plain parameters are passed to helm, which supports list indexes
+	source := &v1alpha1.ApplicationSource{}
+	if err := applyHelmOptions(source, HelmOptions{Parameters: []HelmParameter{{Name: "servers[0].port", Value: "80"}}}); err != nil {
+		t.Errorf("Expected a plain parameter with a list index to be passed to helm, got %v", err)
+	}
+}
+
+func TestApplyHelmOptionsExtraValueFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.yaml")
+	second := filepath.Join(dir, "second.yaml")
+	if err := os.WriteFile(first, []byte("image:\n  tag: \"1.21\"\n  pullPolicy: Always\nreplicaCount: 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to write values file: %v", err)
+	}
+	if err := os.WriteFile(second, []byte("image:\n  tag: \"1.22\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write values file: %v", err)
+	}
+
+	source := &v1alpha1.ApplicationSource{
+		Helm: &v1alpha1.ApplicationSourceHelm{
+			ValueFiles: []string{"values-prod.yaml"},
+			Values:     "replicaCount: 2\nimage:\n  repository: nginx\n",
+		},
+	}
+	opts := HelmOptions{ExtraValueFiles: []string{first, second}}
+	if err := applyHelmOptions(source, opts); err != nil {
+		t.Fatalf("applyHelmOptions failed: %v", err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(source.Helm.ValuesYAML(), &values); err != nil {
+		t.Fatalf("Failed to parse values: %v", err)
+	}
+
+	// The extra value files are applied last, in the order they were given
+	if values["replicaCount"] != float64(3) {
+		t.Errorf("Expected extra values file to override inline values, got %v", values["replicaCount"])
+	}
+	image := values["image"].(map[string]interface{})
+	if image["tag"] != "1.22" || image["pullPolicy"] != "Always" || image["repository"] != "nginx" {
+		t.Errorf("Expected nested values to be merged in order, got %v", image)
+	}
+	if len(source.Helm.ValueFiles) != 1 {
+		t.Errorf("Expected value files of the source to be kept, got %v", source.Helm.ValueFiles)
+	}
+}
+
+func TestApplyHelmOptionsExtraFileParameters(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile("config.json", []byte(`{"debug": true, "hosts": ["a", "b"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	source := &v1alpha1.ApplicationSource{
+		Helm: &v1alpha1.ApplicationSourceHelm{
+			FileParameters: []v1alpha1.HelmFileParameter{{Name: "other", Path: "other.txt"}},
+		},
+	}
+	opts := HelmOptions{ExtraFileParameters: []HelmFileParameter{{Name: "app.config", Path: "config.json"}}}
+	if err := applyHelmOptions(source, opts); err != nil {
+		t.Fatalf("applyHelmOptions failed: %v", err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(source.Helm.ValuesYAML(), &values); err != nil {
+		t.Fatalf("Failed to parse values: %v", err)
+	}
+	app, _ := values["app"].(map[string]interface{})
+	if app["config"] != `{"debug": true, "hosts": ["a", "b"]}` {
+		t.Errorf("Expected the file content as a string value, got %v", values)
+	}
+	if len(source.Helm.FileParameters) != 1 {
+		t.Errorf("Expected file parameters of the source to be kept, got %v", source.Helm.FileParameters)
+	}
+
+	opts = HelmOptions{ExtraFileParameters: []HelmFileParameter{{Name: "missing", Path: filepath.Join(dir, "missing.txt")}}}
+	if err := applyHelmOptions(source, opts); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestApplyHelmOptionsAPIVersionsAndCRDs(t *testing.T) {
+	source := &v1alpha1.ApplicationSource{
+		Helm: &v1alpha1.ApplicationSourceHelm{
+			SkipCrds:    true,
+			APIVersions: []string{"monitoring.coreos.com/v1"},
+		},
+	}
+
+	opts := HelmOptions{
+		APIVersions: []string{"networking.k8s.io/v1/Ingress"},
+		IncludeCrds: true,
+	}
+	if err := applyHelmOptions(source, opts); err != nil {
+		t.Fatalf("applyHelmOptions failed: %v", err)
+	}
+
+	if got := strings.Join(source.Helm.APIVersions, ","); got != "monitoring.coreos.com/v1,networking.k8s.io/v1/Ingress" {
+		t.Errorf("Expected API versions to be appended, got %s", got)
+	}
+	if source.Helm.SkipCrds {
+		t.Error("Expected IncludeCrds to override skipCrds of the source")
+	}
+}
+
+func TestApplyHelmOptionsForceStringParameters(t *testing.T) {
+	for _, forceString := range []bool{false, true} {
+		newSource := func() *v1alpha1.ApplicationSource {
+			return &v1alpha1.ApplicationSource{
+				Helm: &v1alpha1.ApplicationSourceHelm{
+					Parameters: []v1alpha1.HelmParameter{{Name: "image.tag", Value: "1.10"}},
+				},
+			}
+		}
+		opts := TemplateOptions{
+			Helm: HelmOptions{
+				Parameters:            []HelmParameter{{Name: "sidecar.tag", Value: "2.20"}},
+				ForceStringParameters: forceString,
+			},
+		}
+		flag := "--set"
+		if forceString {
+			flag = "--set-string"
+		}
+
+		source := newSource()
+		if err := applyHelmOptions(source, opts.Helm); err != nil {
+			t.Fatalf("applyHelmOptions failed: %v", err)
+		}
+		if len(source.Helm.Parameters) != 2 {
+			t.Fatalf("Expected 2 parameters, got %v", source.Helm.Parameters)
+		}
+		for _, param := range source.Helm.Parameters {
+			if param.ForceString != forceString {
+				t.Errorf("Expected ForceString of %s to be %t, got %t", param.Name, forceString, param.ForceString)
+			}
+		}
+
+		q := &apiclient.ManifestRequest{AppName: "guestbook", ApplicationSource: newSource()}
+		command := strings.Join(helmTemplateCommand("charts/guestbook", q, opts), " ")
+		for _, expected := range []string{" " + flag + " image.tag=1.10", " " + flag + " sidecar.tag=2.20"} {
+			if !strings.Contains(command, expected) {
+				t.Errorf("Expected command to contain %q, got:\n%s", expected, command)
+			}
+		}
+	}
+}
+
+func writeChartWithDependencies(t *testing.T) string {
+	t.Helper()
+	chartDir := t.TempDir()
+	chartYAML := `apiVersion: v2
+name: with-deps
+version: 0.1.0
+dependencies:
+- name: redis
+  version: 1.0.0
+  repository: https://charts.example.com
+`
+	if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(chartYAML), 0644); err != nil {
+		t.Fatalf("Failed to write Chart.yaml: %v", err)
+	}
+	return chartDir
+}
+
+func TestHelmDependenciesNeedUpdate(t *testing.T) {
+	chartDir := writeChartWithDependencies(t)
+
+	needsUpdate, err := helmDependenciesNeedUpdate(chartDir)
+	if err != nil {
+		t.Fatalf("helmDependenciesNeedUpdate failed: %v", err)
+	}
+	if !needsUpdate {
+		t.Error("Expected update when charts/ is missing")
+	}
+
+	chartsDir := filepath.Join(chartDir, "charts")
+	lockFile := filepath.Join(chartDir, "Chart.lock")
+	if err := os.Mkdir(chartsDir, 0755); err != nil {
+		t.Fatalf("Failed to create charts directory: %v", err)
+	}
+	if err := os.WriteFile(lockFile, []byte("dependencies: []\n"), 0644); err != nil {
+		t.Fatalf("Failed to write Chart.lock: %v", err)
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(lockFile, now.Add(-time.Hour), now.Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to set Chart.lock time: %v", err)
+	}
+	needsUpdate, err = helmDependenciesNeedUpdate(chartDir)
+	if err != nil {
+		t.Fatalf("helmDependenciesNeedUpdate failed: %v", err)
+	}
+	if needsUpdate {
+		t.Error("Expected no update when charts/ is newer than Chart.lock")
+	}
+
+	if err := os.Chtimes(lockFile, now.Add(time.Hour), now.Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to set Chart.lock time: %v", err)
+	}
+	needsUpdate, err = helmDependenciesNeedUpdate(chartDir)
+	if err != nil {
+		t.Fatalf("helmDependenciesNeedUpdate failed: %v", err)
+	}
+	if !needsUpdate {
+		t.Error("Expected update when Chart.lock is newer than charts/")
+	}
+}
+
+func TestHelmDependenciesNeedUpdateWithoutDependencies(t *testing.T) {
+	needsUpdate, err := helmDependenciesNeedUpdate("examples/helm/input")
+	if err != nil {
+		t.Fatalf("helmDependenciesNeedUpdate failed: %v", err)
+	}
+	if needsUpdate {
+		t.Error("Expected no update for a chart without dependencies")
+	}
+}
+
+func TestTemplateFromApplicationHelmDependencies(t *testing.T) {
+	logFile := installFakeHelm(t)
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		return &apiclient.ManifestResponse{}, nil
+	})
+
+	chartDir := writeChartWithDependencies(t)
+	appFile := writeApplication(t, `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: with-deps
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: "`+filepath.Base(chartDir)+`"
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`)
+	opts := TemplateOptions{ApplicationFile: appFile, RepoRoot: filepath.Dir(chartDir)}
+
+	// Without UpdateDependencies the missing dependencies are only reported
+	result, err := TemplateFromApplication(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if _, err := os.Stat(logFile); !os.IsNotExist(err) {
+		t.Errorf("Expected helm not to be run without UpdateDependencies, got %v", readHelmLog(t, logFile))
+	}
+	var warned bool
+	for _, warning := range result.Warnings {
+		warned = warned || strings.Contains(warning.Message, "are missing or older than Chart.lock")
+	}
+	if !warned {
+		t.Errorf("Expected a warning about the missing dependencies, got %+v", result.Warnings)
+	}
+
+	opts.Helm.UpdateDependencies = true
+	if _, err := TemplateFromApplication(context.Background(), opts); err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if log := readHelmLog(t, logFile); len(log) != 1 || log[0] != "dependency update "+chartDir {
+		t.Errorf("Expected helm dependency update to be run, got %v", log)
+	}
+
+	// The dependencies are fetched before the chart is linted
+	opts.Helm.LintBeforeRender = true
+	if _, err := TemplateFromApplication(context.Background(), opts); err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if log := readHelmLog(t, logFile); len(log) != 3 || !strings.HasPrefix(log[1], "dependency update ") || !strings.HasPrefix(log[2], "lint ") {
+		t.Errorf("Expected helm dependency update before helm lint, got %v", log)
+	}
+}
+
+func TestRunHelmDependencyUpdateWithoutHelm(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	err := runHelmDependencyUpdate(context.Background(), "helm", writeChartWithDependencies(t))
+	if err == nil {
+		t.Fatal("Expected error when helm is not in PATH")
+	}
+	if !strings.Contains(err.Error(), "helm binary not found in PATH") {
+		t.Errorf("Expected helm not found error, got: %v", err)
+	}
+}
+
+// fakeHelmScript records its arguments and extracts an empty chart on helm pull
+const fakeHelmScript = `#!/bin/sh
+echo "$@" >> "$FAKE_HELM_LOG"
+if [ "$1" = "pull" ]; then
+	chart=$(basename "$2")
+	while [ $# -gt 0 ]; do
+		if [ "$1" = "--destination" ]; then
+			dest="$2"
+		fi
+		shift
+	done
+	mkdir -p "$dest/$chart"
+	printf 'apiVersion: v2\nname: %s\nversion: 0.1.0\n' "$chart" > "$dest/$chart/Chart.yaml"
+fi
+`
+
+// installFakeHelm puts a fake helm binary first in PATH and returns the file its invocations are logged to
+func installFakeHelm(t *testing.T) string {
+	t.Helper()
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "helm"), []byte(fakeHelmScript), 0755); err != nil {
+		t.Fatalf("Failed to write fake helm: %v", err)
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required for the fake helm binary")
+	}
+
+	logFile := filepath.Join(t.TempDir(), "helm.log")
+	t.Setenv("FAKE_HELM_LOG", logFile)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logFile
+}
+
+func readHelmLog(t *testing.T, logFile string) []string {
+	t.Helper()
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read fake helm log: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestDownloadHelmChartOCI(t *testing.T) {
+	logFile := installFakeHelm(t)
+	cacheDir := t.TempDir()
+	t.Setenv("HELM_OCI_USERNAME", "user")
+	t.Setenv("HELM_OCI_PASSWORD", "secret")
+
+	chartDir, err := downloadHelmChart(context.Background(), cacheDir, "oci://registry-1.docker.io/cloudpirates", "nginx", "0.1.6", DefaultCacheTTL, false, nil, "helm", 0, nil)
+	if err != nil {
+		t.Fatalf("downloadHelmChart failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(chartDir, "Chart.yaml")); err != nil {
+		t.Errorf("Expected chart to be extracted to %s: %v", chartDir, err)
+	}
+
+	calls := readHelmLog(t, logFile)
+	if len(calls) != 2 {
+		t.Fatalf("Expected a login and a pull without logout, got %v", calls)
+	}
+	registryConfig := registryConfigArg(t, calls[0])
+	if calls[0] != "registry login registry-1.docker.io --registry-config "+registryConfig+" --username user --password-stdin" {
+		t.Errorf("Unexpected login invocation: %s", calls[0])
+	}
+	if !strings.HasPrefix(calls[1], "pull oci://registry-1.docker.io/cloudpirates/nginx --version 0.1.6 --destination ") || !strings.HasSuffix(calls[1], " --registry-config "+registryConfig) {
+		t.Errorf("Unexpected pull invocation: %s", calls[1])
+	}
+	if strings.Contains(strings.Join(calls, "\n"), "secret") {
+		t.Error("Password must not be passed as an argument")
+	}
+
+	// The second download is served from the cache
+	cachedDir, err := downloadHelmChart(context.Background(), cacheDir, "oci://registry-1.docker.io/cloudpirates", "nginx", "0.1.6", DefaultCacheTTL, false, nil, "helm", 0, nil)
+	if err != nil {
+		t.Fatalf("downloadHelmChart failed: %v", err)
+	}
+	if cachedDir != chartDir {
+		t.Errorf("Expected cached chart directory %s, got %s", chartDir, cachedDir)
+	}
+	if calls := readHelmLog(t, logFile); len(calls) != 2 {
+		t.Errorf("Expected cached chart to not be pulled again, got %v", calls)
+	}
+}
+
+func TestHelmPullArgs(t *testing.T) {
+	args := helmPullArgs("https://charts.example.com/", "nginx", "1.0.0", "/tmp/cache")
+	expected := "pull https://charts.example.com/nginx --version 1.0.0 --destination /tmp/cache --untar"
+	if got := strings.Join(args, " "); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestDownloadHelmChartDigest(t *testing.T) {
+	logFile := installFakeHelm(t)
+	t.Setenv("HELM_OCI_USERNAME", "")
+	t.Setenv("HELM_OCI_PASSWORD", "")
+	t.Setenv("HELM_REGISTRY_CONFIG", filepath.Join(t.TempDir(), "missing.json"))
+	cacheDir := t.TempDir()
+
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	chartDir, err := downloadHelmChart(context.Background(), cacheDir, "oci://ghcr.io/myorg/charts", "nginx", digest, DefaultCacheTTL, false, nil, "helm", 0, nil)
+	if err != nil {
+		t.Fatalf("downloadHelmChart failed: %v", err)
+	}
+	if chartDir != filepath.Join(cacheDir, "chart-"+strings.Repeat("ab", 32)) {
+		t.Errorf("Expected the digest as cache key, got %s", chartDir)
+	}
+
+	calls := readHelmLog(t, logFile)
+	if len(calls) != 1 || !strings.HasPrefix(calls[0], "pull oci://ghcr.io/myorg/charts/nginx --version "+digest+" --destination ") {
+		t.Errorf("Unexpected helm invocations: %v", calls)
+	}
+}
+
+func TestDownloadHelmChartInvalidDigest(t *testing.T) {
+	logFile := installFakeHelm(t)
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	outside := filepath.Join(filepath.Dir(cacheDir), "outside")
+
+	for _, digest := range []string{"sha256:/../../outside", "sha256:" + strings.Repeat("AB", 32), "sha256:abc"} {
+		_, err := downloadHelmChart(context.Background(), cacheDir, "oci://ghcr.io/myorg/charts", "nginx", digest, 0, false, nil, "helm", 0, nil)
+		if err == nil || !strings.Contains(err.Error(), "invalid chart digest") {
+			t.Errorf("Expected an invalid digest error for %s, got %v", digest, err)
+		}
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written outside the cache, got %v", err)
+	}
+	if _, err := os.Stat(logFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no chart to be pulled, got %v", readHelmLog(t, logFile))
+	}
+}
+
+func TestChartDigestMultipleCharts(t *testing.T) {
+	logFile := installFakeHelm(t)
+	application := `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: digest
+spec:
+  sources:
+  - repoURL: oci://ghcr.io/myorg/charts
+    chart: nginx
+    targetRevision: 1.0.0
+  - repoURL: oci://ghcr.io/myorg/charts
+    chart: redis
+    targetRevision: 2.0.0
+`
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: writeApplication(t, application),
+		RepoRoot:        ".",
+		CacheDir:        t.TempDir(),
+		Helm:            HelmOptions{ChartDigest: "sha256:" + strings.Repeat("ab", 32)},
+	})
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) || renderErr.Phase != RenderPhaseParse {
+		t.Fatalf("Expected a parse error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "2 Helm chart sources") {
+		t.Errorf("Expected the number of chart sources in the error, got %v", err)
+	}
+	if _, err := os.Stat(logFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no chart to be pulled, got %v", readHelmLog(t, logFile))
+	}
+}
+
+func TestDownloadHelmChartInvalidChartYAML(t *testing.T) {
+	binDir := t.TempDir()
+	script := "#!/bin/sh\nmkdir -p \"$6/nginx\"\necho 'name: [' > \"$6/nginx/Chart.yaml\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "helm"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake helm: %v", err)
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required for the fake helm binary")
+	}
+	cacheDir := t.TempDir()
+
+	_, err := downloadHelmChart(context.Background(), cacheDir, "https://charts.example.com", "nginx", "1.0.0", DefaultCacheTTL, false, nil, filepath.Join(binDir, "helm"), 0, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to parse Chart.yaml") {
+		t.Fatalf("Expected a Chart.yaml parse error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+		t.Errorf("Expected the invalid chart to be removed from the cache, got %v", entries)
+	}
+}
+
+func TestDownloadHelmChartRetries(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required for the fake helm binary")
+	}
+	original := helmPullRetryDelay
+	t.Cleanup(func() { helmPullRetryDelay = original })
+	helmPullRetryDelay = time.Millisecond
+
+	// The fake helm fails with a network error until it was called three times
+	binDir := t.TempDir()
+	attemptsFile := filepath.Join(t.TempDir(), "attempts")
+	script := `#!/bin/sh
+echo attempt >> "` + attemptsFile + `"
+if [ $(wc -l < "` + attemptsFile + `") -lt 3 ]; then
+	echo 'Error: dial tcp: lookup charts.example.com: no such host' >&2
+	exit 1
+fi
+mkdir -p "$6/nginx"
+printf 'apiVersion: v2\nname: nginx\nversion: 1.0.0\n' > "$6/nginx/Chart.yaml"
+`
+	if err := os.WriteFile(filepath.Join(binDir, "helm"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake helm: %v", err)
+	}
+	helmBinary := filepath.Join(binDir, "helm")
+
+	var retryLog bytes.Buffer
+	if _, err := downloadHelmChart(context.Background(), t.TempDir(), "https://charts.example.com", "nginx", "1.0.0", DefaultCacheTTL, false, nil, helmBinary, 3, &retryLog); err != nil {
+		t.Fatalf("downloadHelmChart failed: %v", err)
+	}
+	if count := strings.Count(retryLog.String(), "retrying"); count != 2 {
+		t.Errorf("Expected 2 logged retries, got:\n%s", retryLog.String())
+	}
+
+	// Without retries the first network error fails the download
+	os.Remove(attemptsFile)
+	if _, err := downloadHelmChart(context.Background(), t.TempDir(), "https://charts.example.com", "nginx", "1.0.0", DefaultCacheTTL, false, nil, helmBinary, 0, nil); err == nil || !strings.Contains(err.Error(), "no such host") {
+		t.Errorf("Expected the network error without retries, got %v", err)
+	}
+}
+
+func TestTemplateFromApplicationTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required for the fake helm binary")
+	}
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "helm"), []byte("#!/bin/sh\nexec sleep 10\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake helm: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	appFile := writeApplication(t, `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: slow-chart
+spec:
+  source:
+    repoURL: https://charts.example.com
+    chart: nginx
+    targetRevision: 1.0.0
+  destination:
+    namespace: default
+`)
+	start := time.Now()
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		CacheDir:        t.TempDir(),
+		Timeout:         time.Millisecond,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the error to wrap context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the helm pull to be stopped by the timeout, took %s", elapsed)
+	}
+}
+
+func TestTemplateFromApplicationGenerateName(t *testing.T) {
+	installFakeHelm(t)
+	var releaseName string
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		releaseName = q.ApplicationSource.Helm.ReleaseName
+		return &apiclient.ManifestResponse{}, nil
+	})
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/helm/app.yaml",
+		RepoRoot:        ".",
+		Helm:            HelmOptions{GenerateName: true},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	chartName, err := readChartName("examples/helm/input")
+	if err != nil {
+		t.Fatalf("readChartName failed: %v", err)
+	}
+	suffix, found := strings.CutPrefix(releaseName, chartName+"-")
+	if _, err := strconv.ParseInt(suffix, 10, 64); !found || err != nil {
+		t.Errorf("Expected a release name generated from the chart name %s, got %q", chartName, releaseName)
+	}
+}
+
+func TestTemplateFromApplicationValuesObject(t *testing.T) {
+	installFakeHelm(t)
+	var values []byte
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		values = q.ApplicationSource.Helm.ValuesYAML()
+		return &apiclient.ManifestResponse{}, nil
+	})
+
+	appFile := writeApplication(t, `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: helm-example-app
+spec:
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: examples/helm/input
+    helm:
+      valuesObject:
+        replicaCount: 3
+        image:
+          tag: "1.21"
+  destination:
+    namespace: helm-namespace
+`)
+	extraValues := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(extraValues, []byte("image:\n  pullPolicy: Always\n"), 0644); err != nil {
+		t.Fatalf("Failed to write value file: %v", err)
+	}
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		Helm:            HelmOptions{ExtraValueFiles: []string{extraValues}},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	var parsed map[string]interface{}
+	if err := yaml.Unmarshal(values, &parsed); err != nil {
+		t.Fatalf("Failed to parse values passed to helm: %v", err)
+	}
+	image, _ := parsed["image"].(map[string]interface{})
+	if parsed["replicaCount"] != float64(3) || image["tag"] != "1.21" || image["pullPolicy"] != "Always" {
+		t.Errorf("Expected the valuesObject merged with the extra value file, got:\n%s", values)
+	}
+}
+
+func TestRunPostRenderer(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required for the post-renderer script")
+	}
+
+	script := filepath.Join(t.TempDir(), "post-renderer.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nsed \"s/$1/$2/\"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write post-renderer: %v", err)
+	}
+
+	manifests := []string{
+		`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"original"}}`,
+		`{"apiVersion":"v1","kind":"Service","metadata":{"name":"other"}}`,
+	}
+
+	result, err := runPostRenderer(context.Background(), script, []string{"original", "patched"}, manifests)
+	if err != nil {
+		t.Fatalf("runPostRenderer failed: %v", err)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 manifests, got %d: %v", len(result), result)
+	}
+	if !strings.Contains(result[0], `"name":"patched"`) {
+		t.Errorf("Expected manifest to be piped through the post-renderer, got %s", result[0])
+	}
+	if !strings.Contains(result[1], `"name":"other"`) {
+		t.Errorf("Expected untouched manifest to be preserved, got %s", result[1])
+	}
+}
+
+func TestDownloadHelmChartCacheTTL(t *testing.T) {
+	logFile := installFakeHelm(t)
+	cacheDir := t.TempDir()
+
+	chartDir, err := downloadHelmChart(context.Background(), cacheDir, "https://charts.example.com", "nginx", "1.0.0", time.Hour, false, nil, "helm", 0, nil)
+	if err != nil {
+		t.Fatalf("downloadHelmChart failed: %v", err)
+	}
+
+	// Expire the cache entry
+	expired := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(chartDir, expired, expired); err != nil {
+		t.Fatalf("Failed to expire cache entry: %v", err)
+	}
+	if _, err := downloadHelmChart(context.Background(), cacheDir, "https://charts.example.com", "nginx", "1.0.0", time.Hour, false, nil, "helm", 0, nil); err != nil {
+		t.Fatalf("downloadHelmChart failed: %v", err)
+	}
+	if calls := readHelmLog(t, logFile); len(calls) != 2 {
+		t.Errorf("Expected expired chart to be pulled again, got %v", calls)
+	}
+
+	// A negative TTL always downloads the chart
+	if _, err := downloadHelmChart(context.Background(), cacheDir, "https://charts.example.com", "nginx", "1.0.0", -1, false, nil, "helm", 0, nil); err != nil {
+		t.Fatalf("downloadHelmChart failed: %v", err)
+	}
+	if calls := readHelmLog(t, logFile); len(calls) != 3 {
+		t.Errorf("Expected chart to be pulled when caching is disabled, got %v", calls)
+	}
+}
+
+func TestHelmLintArgs(t *testing.T) {
+	helm := &v1alpha1.ApplicationSourceHelm{
+		ValueFiles: []string{"values-prod.yaml", "/shared/values.yaml"},
+		Values:     "replicaCount: 2\n",
+		Parameters: []v1alpha1.HelmParameter{
+			{Name: "image.tag", Value: "1.21"},
+			{Name: "version", Value: "1.0", ForceString: true},
+		},
+	}
+
+	args, cleanup, err := helmLintArgs(helmValueFiles{chartPath: "charts/app", repoRoot: "/repo"}, "prod", helm)
+	if err != nil {
+		t.Fatalf("helmLintArgs failed: %v", err)
+	}
+	defer cleanup()
+
+	valuesFile := args[9]
+	data, err := os.ReadFile(valuesFile)
+	if err != nil {
+		t.Fatalf("Failed to read values file: %v", err)
+	}
+	if string(data) != "replicaCount: 2\n" {
+		t.Errorf("Expected inline values to be written to %s, got %q", valuesFile, data)
+	}
+
+	expected := "lint charts/app --namespace prod --values charts/app/values-prod.yaml --values /repo/shared/values.yaml --values " + valuesFile + " --set image.tag=1.21 --set-string version=1.0"
+	if got := strings.Join(args, " "); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestHelmLintArgsValueFiles(t *testing.T) {
+	chartPath := t.TempDir()
+	valuesDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(valuesDir, "values-prod.yaml"), []byte("replicaCount: 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to write values file: %v", err)
+	}
+	helm := &v1alpha1.ApplicationSourceHelm{
+		ValueFiles:              []string{"$values/values-prod.yaml", "values-missing.yaml"},
+		IgnoreMissingValueFiles: true,
+	}
+	files := helmValueFiles{
+		chartPath:  chartPath,
+		repoRoot:   t.TempDir(),
+		refs:       map[string]*v1alpha1.RefTarget{"$values": {}},
+		sourceRefs: map[string]string{"values": valuesDir},
+	}
+
+	args, cleanup, err := helmLintArgs(files, "", helm)
+	if err != nil {
+		t.Fatalf("helmLintArgs failed: %v", err)
+	}
+	defer cleanup()
+
+	// $ref value files are read from the referenced source and ignored missing ones are skipped
+	expected := "lint " + chartPath + " --values " + filepath.Join(valuesDir, "values-prod.yaml")
+	if got := strings.Join(args, " "); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	helm.IgnoreMissingValueFiles = false
+	args, cleanup, err = helmLintArgs(files, "", helm)
+	if err != nil {
+		t.Fatalf("helmLintArgs failed: %v", err)
+	}
+	defer cleanup()
+	if !slices.Contains(args, filepath.Join(chartPath, "values-missing.yaml")) {
+		t.Errorf("Expected the missing values file to be passed when it is not ignored, got %v", args)
+	}
+}
+
+func TestTemplateFromApplicationHelmLint(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required for the fake helm binary")
+	}
+
+	// The fake helm fails linting like helm does for a chart without Chart.yaml
+	binDir := t.TempDir()
+	script := "#!/bin/sh\necho \"Error: unable to check Chart.yaml file in chart: stat Chart.yaml: no such file or directory\"\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(binDir, "helm"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake helm: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	repoRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoRoot, "chart", "templates"), 0755); err != nil {
+		t.Fatalf("Failed to create chart: %v", err)
+	}
+	application := `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: broken-chart
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: chart
+    helm:
+      releaseName: broken
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`
+	if err := os.WriteFile(filepath.Join(repoRoot, "application.yaml"), []byte(application), 0644); err != nil {
+		t.Fatalf("Failed to write application: %v", err)
+	}
+	t.Chdir(repoRoot)
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "application.yaml",
+		RepoRoot:        ".",
+		Helm:            HelmOptions{LintBeforeRender: true},
+	})
+
+	var lintErr *HelmLintError
+	if !errors.As(err, &lintErr) {
+		t.Fatalf("Expected HelmLintError, got %v", err)
+	}
+	if !strings.Contains(lintErr.Output, "unable to check Chart.yaml") {
+		t.Errorf("Expected lint output in error, got %q", lintErr.Output)
+	}
+}
+
+func TestDownloadHelmChartVerifyCache(t *testing.T) {
+	logFile := installFakeHelm(t)
+	cacheDir := t.TempDir()
+
+	chartDir, err := downloadHelmChart(context.Background(), cacheDir, "https://charts.example.com", "nginx", "1.0.0", time.Hour, true, nil, "helm", 0, nil)
+	if err != nil {
+		t.Fatalf("downloadHelmChart failed: %v", err)
+	}
+
+	// An unmodified chart is served from the cache
+	if _, err := downloadHelmChart(context.Background(), cacheDir, "https://charts.example.com", "nginx", "1.0.0", time.Hour, true, nil, "helm", 0, nil); err != nil {
+		t.Fatalf("downloadHelmChart failed: %v", err)
+	}
+	if calls := readHelmLog(t, logFile); len(calls) != 1 {
+		t.Fatalf("Expected unmodified chart to be served from the cache, got %v", calls)
+	}
+
+	// Corrupt the cached chart
+	if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("corrupted"), 0644); err != nil {
+		t.Fatalf("Failed to modify cached chart: %v", err)
+	}
+
+	if _, err := downloadHelmChart(context.Background(), cacheDir, "https://charts.example.com", "nginx", "1.0.0", time.Hour, true, nil, "helm", 0, nil); err != nil {
+		t.Fatalf("downloadHelmChart failed: %v", err)
+	}
+	if calls := readHelmLog(t, logFile); len(calls) != 2 {
+		t.Errorf("Expected modified chart to be downloaded again, got %v", calls)
+	}
+
+	data, err := os.ReadFile(filepath.Join(chartDir, "Chart.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read Chart.yaml: %v", err)
+	}
+	if string(data) == "corrupted" {
+		t.Error("Expected corrupted chart to be replaced")
+	}
+}
+
+func TestComputeDirectoryHash(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "templates"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("name: test\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "templates", "cm.yaml"), []byte("kind: ConfigMap\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	first, err := computeDirectoryHash(dir)
+	if err != nil {
+		t.Fatalf("computeDirectoryHash failed: %v", err)
+	}
+	second, err := computeDirectoryHash(dir)
+	if err != nil {
+		t.Fatalf("computeDirectoryHash failed: %v", err)
+	}
+	if first != second {
+		t.Error("Expected the hash to be stable")
+	}
+
+	if err := os.Rename(filepath.Join(dir, "templates", "cm.yaml"), filepath.Join(dir, "templates", "configmap.yaml")); err != nil {
+		t.Fatalf("Failed to rename file: %v", err)
+	}
+	renamed, err := computeDirectoryHash(dir)
+	if err != nil {
+		t.Fatalf("computeDirectoryHash failed: %v", err)
+	}
+	if renamed == first {
+		t.Error("Expected renaming a file to change the hash")
+	}
+}
+
+// writeEnvChart writes a chart whose Deployment uses the image tag from a values file
+// referencing $MY_TAG and returns the Application rendering it
+func writeEnvChart(t *testing.T) string {
+	t.Helper()
+	repoRoot := t.TempDir()
+	t.Chdir(repoRoot)
+
+	files := map[string]string{
+		"chart/Chart.yaml":       "apiVersion: v2\nname: env\nversion: 0.1.0\n",
+		"chart/values.yaml":      "image:\n  tag: latest\n",
+		"chart/values-env.yaml":  "image:\n  tag: $MY_TAG\n",
+		"chart/templates/d.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: env\n  annotations:\n    owner: {{ .Values.owner | quote }}\nspec:\n  template:\n    spec:\n      containers:\n      - name: app\n        image: nginx:{{ .Values.image.tag }}\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	return writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: env
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: chart
+    helm:
+      valueFiles:
+      - values-env.yaml
+      values: |
+        owner: ${MY_OWNER}
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`)
+}
+
+func TestTemplateFromApplicationExpandEnvInValues(t *testing.T) {
+	if _, err := exec.LookPath("helm"); err != nil {
+		t.Skip("helm is required to render Helm sources")
+	}
+	appFile := writeEnvChart(t)
+	t.Setenv("MY_TAG", "1.2.3")
+	t.Setenv("MY_OWNER", "")
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		Helm:            HelmOptions{ExpandEnvInValues: true},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	containers, _, _ := unstructured.NestedSlice(result.Objects[0].Object, "spec", "template", "spec", "containers")
+	if image := containers[0].(map[string]interface{})["image"]; image != "nginx:1.2.3" {
+		t.Errorf("Expected the expanded image tag, got %v", image)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0].Message, "MY_OWNER is empty") {
+		t.Errorf("Expected a warning for the empty variable, got %v", result.Warnings)
+	}
+}
+
+func TestExpandEnvInHelmValues(t *testing.T) {
+	installFakeHelm(t)
+	appFile := writeEnvChart(t)
+	t.Setenv("MY_TAG", "1.2.3")
+	t.Setenv("MY_OWNER", "team-a")
+
+	var valueFiles []string
+	var values string
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		for _, valueFile := range q.ApplicationSource.Helm.ValueFiles {
+			data, err := os.ReadFile(filepath.Join(appPath, valueFile))
+			if err != nil {
+				return nil, err
+			}
+			valueFiles = append(valueFiles, string(data))
+		}
+		values = string(q.ApplicationSource.Helm.ValuesYAML())
+		return &apiclient.ManifestResponse{}, nil
+	})
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		Helm:            HelmOptions{ExpandEnvInValues: true},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	// The expanded values file is merged below the inline values instead of written to the chart
+	if len(valueFiles) != 0 {
+		t.Errorf("Expected the expanded values file to be merged into the inline values, got %q", valueFiles)
+	}
+	if !strings.Contains(values, "tag: 1.2.3") {
+		t.Errorf("Expected the expanded values file in the inline values, got %q", values)
+	}
+	if !strings.Contains(values, "owner: team-a") {
+		t.Errorf("Expected the expanded inline values, got %q", values)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", result.Warnings)
+	}
+
+	entries, err := os.ReadDir("chart")
+	if err != nil || len(entries) != 4 {
+		t.Errorf("Expected no expanded values to be written to the chart, got %v, %v", entries, err)
+	}
+}
+
+func TestTemplateFromApplicationHelmBinaryPath(t *testing.T) {
+	logFile := installFakeHelm(t)
+	helm3 := filepath.Join(t.TempDir(), "helm3")
+	if err := os.WriteFile(helm3, []byte("#!/bin/sh\necho \"helm3 $@\" >> \"$FAKE_HELM_LOG\"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake helm3: %v", err)
+	}
+
+	// Argo CD runs helm template with the helm from PATH
+	var templateBinary string
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		helm, err := exec.LookPath("helm")
+		if err != nil {
+			return nil, err
+		}
+		templateBinary, err = filepath.EvalSymlinks(helm)
+		return &apiclient.ManifestResponse{}, err
+	})
+	opts := TemplateOptions{
+		ApplicationFile: "examples/helm/app.yaml",
+		RepoRoot:        ".",
+		Helm: HelmOptions{
+			BinaryPath:         helm3,
+			LintBeforeRender:   true,
+			UpdateDependencies: true,
+		},
+	}
+	path := os.Getenv("PATH")
+
+	// The library does not change PATH of the process, so a different helm in PATH is an error
+	_, err := TemplateFromApplication(context.Background(), opts)
+	if err == nil || !strings.Contains(err.Error(), "put it first in PATH") {
+		t.Fatalf("Expected an error for the helm in PATH, got %v", err)
+	}
+	if os.Getenv("PATH") != path {
+		t.Errorf("Expected PATH to be left unchanged, got %s", os.Getenv("PATH"))
+	}
+	if _, err := os.Stat(logFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no helm calls, got %v", readHelmLog(t, logFile))
+	}
+
+	binDir := t.TempDir()
+	if err := os.Symlink(helm3, filepath.Join(binDir, "helm")); err != nil {
+		t.Fatalf("Failed to link fake helm3: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+path)
+
+	if _, err := TemplateFromApplication(context.Background(), opts); err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	calls := readHelmLog(t, logFile)
+	expected := []string{
+		"helm3 dependency update examples/helm/input",
+		"helm3 lint examples/helm/input --namespace helm-namespace --set image.tag=1.21 --set replicaCount=2",
+	}
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the helm binary to be called with:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(calls, "\n"))
+	}
+	if expected, _ := filepath.EvalSymlinks(helm3); templateBinary != expected {
+		t.Errorf("Expected helm template to run %s, got %s", expected, templateBinary)
+	}
+}
+
+func TestTemplateFromHelmChart(t *testing.T) {
+	if _, err := exec.LookPath("helm"); err != nil {
+		t.Skip("helm is required to render Helm sources")
+	}
+
+	// The same release as examples/helm/app.yaml, with the parameters given as values
+	values := map[string]interface{}{
+		"image":        map[string]interface{}{"tag": "1.21"},
+		"replicaCount": 2,
+	}
+	result, err := TemplateFromHelmChart(context.Background(), "examples/helm/input", values, HelmChartOptions{
+		ReleaseName: "helm-example-app",
+		Namespace:   "helm-namespace",
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromHelmChart failed: %v", err)
+	}
+
+	compareGolden(t, "examples/helm/expected.yaml", formatOutput(result))
+}
@@ -0,0 +1,67 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+)
+
+// HelmChartOptions contains the settings of a chart rendered without an Application
+type HelmChartOptions struct {
+	// ReleaseName is the name of the release, which is also used as the application name
+	// of the tracking label. The tracking label is omitted if empty.
+	ReleaseName string
+	// Namespace is the namespace the chart is rendered for
+	Namespace string
+	// KubeVersion is the Kubernetes version passed to helm template
+	KubeVersion string
+	SkipCrds    bool
+	SkipTests   bool
+	// APIVersions are passed to helm template with --api-versions
+	APIVersions []string
+	// ExtraValueFiles are merged over the values, in order. Relative paths are resolved
+	// against the current working directory.
+	ExtraValueFiles []string
+}
+
+// TemplateFromHelmChart renders a local Helm chart with the given values without an Application
+func TemplateFromHelmChart(ctx context.Context, chartPath string, values map[string]interface{}, opts HelmChartOptions) (*TemplateResult, error) {
+	info, err := os.Stat(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", chartPath)
+	}
+
+	app := &v1alpha1.Application{}
+	app.Name = opts.ReleaseName
+	app.Spec.Destination.Namespace = opts.Namespace
+
+	// The path is relative to the repository root, which is the chart itself
+	source := &v1alpha1.ApplicationSource{
+		Path: ".",
+		Helm: &v1alpha1.ApplicationSourceHelm{
+			ReleaseName: opts.ReleaseName,
+			SkipCrds:    opts.SkipCrds,
+			SkipTests:   opts.SkipTests,
+			APIVersions: opts.APIVersions,
+		},
+	}
+	if len(values) > 0 {
+		if err := setHelmValues(source.Helm, values); err != nil {
+			return nil, err
+		}
+	}
+	templateOpts := TemplateOptions{
+		RepoRoot:    chartPath,
+		KubeVersion: opts.KubeVersion,
+		Helm:        HelmOptions{ExtraValueFiles: opts.ExtraValueFiles},
+	}
+	// The extra value files are merged by the renderer like those of an Application
+	request := newManifestRequest(app, source, false, templateOpts)
+	return templateFromRequests(ctx, []*apiclient.ManifestRequest{request}, applicationSettings{}, templateOpts)
+}
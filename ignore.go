@@ -0,0 +1,115 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+// applyIgnoreDifferences removes the fields the Application ignores during comparison
+// from the matching objects. Objects without a namespace are matched against the
+// destination namespace. JQ path expressions are not supported and are reported as warnings.
+func applyIgnoreDifferences(objects []*unstructured.Unstructured, ignoreDifferences v1alpha1.IgnoreDifferences, namespace string) []string {
+	var warnings []string
+
+	for _, ignore := range ignoreDifferences {
+		if len(ignore.JQPathExpressions) > 0 {
+			warnings = append(warnings, fmt.Sprintf("ignoreDifferences for kind %s: jqPathExpressions are not supported and were not applied", ignore.Kind))
+		}
+	}
+
+	for _, obj := range objects {
+		for _, ignore := range ignoreDifferences {
+			if !ignoreDifferencesMatches(ignore, obj, namespace) {
+				continue
+			}
+			for _, pointer := range ignore.JSONPointers {
+				unstructured.RemoveNestedField(obj.Object, jsonPointerFields(pointer)...)
+			}
+			for _, manager := range ignore.ManagedFieldsManagers {
+				removeManagedFields(obj, manager)
+			}
+		}
+	}
+
+	return warnings
+}
+
+// ignoreDifferencesMatches reports whether the ignoreDifferences entry applies to the object.
+// An empty name or namespace matches every object and "*" matches any group or kind.
+func ignoreDifferencesMatches(ignore v1alpha1.ResourceIgnoreDifferences, obj *unstructured.Unstructured, namespace string) bool {
+	gvk := obj.GroupVersionKind()
+	if ignore.Group != "*" && ignore.Group != gvk.Group {
+		return false
+	}
+	if ignore.Kind != "*" && ignore.Kind != gvk.Kind {
+		return false
+	}
+	if ignore.Name != "" && ignore.Name != obj.GetName() {
+		return false
+	}
+	if ignore.Namespace != "" {
+		objNamespace := obj.GetNamespace()
+		if objNamespace == "" {
+			objNamespace = namespace
+		}
+		if ignore.Namespace != objNamespace {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonPointerFields splits a JSON pointer (RFC 6901) such as "/spec/replicas" into its fields
+func jsonPointerFields(pointer string) []string {
+	pointer = strings.TrimPrefix(pointer, "/")
+	if pointer == "" {
+		return nil
+	}
+	fields := strings.Split(pointer, "/")
+	for i, field := range fields {
+		fields[i] = strings.ReplaceAll(strings.ReplaceAll(field, "~1", "/"), "~0", "~")
+	}
+	return fields
+}
+
+// removeManagedFields removes the fields owned by the given manager according to the
+// managedFields of the object. Only fields of nested objects are removed, list items
+// (k: and v: entries) are left untouched.
+func removeManagedFields(obj *unstructured.Unstructured, manager string) {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager != manager || entry.FieldsV1 == nil {
+			continue
+		}
+		var owned map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &owned); err != nil {
+			continue
+		}
+		for _, fields := range managedFieldPaths(owned, nil) {
+			unstructured.RemoveNestedField(obj.Object, fields...)
+		}
+	}
+}
+
+// managedFieldPaths returns the paths of the leaf fields in a fieldsV1 set
+func managedFieldPaths(set map[string]interface{}, prefix []string) [][]string {
+	var paths [][]string
+	for key, value := range set {
+		field, ok := strings.CutPrefix(key, "f:")
+		if !ok {
+			continue
+		}
+		path := append(append([]string{}, prefix...), field)
+		children, _ := value.(map[string]interface{})
+		if len(children) == 0 {
+			paths = append(paths, path)
+			continue
+		}
+		paths = append(paths, managedFieldPaths(children, path)...)
+	}
+	return paths
+}
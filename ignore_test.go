@@ -0,0 +1,133 @@
+package renderer
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+func TestTemplateFromApplicationIgnoreDifferences(t *testing.T) {
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+  ignoreDifferences:
+  - group: apps
+    kind: Deployment
+    jsonPointers:
+    - /spec/replicas
+`)
+
+	for _, apply := range []bool{false, true} {
+		result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+			ApplicationFile:        appFile,
+			RepoRoot:               ".",
+			ApplyIgnoreDifferences: apply,
+		})
+		if err != nil {
+			t.Fatalf("TemplateFromApplication failed: %v", err)
+		}
+
+		var deployment *unstructured.Unstructured
+		for _, obj := range result.Objects {
+			if obj.GetKind() == "Deployment" {
+				deployment = obj
+			}
+		}
+		if deployment == nil {
+			t.Fatal("Expected a Deployment in the output")
+		}
+
+		_, found, _ := unstructured.NestedFieldNoCopy(deployment.Object, "spec", "replicas")
+		if found == apply {
+			t.Errorf("ApplyIgnoreDifferences=%v: spec.replicas present=%v", apply, found)
+		}
+		if _, found, _ := unstructured.NestedFieldNoCopy(deployment.Object, "spec", "template"); !found {
+			t.Errorf("ApplyIgnoreDifferences=%v: expected spec.template to be kept", apply)
+		}
+	}
+}
+
+func TestApplyIgnoreDifferences(t *testing.T) {
+	newDeployment := func(name string) *unstructured.Unstructured {
+		obj := newObject("apps/v1", "Deployment", "", name)
+		obj.Object["spec"] = map[string]interface{}{
+			"replicas": int64(3),
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": name}},
+		}
+		obj.Object["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{"a/b": "c"}
+		obj.Object["metadata"].(map[string]interface{})["managedFields"] = []interface{}{
+			map[string]interface{}{
+				"manager":    "hpa-controller",
+				"operation":  "Update",
+				"fieldsType": "FieldsV1",
+				"fieldsV1":   map[string]interface{}{"f:spec": map[string]interface{}{"f:replicas": map[string]interface{}{}}},
+			},
+		}
+		return obj
+	}
+
+	t.Run("json pointers", func(t *testing.T) {
+		matching := newDeployment("web")
+		other := newDeployment("worker")
+		warnings := applyIgnoreDifferences([]*unstructured.Unstructured{matching, other}, v1alpha1.IgnoreDifferences{
+			{Group: "apps", Kind: "Deployment", Name: "web", JSONPointers: []string{"/spec/replicas", "/metadata/annotations/a~1b"}},
+		}, "default")
+		if len(warnings) != 0 {
+			t.Errorf("Expected no warnings, got %v", warnings)
+		}
+		if _, found, _ := unstructured.NestedFieldNoCopy(matching.Object, "spec", "replicas"); found {
+			t.Error("Expected spec.replicas to be removed")
+		}
+		if _, found, _ := unstructured.NestedFieldNoCopy(matching.Object, "metadata", "annotations", "a/b"); found {
+			t.Error("Expected the escaped annotation to be removed")
+		}
+		if _, found, _ := unstructured.NestedFieldNoCopy(other.Object, "spec", "replicas"); !found {
+			t.Error("Expected spec.replicas of another Deployment to be kept")
+		}
+	})
+
+	t.Run("managed fields managers", func(t *testing.T) {
+		obj := newDeployment("web")
+		applyIgnoreDifferences([]*unstructured.Unstructured{obj}, v1alpha1.IgnoreDifferences{
+			{Group: "apps", Kind: "Deployment", ManagedFieldsManagers: []string{"hpa-controller"}},
+		}, "default")
+		if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "replicas"); found {
+			t.Error("Expected spec.replicas owned by the manager to be removed")
+		}
+		if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "selector"); !found {
+			t.Error("Expected spec.selector to be kept")
+		}
+	})
+
+	t.Run("namespace", func(t *testing.T) {
+		obj := newDeployment("web")
+		applyIgnoreDifferences([]*unstructured.Unstructured{obj}, v1alpha1.IgnoreDifferences{
+			{Group: "apps", Kind: "Deployment", Namespace: "other", JSONPointers: []string{"/spec/replicas"}},
+		}, "default")
+		if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "replicas"); !found {
+			t.Error("Expected spec.replicas to be kept for an object in another namespace")
+		}
+	})
+
+	t.Run("jq path expressions", func(t *testing.T) {
+		warnings := applyIgnoreDifferences(nil, v1alpha1.IgnoreDifferences{
+			{Kind: "ConfigMap", JQPathExpressions: []string{".data"}},
+		}, "default")
+		if len(warnings) != 1 {
+			t.Errorf("Expected a warning for jqPathExpressions, got %v", warnings)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/yannh/kubeconform/pkg/resource"
+	"github.com/yannh/kubeconform/pkg/validator"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// KubeconformOptions contains options for validating rendered manifests with kubeconform
+type KubeconformOptions struct {
+	// KubernetesVersion is the version of the schemas resources are validated against,
+	// e.g. "1.29.0". Defaults to the latest schemas.
+	KubernetesVersion string
+	// Strict rejects resources containing fields that are not part of the schema
+	Strict bool
+	// IgnoreMissingSchemas skips resources without a schema instead of reporting an error
+	IgnoreMissingSchemas bool
+	// SchemaLocations are kubeconform schema locations, either directories, URLs or
+	// templates. Defaults to the yannh/kubernetes-json-schema repository.
+	SchemaLocations []string
+}
+
+// ValidateWithKubeconform validates the objects with kubeconform
+func ValidateWithKubeconform(objects []*unstructured.Unstructured, opts KubeconformOptions) []ValidationError {
+	var validationErrors []ValidationError
+
+	v, err := validator.New(opts.SchemaLocations, validator.Opts{
+		KubernetesVersion:    opts.KubernetesVersion,
+		Strict:               opts.Strict,
+		IgnoreMissingSchemas: opts.IgnoreMissingSchemas,
+	})
+	if err != nil {
+		return []ValidationError{{Message: fmt.Sprintf("failed to create kubeconform validator: %v", err)}}
+	}
+
+	for _, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		newError := func(format string, args ...interface{}) ValidationError {
+			return ValidationError{
+				Group:     gvk.Group,
+				Version:   gvk.Version,
+				Kind:      gvk.Kind,
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				Message:   fmt.Sprintf(format, args...),
+			}
+		}
+
+		data, err := json.Marshal(obj.Object)
+		if err != nil {
+			validationErrors = append(validationErrors, newError("failed to marshal resource: %v", err))
+			continue
+		}
+
+		result := v.ValidateResource(resource.Resource{Bytes: data})
+		switch result.Status {
+		case validator.Invalid:
+			var messages []string
+			for _, validationError := range result.ValidationErrors {
+				messages = append(messages, fmt.Sprintf("%s: %s", validationError.Path, validationError.Msg))
+			}
+			if len(messages) == 0 && result.Err != nil {
+				messages = append(messages, result.Err.Error())
+			}
+			validationErrors = append(validationErrors, newError("%s", strings.Join(messages, "; ")))
+		case validator.Error:
+			validationErrors = append(validationErrors, newError("%v", result.Err))
+		}
+	}
+
+	return validationErrors
+}
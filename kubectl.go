@@ -0,0 +1,80 @@
+package renderer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Values of TemplateOptions.DryRunMode
+const (
+	// DryRunModeClient passes the rendered manifests to kubectl apply --dry-run=client
+	DryRunModeClient = "client"
+	// DryRunModeServer passes the rendered manifests to kubectl apply --dry-run=server, which
+	// validates them against the cluster
+	DryRunModeServer = "server"
+)
+
+// validateDryRunMode returns an error if the kubectl dry run mode is not supported
+func validateDryRunMode(mode string) error {
+	switch mode {
+	case DryRunModeClient, DryRunModeServer:
+		return nil
+	}
+	return fmt.Errorf("unsupported kubectl dry run mode %q, expected %q or %q", mode, DryRunModeClient, DryRunModeServer)
+}
+
+// KubectlDryRun pipes the objects to kubectl apply --dry-run in the given mode and returns the
+// warnings kubectl printed to stderr. The result of each object kubectl prints to stdout, e.g.
+// "configmap/x created (server dry run)", is discarded. An empty kubeconfigPath uses the
+// default kubeconfig of kubectl. Objects rejected by kubectl are returned as an error.
+func KubectlDryRun(ctx context.Context, objects []*unstructured.Unstructured, mode string, kubeconfigPath string) ([]string, error) {
+	if err := validateDryRunMode(mode); err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, nil
+	}
+	if err := checkBinaryExists("kubectl"); err != nil {
+		return nil, err
+	}
+
+	var manifests bytes.Buffer
+	for i, obj := range objects {
+		if i > 0 {
+			manifests.WriteString("---\n")
+		}
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", objectRef(obj), err)
+		}
+		manifests.Write(data)
+	}
+
+	args := []string{"apply", "--dry-run=" + mode, "-f", "-"}
+	if kubeconfigPath != "" {
+		args = append(args, "--kubeconfig", kubeconfigPath)
+	}
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	cmd.Stdin = &manifests
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("kubectl apply --dry-run=%s failed: %w\nOutput: %s", mode, err, stderr.String())
+	}
+
+	// kubectl prints warnings to stderr, e.g. about deprecated APIs
+	var warnings []string
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "Warning: "))
+		if line != "" {
+			warnings = append(warnings, line)
+		}
+	}
+	return warnings, nil
+}
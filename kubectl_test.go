@@ -0,0 +1,135 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// newFakeAPIServer returns a Kubernetes API server that knows ConfigMaps, accepts every dry run
+// create with a warning and rejects ConfigMaps named invalid. The dry run requests are recorded.
+func newFakeAPIServer(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var dryRuns []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api":
+			fmt.Fprint(w, `{"kind":"APIVersions","versions":["v1"],"serverAddressByClientCIDRs":[{"clientCIDR":"0.0.0.0/0","serverAddress":"127.0.0.1"}]}`)
+		case r.URL.Path == "/apis":
+			fmt.Fprint(w, `{"kind":"APIGroupList","apiVersion":"v1","groups":[]}`)
+		case r.URL.Path == "/openapi/v3":
+			fmt.Fprint(w, `{"paths":{"api/v1":{"serverRelativeURL":"/openapi/v3/api/v1"}}}`)
+		case r.URL.Path == "/openapi/v3/api/v1":
+			// Supporting dryRun and fieldValidation lets kubectl leave the validation to the server
+			fmt.Fprint(w, `{"openapi":"3.0.0","info":{"title":"Kubernetes","version":"v1.33.1"},"paths":{"/api/v1/namespaces/{namespace}/configmaps/{name}":{"patch":{"x-kubernetes-group-version-kind":{"group":"","version":"v1","kind":"ConfigMap"},"parameters":[{"name":"dryRun","in":"query","schema":{"type":"string"}},{"name":"fieldValidation","in":"query","schema":{"type":"string"}}]}}}}`)
+		case r.URL.Path == "/api/v1":
+			fmt.Fprint(w, `{"kind":"APIResourceList","groupVersion":"v1","resources":[{"name":"configmaps","singularName":"configmap","namespaced":true,"kind":"ConfigMap","verbs":["create","get","list","patch","update","delete"]}]}`)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/default/configmaps/"):
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/namespaces/default/configmaps":
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			dryRuns = append(dryRuns, r.URL.Query().Get("dryRun"))
+			mu.Unlock()
+			if strings.Contains(string(body), `"name":"invalid"`) {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"ConfigMap \"invalid\" is invalid: data: Invalid value","reason":"Invalid","code":422}`)
+				return
+			}
+			w.Header().Set("Warning", `299 - "configmap is deprecated"`)
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &dryRuns
+}
+
+// writeKubeconfig writes a kubeconfig for the server and returns its path
+func writeKubeconfig(t *testing.T, server string) string {
+	t.Helper()
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: fake
+  cluster:
+    server: %s
+    insecure-skip-tls-verify: true
+contexts:
+- name: fake
+  context:
+    cluster: fake
+    user: fake
+    namespace: default
+current-context: fake
+users:
+- name: fake
+  user:
+    token: fake
+`, server)
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(path, []byte(kubeconfig), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+	return path
+}
+
+func TestKubectlDryRun(t *testing.T) {
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		t.Skip("kubectl is required for the dry run")
+	}
+	server, dryRuns := newFakeAPIServer(t)
+	kubeconfig := writeKubeconfig(t, server.URL)
+	t.Setenv("HOME", t.TempDir())
+
+	configMap := newObject("v1", "ConfigMap", "default", "web")
+	configMap.Object["data"] = map[string]interface{}{"key": "value"}
+	warnings, err := KubectlDryRun(context.Background(), []*unstructured.Unstructured{configMap}, DryRunModeServer, kubeconfig)
+	if err != nil {
+		t.Fatalf("KubectlDryRun failed: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0] != "configmap is deprecated" {
+		t.Errorf("Expected the warning of the API server, got %v", warnings)
+	}
+	if len(*dryRuns) != 1 || (*dryRuns)[0] != "All" {
+		t.Errorf("Expected a single server-side dry run create, got %v", *dryRuns)
+	}
+
+	invalid := newObject("v1", "ConfigMap", "default", "invalid")
+	_, err = KubectlDryRun(context.Background(), []*unstructured.Unstructured{invalid}, DryRunModeServer, kubeconfig)
+	if err == nil || !strings.Contains(err.Error(), `ConfigMap "invalid" is invalid`) {
+		t.Errorf("Expected the object rejected by the API server to fail the dry run, got %v", err)
+	}
+}
+
+func TestKubectlDryRunInvalidMode(t *testing.T) {
+	_, err := KubectlDryRun(context.Background(), nil, "everything", "")
+	if err == nil || !strings.Contains(err.Error(), `unsupported kubectl dry run mode "everything"`) {
+		t.Errorf("Expected an error for an unsupported mode, got %v", err)
+	}
+
+	_, err = TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/directory/app.yaml",
+		RepoRoot:        ".",
+		DryRunMode:      "everything",
+	})
+	if err == nil || !strings.Contains(err.Error(), "unsupported kubectl dry run mode") {
+		t.Errorf("Expected TemplateFromApplication to reject the mode, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package renderer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// kubeVersionTimeout limits how long DetectKubeVersion waits for the API server
+const kubeVersionTimeout = 3 * time.Second
+
+// DetectKubeVersion returns the Major.Minor version of the cluster of the current kubeconfig
+// context. An empty kubeconfigPath uses the default kubeconfig loading rules.
+func DetectKubeVersion(ctx context.Context, kubeconfigPath string) (string, error) {
+	config, err := loadKubeconfig(kubeconfigPath)
+	if err != nil {
+		return "", err
+	}
+	config.Timeout = kubeVersionTimeout
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, kubeVersionTimeout)
+	defer cancel()
+
+	body, err := client.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	if err != nil {
+		return "", fmt.Errorf("failed to get server version: %w", err)
+	}
+
+	var info version.Info
+	if err := json.Unmarshal(body, &info); err != nil {
+		return "", fmt.Errorf("failed to parse server version: %w", err)
+	}
+
+	return formatKubeVersion(info)
+}
+
+// formatKubeVersion returns the Major.Minor version. Some providers report the minor
+// version with a suffix like "27+", which is stripped.
+func formatKubeVersion(info version.Info) (string, error) {
+	major := strings.TrimRight(info.Major, "+")
+	minor := strings.TrimRight(info.Minor, "+")
+	if major == "" || minor == "" {
+		return "", fmt.Errorf("server returned incomplete version %q", info.GitVersion)
+	}
+	return major + "." + minor, nil
+}
+
+// loadKubeconfig returns the client config of the current kubeconfig context. An empty
+// kubeconfigPath uses the default kubeconfig loading rules.
+func loadKubeconfig(kubeconfigPath string) (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfigPath
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,233 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+// KustomizeOptions contains options that are passed to kustomize build for every Kustomize source
+type KustomizeOptions struct {
+	// EnableAlphaPlugins allows alpha plugins such as generators and transformers
+	EnableAlphaPlugins bool
+	// EnableExec allows exec plugins, which can run arbitrary programs
+	EnableExec bool
+	// ForceCommonLabels are set on every resource, overwriting existing values. Unlike
+	// commonLabels they are not added to selectors.
+	ForceCommonLabels map[string]string
+	// ForceCommonAnnotations are set on every resource, overwriting existing values
+	ForceCommonAnnotations map[string]string
+	// LoadRestrictor is one of the LoadRestrictor constants. The default lifts the restriction
+	// when the source is built through the temporary overlay, whose root does not contain it.
+	LoadRestrictor string
+	// Reorder is passed to kustomize build --reorder, "legacy" or "none". It is ignored with a
+	// warning by kustomize versions before v5.
+	Reorder string
+	// EnableHelm lets kustomize inflate the charts in the helmCharts field of a kustomization
+	EnableHelm bool
+	// HelmCommand is the helm binary kustomize inflates charts with, helm from PATH if empty
+	HelmCommand string
+}
+
+// minReorderMajorVersion is the first major kustomize version --reorder is passed to
+const minReorderMajorVersion = 5
+
+// kustomizeVersionPattern matches the version in the output of kustomize version, e.g.
+// "v5.4.1" or "{Version:kustomize/v4.5.7 GitCommit:...}"
+var kustomizeVersionPattern = regexp.MustCompile(`v(\d+)\.\d+`)
+
+// kustomizeVersion returns the output of kustomize version, replaceable in tests
+var kustomizeVersion = func(ctx context.Context) (string, error) {
+	output, err := exec.CommandContext(ctx, "kustomize", "version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("kustomize version failed: %w\nOutput: %s", err, string(output))
+	}
+	return string(output), nil
+}
+
+// Values of KustomizeOptions.LoadRestrictor
+const (
+	// LoadRestrictorAuto lifts the restriction only for the temporary overlay
+	LoadRestrictorAuto = ""
+	// LoadRestrictorNone allows loading files outside the kustomization root
+	LoadRestrictorNone = "none"
+	// LoadRestrictorRootOnly only allows loading files inside the kustomization root
+	LoadRestrictorRootOnly = "root-only"
+)
+
+// validateKustomizeOptions returns an error if the load restrictor or the reorder option is not supported
+func validateKustomizeOptions(opts KustomizeOptions) error {
+	switch opts.LoadRestrictor {
+	case LoadRestrictorAuto, LoadRestrictorNone, LoadRestrictorRootOnly:
+	default:
+		return fmt.Errorf("unsupported kustomize load restrictor %q, expected %q or %q", opts.LoadRestrictor, LoadRestrictorNone, LoadRestrictorRootOnly)
+	}
+	switch opts.Reorder {
+	case "", "legacy", "none":
+	default:
+		return fmt.Errorf("unsupported kustomize reorder %q, expected legacy or none", opts.Reorder)
+	}
+	return nil
+}
+
+// checkReorderSupported clears the reorder option with a warning if the installed kustomize
+// does not support it
+func checkReorderSupported(ctx context.Context, opts KustomizeOptions) (KustomizeOptions, []string) {
+	if opts.Reorder == "" {
+		return opts, nil
+	}
+
+	output, err := kustomizeVersion(ctx)
+	if err != nil {
+		opts.Reorder = ""
+		return opts, []string{fmt.Sprintf("ignoring kustomize --reorder, failed to detect the kustomize version: %v", err)}
+	}
+	match := kustomizeVersionPattern.FindStringSubmatch(output)
+	if match == nil {
+		opts.Reorder = ""
+		return opts, []string{fmt.Sprintf("ignoring kustomize --reorder, unknown kustomize version %q", strings.TrimSpace(output))}
+	}
+	if major, _ := strconv.Atoi(match[1]); major < minReorderMajorVersion {
+		opts.Reorder = ""
+		return opts, []string{fmt.Sprintf("ignoring kustomize --reorder, it requires kustomize v%d or later, found v%s", minReorderMajorVersion, match[1])}
+	}
+	return opts, nil
+}
+
+// checkKustomizeHelm returns a warning if kustomize inflates Helm charts with helm from
+// PATH, but helm is not installed
+func checkKustomizeHelm(opts KustomizeOptions) []string {
+	if !opts.EnableHelm || opts.HelmCommand != "" {
+		return nil
+	}
+	if _, err := exec.LookPath("helm"); err != nil {
+		return []string{"kustomize Helm chart inflation is enabled, but helm was not found in PATH"}
+	}
+	return nil
+}
+
+// kustomizationLabels is an entry of the labels field of a kustomization
+type kustomizationLabels struct {
+	Pairs            map[string]string `json:"pairs"`
+	IncludeSelectors bool              `json:"includeSelectors"`
+}
+
+// kustomization is the part of a kustomization.yaml written for the temporary overlay
+type kustomization struct {
+	APIVersion        string                `json:"apiVersion"`
+	Kind              string                `json:"kind"`
+	Resources         []string              `json:"resources"`
+	Labels            []kustomizationLabels `json:"labels,omitempty"`
+	CommonAnnotations map[string]string     `json:"commonAnnotations,omitempty"`
+}
+
+// kustomizationOverlay returns the kustomization.yaml of an overlay that references the
+// original path and applies the forced labels and annotations
+func kustomizationOverlay(resourcePath string, opts KustomizeOptions) ([]byte, error) {
+	overlay := kustomization{
+		APIVersion:        "kustomize.config.k8s.io/v1beta1",
+		Kind:              "Kustomization",
+		Resources:         []string{resourcePath},
+		CommonAnnotations: opts.ForceCommonAnnotations,
+	}
+	if len(opts.ForceCommonLabels) > 0 {
+		overlay.Labels = []kustomizationLabels{{Pairs: opts.ForceCommonLabels}}
+	}
+
+	data, err := yaml.Marshal(overlay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal kustomization: %w", err)
+	}
+	return data, nil
+}
+
+// buildKustomizeArgs returns the additional arguments for kustomize build. isTempOverlay is
+// set when the source is built through the temporary overlay.
+func buildKustomizeArgs(opts KustomizeOptions, isTempOverlay bool) []string {
+	var args []string
+	if opts.EnableAlphaPlugins {
+		args = append(args, "--enable-alpha-plugins")
+	}
+	if opts.EnableExec {
+		args = append(args, "--enable-exec")
+	}
+	switch {
+	case opts.LoadRestrictor == LoadRestrictorNone,
+		opts.LoadRestrictor == LoadRestrictorAuto && isTempOverlay:
+		args = append(args, "--load-restrictor", "LoadRestrictionsNone")
+	case opts.LoadRestrictor == LoadRestrictorRootOnly:
+		args = append(args, "--load-restrictor", "LoadRestrictionsRootOnly")
+	}
+	if opts.Reorder != "" {
+		args = append(args, "--reorder", opts.Reorder)
+	}
+	if opts.EnableHelm {
+		args = append(args, "--enable-helm")
+	}
+	if opts.HelmCommand != "" {
+		args = append(args, "--helm-command", opts.HelmCommand)
+	}
+	return args
+}
+
+// kustomizeBuildOptions converts the options into the build options understood by Argo CD
+func kustomizeBuildOptions(opts KustomizeOptions, isTempOverlay bool) *v1alpha1.KustomizeOptions {
+	args := buildKustomizeArgs(opts, isTempOverlay)
+	if len(args) == 0 {
+		return nil
+	}
+	return &v1alpha1.KustomizeOptions{
+		BuildOptions: strings.Join(args, " "),
+	}
+}
+
+// createKustomizeOverlay writes a temporary overlay that references appPath and applies the
+// forced labels and annotations. It returns the absolute path of the overlay and a function
+// that removes it.
+func createKustomizeOverlay(appPath string, opts KustomizeOptions) (string, func(), error) {
+	tempDir, err := os.MkdirTemp(".", "kustomize-overlay-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating temp directory for Kustomize overlay: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(tempDir) }
+
+	// The overlay is created in the working directory, which can differ from the repository root
+	absTempDir, err := filepath.Abs(tempDir)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("error resolving temp directory: %w", err)
+	}
+	absAppPath, err := filepath.Abs(appPath)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("error resolving app path: %w", err)
+	}
+	relPath, err := filepath.Rel(absTempDir, absAppPath)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("error calculating relative path: %w", err)
+	}
+
+	// Create a kustomization.yaml that references the original path
+	kustomizationContent, err := kustomizationOverlay(relPath, opts)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	kustomizationPath := filepath.Join(tempDir, "kustomization.yaml")
+	if err := os.WriteFile(kustomizationPath, kustomizationContent, 0644); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("error writing kustomization.yaml: %w", err)
+	}
+	return absTempDir, cleanup, nil
+}
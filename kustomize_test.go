@@ -0,0 +1,320 @@
+package renderer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+)
+
+func TestBuildKustomizeArgs(t *testing.T) {
+	if args := buildKustomizeArgs(KustomizeOptions{}, false); len(args) != 0 {
+		t.Errorf("Expected no arguments by default, got %v", args)
+	}
+	if opts := kustomizeBuildOptions(KustomizeOptions{}, false); opts != nil {
+		t.Errorf("Expected no build options by default, got %v", opts)
+	}
+
+	args := buildKustomizeArgs(KustomizeOptions{EnableAlphaPlugins: true, EnableExec: true}, false)
+	expected := []string{"--enable-alpha-plugins", "--enable-exec"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	opts := kustomizeBuildOptions(KustomizeOptions{EnableAlphaPlugins: true}, false)
+	if opts == nil || opts.BuildOptions != "--enable-alpha-plugins" {
+		t.Errorf("Expected build options '--enable-alpha-plugins', got %v", opts)
+	}
+}
+
+func TestBuildKustomizeArgsLoadRestrictor(t *testing.T) {
+	tests := []struct {
+		name           string
+		loadRestrictor string
+		isTempOverlay  bool
+		expected       []string
+	}{
+		{name: "auto without overlay"},
+		{name: "auto with overlay", isTempOverlay: true, expected: []string{"--load-restrictor", "LoadRestrictionsNone"}},
+		{name: "none", loadRestrictor: LoadRestrictorNone, expected: []string{"--load-restrictor", "LoadRestrictionsNone"}},
+		{name: "root-only with overlay", loadRestrictor: LoadRestrictorRootOnly, isTempOverlay: true, expected: []string{"--load-restrictor", "LoadRestrictionsRootOnly"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := buildKustomizeArgs(KustomizeOptions{LoadRestrictor: tt.loadRestrictor}, tt.isTempOverlay)
+			if !reflect.DeepEqual(args, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, args)
+			}
+		})
+	}
+
+	opts := kustomizeBuildOptions(KustomizeOptions{}, true)
+	if opts == nil || opts.BuildOptions != "--load-restrictor LoadRestrictionsNone" {
+		t.Errorf("Expected the overlay to be built without load restrictions, got %v", opts)
+	}
+	if err := validateKustomizeOptions(KustomizeOptions{LoadRestrictor: "everything"}); err == nil {
+		t.Error("Expected an error for an unsupported load restrictor")
+	}
+}
+
+func TestCheckReorderSupported(t *testing.T) {
+	original := kustomizeVersion
+	t.Cleanup(func() { kustomizeVersion = original })
+
+	tests := []struct {
+		name     string
+		output   string
+		expected []string
+		warning  string
+	}{
+		{name: "v5", output: "v5.4.1\n", expected: []string{"--reorder", "none"}},
+		{name: "v4", output: "{Version:kustomize/v4.5.7 GitCommit:56d82a8378dfc8dc3b3b1085e5a6e67b82966bd7 BuildDate:2022-08-02T16:35:54Z GoOs:linux GoArch:amd64}\n", warning: "requires kustomize v5 or later, found v4"},
+		{name: "unknown", output: "development build\n", warning: "unknown kustomize version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kustomizeVersion = func(ctx context.Context) (string, error) {
+				return tt.output, nil
+			}
+
+			opts, warnings := checkReorderSupported(context.Background(), KustomizeOptions{Reorder: "none"})
+			if args := buildKustomizeArgs(opts, false); !reflect.DeepEqual(args, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, args)
+			}
+			if tt.warning == "" && len(warnings) != 0 {
+				t.Errorf("Expected no warnings, got %v", warnings)
+			}
+			if tt.warning != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], tt.warning)) {
+				t.Errorf("Expected a warning containing %q, got %v", tt.warning, warnings)
+			}
+		})
+	}
+
+	if err := validateKustomizeOptions(KustomizeOptions{Reorder: "alphabetical"}); err == nil {
+		t.Error("Expected an error for an unsupported reorder option")
+	}
+}
+
+func TestKustomizationOverlay(t *testing.T) {
+	data, err := kustomizationOverlay("../app", KustomizeOptions{
+		ForceCommonLabels:      map[string]string{"team": "platform"},
+		ForceCommonAnnotations: map[string]string{"owner": "platform@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("kustomizationOverlay failed: %v", err)
+	}
+
+	expected := `apiVersion: kustomize.config.k8s.io/v1beta1
+commonAnnotations:
+  owner: platform@example.com
+kind: Kustomization
+labels:
+- includeSelectors: false
+  pairs:
+    team: platform
+resources:
+- ../app
+`
+	if string(data) != expected {
+		t.Errorf("Expected overlay:\n%s\ngot:\n%s", expected, data)
+	}
+
+	data, err = kustomizationOverlay("../app", KustomizeOptions{})
+	if err != nil {
+		t.Fatalf("kustomizationOverlay failed: %v", err)
+	}
+	if strings.Contains(string(data), "labels") || strings.Contains(string(data), "commonAnnotations") {
+		t.Errorf("Expected no labels or annotations without forced values, got:\n%s", data)
+	}
+}
+
+func TestTemplateFromApplicationForceCommonLabels(t *testing.T) {
+	if _, err := exec.LookPath("kustomize"); err != nil {
+		t.Skip("kustomize is required to render Kustomize sources")
+	}
+
+	repoRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoRoot, "app"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	files := map[string]string{
+		"app/kustomization.yaml": "resources:\n- deployment.yaml\n",
+		"app/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx
+`,
+		"application.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: labels-app
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: app
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(filepath.Join(repoRoot, path), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	t.Chdir(repoRoot)
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "application.yaml",
+		RepoRoot:        ".",
+		Kustomize: KustomizeOptions{
+			ForceCommonLabels:      map[string]string{"team": "platform"},
+			ForceCommonAnnotations: map[string]string{"owner": "platform@example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	if len(result.Objects) != 1 {
+		t.Fatalf("Expected 1 object, got %d", len(result.Objects))
+	}
+	deployment := result.Objects[0]
+	if deployment.GetLabels()["team"] != "platform" {
+		t.Errorf("Expected forced label, got %v", deployment.GetLabels())
+	}
+	if deployment.GetAnnotations()["owner"] != "platform@example.com" {
+		t.Errorf("Expected forced annotation, got %v", deployment.GetAnnotations())
+	}
+	selector, _, _ := unstructured.NestedStringMap(deployment.Object, "spec", "selector", "matchLabels")
+	if _, found := selector["team"]; found {
+		t.Errorf("Expected forced label to not be added to the selector, got %v", selector)
+	}
+}
+
+func TestTemplateFromApplicationKustomizeEnableHelm(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoRoot, "app"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	files := map[string]string{
+		"app/kustomization.yaml": `helmCharts:
+- name: podinfo
+  repo: https://stefanprodan.github.io/podinfo
+  version: 6.5.4
+  releaseName: podinfo
+`,
+		"application.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: helm-charts-app
+spec:
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: app
+  destination:
+    namespace: default
+`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(filepath.Join(repoRoot, path), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	t.Chdir(repoRoot)
+
+	var buildOptions string
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		buildOptions = q.KustomizeOptions.BuildOptions
+		return &apiclient.ManifestResponse{}, nil
+	})
+
+	// Only kustomize is installed, the build itself is replaced by generateManifests
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "kustomize"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake kustomize: %v", err)
+	}
+	t.Setenv("PATH", binDir)
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "application.yaml",
+		RepoRoot:        ".",
+		Kustomize:       KustomizeOptions{EnableHelm: true},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if !slices.Contains(strings.Fields(buildOptions), "--enable-helm") {
+		t.Errorf("Expected --enable-helm in the build options, got %q", buildOptions)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0].Message, "helm was not found in PATH") {
+		t.Errorf("Expected a warning about the missing helm binary, got %v", result.Warnings)
+	}
+
+	result, err = TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "application.yaml",
+		RepoRoot:        ".",
+		Kustomize:       KustomizeOptions{EnableHelm: true, HelmCommand: "/opt/helm/bin/helm"},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if !strings.Contains(buildOptions, "--enable-helm --helm-command /opt/helm/bin/helm") {
+		t.Errorf("Expected the helm command in the build options, got %q", buildOptions)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("Expected no warnings with an explicit helm command, got %v", result.Warnings)
+	}
+}
+
+func TestTemplateFromKustomizationDir(t *testing.T) {
+	if _, err := exec.LookPath("kustomize"); err != nil {
+		t.Skip("kustomize is required to render Kustomize sources")
+	}
+
+	result, err := TemplateFromKustomizationDir(context.Background(), "examples/kustomize/input", &KustomizationOverrides{
+		Images:   []string{"nginx:1.25"},
+		Replicas: map[string]int{"my-app": 4},
+	}, KustomizeOptions{})
+	if err != nil {
+		t.Fatalf("TemplateFromKustomizationDir failed: %v", err)
+	}
+
+	if len(result.Objects) != 1 {
+		t.Fatalf("Expected 1 object, got %v", objectKeys(result.Objects))
+	}
+	containers, _, _ := unstructured.NestedSlice(result.Objects[0].Object, "spec", "template", "spec", "containers")
+	if image := containers[0].(map[string]interface{})["image"]; image != "nginx:1.25" {
+		t.Errorf("Expected the overridden image, got %v", image)
+	}
+	if replicas, _, _ := unstructured.NestedInt64(result.Objects[0].Object, "spec", "replicas"); replicas != 4 {
+		t.Errorf("Expected the overridden replicas, got %d", replicas)
+	}
+	if len(result.SourceTypes) != 1 || result.SourceTypes[0] != v1alpha1.ApplicationSourceTypeKustomize {
+		t.Errorf("Expected a Kustomize source, got %v", result.SourceTypes)
+	}
+}
@@ -0,0 +1,105 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/util/git"
+	"github.com/argoproj/argo-cd/v3/util/kustomize"
+)
+
+// KustomizationOverrides are applied to a kustomization rendered without an Application, like
+// the kustomize settings of an Application source
+type KustomizationOverrides struct {
+	NamePrefix string
+	NameSuffix string
+	// Namespace is set on every namespaced resource
+	Namespace string
+	// Images override the images of the resources, e.g. nginx:1.25 or nginx=registry/nginx:1.25
+	Images            []string
+	CommonLabels      map[string]string
+	CommonAnnotations map[string]string
+	// Replicas override the replica count of Deployments and StatefulSets by name
+	Replicas map[string]int
+}
+
+// kustomizeSettings returns the overrides as the kustomize settings of an Argo CD source
+func (o *KustomizationOverrides) kustomizeSettings() *v1alpha1.ApplicationSourceKustomize {
+	if o == nil {
+		return nil
+	}
+	settings := &v1alpha1.ApplicationSourceKustomize{
+		NamePrefix:        o.NamePrefix,
+		NameSuffix:        o.NameSuffix,
+		Namespace:         o.Namespace,
+		CommonLabels:      o.CommonLabels,
+		CommonAnnotations: o.CommonAnnotations,
+	}
+	for _, image := range o.Images {
+		settings.Images = append(settings.Images, v1alpha1.KustomizeImage(image))
+	}
+	// Sorted, so kustomize edit is run the same way every time
+	for _, name := range slices.Sorted(maps.Keys(o.Replicas)) {
+		settings.Replicas = append(settings.Replicas, v1alpha1.KustomizeReplica{Name: name, Count: intstr.FromInt(o.Replicas[name])})
+	}
+	return settings
+}
+
+// TemplateFromKustomizationDir renders a kustomization directory without an Application. The
+// overrides may be nil.
+func TemplateFromKustomizationDir(ctx context.Context, dir string, overrides *KustomizationOverrides, opts KustomizeOptions) (*TemplateResult, error) {
+	start := time.Now()
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kustomization directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	if err := validateKustomizeOptions(opts); err != nil {
+		return nil, err
+	}
+	if err := checkBinaryExists("kustomize"); err != nil {
+		return nil, err
+	}
+	repoRoot, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving kustomization directory: %w", err)
+	}
+
+	kustomizeOpts, warnings := checkReorderSupported(ctx, opts)
+	warnings = append(warnings, checkKustomizeHelm(kustomizeOpts)...)
+
+	// The overrides are applied with kustomize edit, which changes the kustomization in place
+	overlayDir, cleanup, err := createKustomizeOverlay(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// The environment must not be nil, the images of the overrides are substituted with it
+	app := kustomize.NewKustomizeApp(repoRoot, overlayDir, git.NopCreds{}, "", "", "", "")
+	objects, _, _, err := app.Build(overrides.kustomizeSettings(), kustomizeBuildOptions(kustomizeOpts, true), &v1alpha1.Env{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error building kustomization %s: %w", dir, err)
+	}
+	duration := time.Since(start)
+
+	return &TemplateResult{
+		Objects:          objects,
+		Warnings:         newWarnings(WarningSeverityWarning, 0, warnings),
+		SourcesProcessed: 1,
+		SourceTypes:      []v1alpha1.ApplicationSourceType{v1alpha1.ApplicationSourceTypeKustomize},
+		SourceDurations:  []time.Duration{duration},
+		TotalDuration:    duration,
+		Stats:            ComputeStats(objects),
+	}, nil
+}
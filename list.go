@@ -0,0 +1,21 @@
+package renderer
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// WrapInList wraps the objects in a single v1 List, preserving their order
+func WrapInList(objects []*unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	items := make([]interface{}, 0, len(objects))
+	for _, obj := range objects {
+		items = append(items, obj.DeepCopy().Object)
+	}
+
+	list := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	list.SetAPIVersion("v1")
+	list.SetKind("List")
+	if err := unstructured.SetNestedSlice(list.Object, items, "items"); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,49 @@
+package renderer
+
+import (
+	"encoding/json"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestWrapInList(t *testing.T) {
+	objects := []*unstructured.Unstructured{
+		newObject("apps/v1", "Deployment", "default", "web"),
+		newObject("v1", "Service", "default", "web"),
+		newObject("v1", "ConfigMap", "default", "config"),
+	}
+
+	list, err := WrapInList(objects)
+	if err != nil {
+		t.Fatalf("WrapInList failed: %v", err)
+	}
+
+	data, err := json.Marshal(list.Object)
+	if err != nil {
+		t.Fatalf("Failed to marshal list: %v", err)
+	}
+
+	var parsed corev1.List
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("Failed to unmarshal list: %v", err)
+	}
+
+	if parsed.APIVersion != "v1" || parsed.Kind != "List" {
+		t.Errorf("Expected v1 List, got %s %s", parsed.APIVersion, parsed.Kind)
+	}
+	if len(parsed.Items) != len(objects) {
+		t.Fatalf("Expected %d items, got %d", len(objects), len(parsed.Items))
+	}
+
+	for i, item := range parsed.Items {
+		var obj unstructured.Unstructured
+		if err := json.Unmarshal(item.Raw, &obj.Object); err != nil {
+			t.Fatalf("Failed to unmarshal item %d: %v", i, err)
+		}
+		if obj.GetKind() != objects[i].GetKind() {
+			t.Errorf("Item %d: expected %s, got %s", i, objects[i].GetKind(), obj.GetKind())
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+// Event is something that happened while rendering an Application, passed to TemplateOptions.Logger
+type Event interface {
+	// EventName identifies the type of the event, e.g. source_detected
+	EventName() string
+}
+
+// SourceDetectedEvent is logged when the type of a source has been detected, before it is rendered
+type SourceDetectedEvent struct {
+	SourceIndex int                            `json:"sourceIndex"`
+	SourceType  v1alpha1.ApplicationSourceType `json:"sourceType"`
+	// Path is the local directory of the source, the chart directory of remote charts
+	Path string `json:"path"`
+	// Command is the equivalent command line that renders the source
+	Command string `json:"command"`
+}
+
+func (SourceDetectedEvent) EventName() string { return "source_detected" }
+
+// CommandExecutedEvent is logged when a source has been rendered
+type CommandExecutedEvent struct {
+	SourceIndex int                            `json:"sourceIndex"`
+	SourceType  v1alpha1.ApplicationSourceType `json:"sourceType"`
+	Command     string                         `json:"command"`
+	// Duration is how long rendering the source took, in nanoseconds in JSON
+	Duration time.Duration `json:"duration"`
+}
+
+func (CommandExecutedEvent) EventName() string { return "command_executed" }
+
+// ManifestCountEvent is logged with the number of manifests a source rendered, before the
+// manifests are filtered
+type ManifestCountEvent struct {
+	SourceIndex int `json:"sourceIndex"`
+	Count       int `json:"count"`
+}
+
+func (ManifestCountEvent) EventName() string { return "manifest_count" }
+
+// AppNameOverrideEvent is logged when the tracking label is set to another name than the
+// name of the Application
+type AppNameOverrideEvent struct {
+	AppName      string `json:"appName"`
+	TrackingName string `json:"trackingName"`
+}
+
+func (AppNameOverrideEvent) EventName() string { return "app_name_override" }
+
+// Logger receives the events of the templating process. Sources rendered concurrently log
+// from several goroutines.
+type Logger interface {
+	Log(event Event)
+}
+
+// StderrLogger writes the events as human-readable text, the output of TemplateOptions.Verbose
+type StderrLogger struct {
+	// Output receives the text, os.Stderr if nil
+	Output io.Writer
+}
+
+func (l StderrLogger) Log(event Event) {
+	var b strings.Builder
+	switch e := event.(type) {
+	case SourceDetectedEvent:
+		fmt.Fprintf(&b, "Source %d:\n", e.SourceIndex+1)
+		fmt.Fprintf(&b, "  Source Type: %s\n", e.SourceType)
+		fmt.Fprintf(&b, "  Path: %s\n", e.Path)
+		fmt.Fprintf(&b, "  Command: %s\n", e.Command)
+	case CommandExecutedEvent:
+		fmt.Fprintf(&b, "Source %d (%s) rendered in %.2fs\n", e.SourceIndex+1, e.SourceType, e.Duration.Seconds())
+	case ManifestCountEvent:
+		fmt.Fprintf(&b, "Source %d rendered %d manifests\n", e.SourceIndex+1, e.Count)
+	case AppNameOverrideEvent:
+		fmt.Fprintf(&b, "Warning: the tracking label is set to %q instead of the Application name %q\n", e.TrackingName, e.AppName)
+	default:
+		fmt.Fprintf(&b, "%s: %+v\n", event.EventName(), event)
+	}
+	output := l.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	// Written at once so the output of concurrently rendered sources does not interleave
+	io.WriteString(output, b.String())
+}
+
+// JSONLogger writes every event as a JSON object on its own line, with the name of the event
+// in the event field, e.g. {"event":"manifest_count","sourceIndex":0,"count":3}
+type JSONLogger struct {
+	// Output receives the JSON lines, os.Stderr if nil
+	Output io.Writer
+}
+
+func (l JSONLogger) Log(event Event) {
+	fields, err := json.Marshal(event)
+	if err != nil {
+		fields = []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+	line, _ := json.Marshal(event.EventName())
+	line = append([]byte(`{"event":`), line...)
+	// Splice the fields of the event into the object after the event name
+	if len(fields) > 2 {
+		line = append(line, ',')
+		line = append(line, fields[1:]...)
+	} else {
+		line = append(line, '}')
+	}
+	line = append(line, '\n')
+	output := l.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	output.Write(line)
+}
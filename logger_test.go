@@ -0,0 +1,67 @@
+package renderer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+// testLogger collects the logged events
+type testLogger struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (l *testLogger) Log(event Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func TestTemplateFromApplicationLogger(t *testing.T) {
+	logger := &testLogger{}
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/directory/app.yaml",
+		RepoRoot:        ".",
+		Logger:          logger,
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	if len(logger.events) != 3 {
+		t.Fatalf("Expected 3 events, got %+v", logger.events)
+	}
+	detected, ok := logger.events[0].(SourceDetectedEvent)
+	if !ok || detected.SourceType != v1alpha1.ApplicationSourceTypeDirectory || detected.Path != "examples/directory/input" {
+		t.Errorf("Expected the directory source to be detected first, got %+v", logger.events[0])
+	}
+	executed, ok := logger.events[1].(CommandExecutedEvent)
+	if !ok || executed.Command != detected.Command || !strings.HasPrefix(executed.Command, "read manifests from") {
+		t.Errorf("Expected the command of the source to be executed, got %+v", logger.events[1])
+	}
+	if count, ok := logger.events[2].(ManifestCountEvent); !ok || count.SourceIndex != 0 || count.Count != 2 {
+		t.Errorf("Expected 2 manifests of the source, got %+v", logger.events[2])
+	}
+}
+
+func TestJSONLogger(t *testing.T) {
+	var output bytes.Buffer
+	logger := JSONLogger{Output: &output}
+	logger.Log(SourceDetectedEvent{SourceIndex: 0, SourceType: v1alpha1.ApplicationSourceTypeHelm, Path: "./charts/myapp", Command: "helm template ./charts/myapp"})
+	logger.Log(CommandExecutedEvent{SourceIndex: 0, SourceType: v1alpha1.ApplicationSourceTypeHelm, Command: "helm template ./charts/myapp", Duration: time.Second})
+	logger.Log(ManifestCountEvent{SourceIndex: 1, Count: 3})
+
+	expected := `{"event":"source_detected","sourceIndex":0,"sourceType":"Helm","path":"./charts/myapp","command":"helm template ./charts/myapp"}
+{"event":"command_executed","sourceIndex":0,"sourceType":"Helm","command":"helm template ./charts/myapp","duration":1000000000}
+{"event":"manifest_count","sourceIndex":1,"count":3}
+`
+	if output.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output.String())
+	}
+}
@@ -0,0 +1,39 @@
+package renderer
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// InjectOptions contains labels and annotations added to every rendered resource
+type InjectOptions struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	// Overwrite replaces labels and annotations that are already set on a resource
+	Overwrite bool
+}
+
+// applyMetadataInjections merges the labels and annotations into the metadata of every object
+func applyMetadataInjections(objects []*unstructured.Unstructured, opts InjectOptions) {
+	for _, obj := range objects {
+		if len(opts.Labels) > 0 {
+			obj.SetLabels(mergeMetadata(obj.GetLabels(), opts.Labels, opts.Overwrite))
+		}
+		if len(opts.Annotations) > 0 {
+			obj.SetAnnotations(mergeMetadata(obj.GetAnnotations(), opts.Annotations, opts.Overwrite))
+		}
+	}
+}
+
+// mergeMetadata adds the injected entries to the existing ones, keeping existing values unless overwrite is set
+func mergeMetadata(existing, injected map[string]string, overwrite bool) map[string]string {
+	if existing == nil {
+		existing = make(map[string]string, len(injected))
+	}
+	for key, value := range injected {
+		if _, found := existing[key]; found && !overwrite {
+			continue
+		}
+		existing[key] = value
+	}
+	return existing
+}
@@ -0,0 +1,53 @@
+package renderer
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestApplyMetadataInjections(t *testing.T) {
+	tests := []struct {
+		name      string
+		overwrite bool
+		wantTeam  string
+	}{
+		{name: "preserve existing", overwrite: false, wantTeam: "payments"},
+		{name: "overwrite existing", overwrite: true, wantTeam: "platform"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labeled := newObject("apps/v1", "Deployment", "default", "web")
+			labeled.SetLabels(map[string]string{"team": "payments", "app": "web"})
+			labeled.SetAnnotations(map[string]string{"owner": "payments@example.com"})
+			unlabeled := newObject("v1", "ConfigMap", "default", "config")
+
+			applyMetadataInjections([]*unstructured.Unstructured{labeled, unlabeled}, InjectOptions{
+				Labels:      map[string]string{"team": "platform", "environment": "staging"},
+				Annotations: map[string]string{"owner": "platform@example.com"},
+				Overwrite:   tt.overwrite,
+			})
+
+			labels := labeled.GetLabels()
+			if labels["team"] != tt.wantTeam {
+				t.Errorf("Expected team label %q, got %q", tt.wantTeam, labels["team"])
+			}
+			if labels["app"] != "web" || labels["environment"] != "staging" {
+				t.Errorf("Expected existing and injected labels to be merged, got %v", labels)
+			}
+
+			wantOwner := "payments@example.com"
+			if tt.overwrite {
+				wantOwner = "platform@example.com"
+			}
+			if owner := labeled.GetAnnotations()["owner"]; owner != wantOwner {
+				t.Errorf("Expected owner annotation %q, got %q", wantOwner, owner)
+			}
+
+			if unlabeled.GetLabels()["team"] != "platform" || unlabeled.GetAnnotations()["owner"] != "platform@example.com" {
+				t.Errorf("Expected metadata to be injected into resource without labels, got %v %v", unlabeled.GetLabels(), unlabeled.GetAnnotations())
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package renderer
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	kubeutil "github.com/argoproj/gitops-engine/pkg/utils/kube"
+)
+
+// clusterScopedKinds are the built-in Kubernetes kinds that are not namespaced
+var clusterScopedKinds = map[schema.GroupKind]bool{
+	{Group: "", Kind: "Namespace"}:                                                    true,
+	{Group: "", Kind: "Node"}:                                                         true,
+	{Group: "", Kind: "PersistentVolume"}:                                             true,
+	{Group: "", Kind: "ComponentStatus"}:                                              true,
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingWebhookConfiguration"}:     true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}:   true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicy"}:        true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicyBinding"}: true,
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}:                 true,
+	{Group: "apiregistration.k8s.io", Kind: "APIService"}:                             true,
+	{Group: "certificates.k8s.io", Kind: "CertificateSigningRequest"}:                 true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "FlowSchema"}:                       true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "PriorityLevelConfiguration"}:       true,
+	{Group: "networking.k8s.io", Kind: "IngressClass"}:                                true,
+	{Group: "node.k8s.io", Kind: "RuntimeClass"}:                                      true,
+	{Group: "policy", Kind: "PodSecurityPolicy"}:                                      true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:                         true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:                  true,
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}:                               true,
+	{Group: "storage.k8s.io", Kind: "CSIDriver"}:                                      true,
+	{Group: "storage.k8s.io", Kind: "CSINode"}:                                        true,
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:                                   true,
+	{Group: "storage.k8s.io", Kind: "VolumeAttachment"}:                               true,
+}
+
+// scopeInfoProvider is a kubeutil.ResourceInfoProvider that treats the built-in cluster-scoped
+// kinds and the cluster-scoped custom resources defined by the rendered CRDs as cluster-scoped,
+// and everything else as namespaced
+type scopeInfoProvider struct {
+	clusterScoped map[schema.GroupKind]bool
+}
+
+// newScopeInfoProvider returns a resource info provider that knows the scope of the given CRDs
+func newScopeInfoProvider(objects []*unstructured.Unstructured) *scopeInfoProvider {
+	clusterScoped := make(map[schema.GroupKind]bool, len(clusterScopedKinds))
+	for gk := range clusterScopedKinds {
+		clusterScoped[gk] = true
+	}
+
+	for _, obj := range objects {
+		if obj.GroupVersionKind().GroupKind() != (schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}) {
+			continue
+		}
+		scope, _, _ := unstructured.NestedString(obj.Object, "spec", "scope")
+		group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+		if scope == "Cluster" {
+			clusterScoped[schema.GroupKind{Group: group, Kind: kind}] = true
+		}
+	}
+
+	return &scopeInfoProvider{clusterScoped: clusterScoped}
+}
+
+func (r *scopeInfoProvider) IsNamespaced(gk schema.GroupKind) (bool, error) {
+	return !r.clusterScoped[gk], nil
+}
+
+// applyNamespaceOverride sets the namespace of every namespaced object. Cluster-scoped
+// objects are left untouched and a warning is returned for each of them.
+func applyNamespaceOverride(objects []*unstructured.Unstructured, ns string, infoProvider kubeutil.ResourceInfoProvider) []Warning {
+	var warnings []Warning
+	for _, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		if !kubeutil.IsNamespacedOrUnknown(infoProvider, gvk.GroupKind()) {
+			warnings = append(warnings, Warning{
+				Severity:    WarningSeverityWarning,
+				Message:     fmt.Sprintf("Not overriding namespace of cluster-scoped resource %s/%s", gvk.Kind, obj.GetName()),
+				SourceIndex: -1,
+				ResourceRef: objectRef(obj),
+			})
+			continue
+		}
+		obj.SetNamespace(ns)
+	}
+	return warnings
+}
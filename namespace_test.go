@@ -0,0 +1,45 @@
+package renderer
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestApplyNamespaceOverride(t *testing.T) {
+	crd := newObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "widgets.example.com")
+	_ = unstructured.SetNestedField(crd.Object, "example.com", "spec", "group")
+	_ = unstructured.SetNestedField(crd.Object, "Widget", "spec", "names", "kind")
+	_ = unstructured.SetNestedField(crd.Object, "Cluster", "spec", "scope")
+
+	objects := []*unstructured.Unstructured{
+		crd,
+		newObject("apps/v1", "Deployment", "default", "web"),
+		newObject("v1", "ConfigMap", "", "config"),
+		newObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "reader"),
+		newObject("example.com/v1", "Widget", "", "widget"),
+		newObject("example.com/v1", "Gadget", "default", "gadget"),
+	}
+
+	warnings := applyNamespaceOverride(objects, "preview", newScopeInfoProvider(objects))
+
+	expected := map[string]string{
+		"CustomResourceDefinition": "",
+		"Deployment":               "preview",
+		"ConfigMap":                "preview",
+		"ClusterRole":              "",
+		"Widget":                   "",
+		"Gadget":                   "preview",
+	}
+	for _, obj := range objects {
+		if obj.GetNamespace() != expected[obj.GetKind()] {
+			t.Errorf("Expected %s to have namespace %q, got %q", obj.GetKind(), expected[obj.GetKind()], obj.GetNamespace())
+		}
+	}
+
+	if len(warnings) != 3 {
+		t.Errorf("Expected a warning for each of the 3 cluster-scoped resources, got %v", warnings)
+	} else if warnings[1].ResourceRef != "rbac.authorization.k8s.io/v1/ClusterRole/reader" {
+		t.Errorf("Expected the reference of the ClusterRole, got %q", warnings[1].ResourceRef)
+	}
+}
@@ -0,0 +1,111 @@
+package renderer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// NamingStrategy names the files the manifests are written to by WriteManifests
+type NamingStrategy interface {
+	// FileName returns the path of the file of the object, relative to the output directory
+	FileName(obj *unstructured.Unstructured) string
+}
+
+// FlatNaming names files <kind>_<name>.yaml, all in the output directory
+type FlatNaming struct{}
+
+func (FlatNaming) FileName(obj *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s_%s.yaml", fileNamePart(strings.ToLower(obj.GetKind())), fileNamePart(obj.GetName()))
+}
+
+// WaveNaming prefixes the flat file name with the sync wave, e.g. 0002_deployment_web.yaml,
+// so listing the directory shows the files in the order Argo CD applies the waves. Negative
+// waves are written as a minus sign followed by 1000 plus the wave, e.g. -1 as -999, which
+// sorts them before wave 0 and in order.
+type WaveNaming struct{}
+
+func (WaveNaming) FileName(obj *unstructured.Unstructured) string {
+	wave := syncWave(obj)
+	prefix := fmt.Sprintf("%04d", wave)
+	if wave < 0 {
+		prefix = fmt.Sprintf("-%03d", 1000+wave)
+	}
+	return prefix + "_" + FlatNaming{}.FileName(obj)
+}
+
+// GVKNaming writes the files into a directory hierarchy of
+// <group>/<version>/<kind>/<namespace>/<name>.yaml. The core group is named core and
+// cluster-scoped objects have no namespace directory.
+type GVKNaming struct{}
+
+func (GVKNaming) FileName(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	group := gvk.Group
+	if group == "" {
+		group = "core"
+	}
+	parts := []string{fileNamePart(group), fileNamePart(gvk.Version), fileNamePart(gvk.Kind)}
+	if namespace := obj.GetNamespace(); namespace != "" {
+		parts = append(parts, fileNamePart(namespace))
+	}
+	parts = append(parts, fileNamePart(obj.GetName())+".yaml")
+	return filepath.Join(parts...)
+}
+
+// fileNamePart replaces the characters of a name that are not safe in file names, like the
+// colons of RBAC names or path separators, with underscores
+func fileNamePart(name string) string {
+	// An empty name or a name of only dots would not be a file of its own
+	if strings.Trim(name, ".") == "" {
+		return strings.Repeat("_", max(len(name), 1))
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// WriteManifests writes every object as YAML to the file the naming strategy returns,
+// creating the directories as needed. Objects with the same file name are written to the
+// same file as separate documents, in order.
+func WriteManifests(dir string, objects []*unstructured.Unstructured, naming NamingStrategy) error {
+	var order []string
+	documents := map[string][][]byte{}
+	for _, obj := range objects {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", objectRef(obj), err)
+		}
+		name := naming.FileName(obj)
+		if _, found := documents[name]; !found {
+			order = append(order, name)
+		}
+		documents[name] = append(documents[name], data)
+	}
+
+	for _, name := range order {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		var content []byte
+		for i, data := range documents[name] {
+			if i > 0 {
+				content = append(content, "---\n"...)
+			}
+			content = append(content, data...)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
+	return nil
+}
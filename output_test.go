@@ -0,0 +1,123 @@
+package renderer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestNamingStrategies(t *testing.T) {
+	withWave := func(obj *unstructured.Unstructured, wave string) *unstructured.Unstructured {
+		obj.SetAnnotations(map[string]string{syncWaveAnnotation: wave})
+		return obj
+	}
+
+	tests := []struct {
+		name string
+		obj  *unstructured.Unstructured
+		flat string
+		wave string
+		gvk  string
+	}{
+		{
+			name: "namespaced",
+			obj:  newObject("apps/v1", "Deployment", "default", "web"),
+			flat: "deployment_web.yaml",
+			wave: "0000_deployment_web.yaml",
+			gvk:  "apps/v1/Deployment/default/web.yaml",
+		},
+		{
+			name: "core group",
+			obj:  withWave(newObject("v1", "ConfigMap", "team", "settings"), "12"),
+			flat: "configmap_settings.yaml",
+			wave: "0012_configmap_settings.yaml",
+			gvk:  "core/v1/ConfigMap/team/settings.yaml",
+		},
+		{
+			name: "cluster-scoped",
+			obj:  newObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "reader"),
+			flat: "clusterrole_reader.yaml",
+			wave: "0000_clusterrole_reader.yaml",
+			gvk:  "rbac.authorization.k8s.io/v1/ClusterRole/reader.yaml",
+		},
+		{
+			name: "special characters",
+			obj:  withWave(newObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "system:aggregate-to-view"), "-1"),
+			flat: "clusterrole_system_aggregate-to-view.yaml",
+			wave: "-999_clusterrole_system_aggregate-to-view.yaml",
+			gvk:  "rbac.authorization.k8s.io/v1/ClusterRole/system_aggregate-to-view.yaml",
+		},
+		{
+			name: "dot names",
+			obj:  newObject("v1", "Namespace", "", ".."),
+			flat: "namespace___.yaml",
+			wave: "0000_namespace___.yaml",
+			gvk:  "core/v1/Namespace/__.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (FlatNaming{}).FileName(tt.obj); got != tt.flat {
+				t.Errorf("FlatNaming: expected %s, got %s", tt.flat, got)
+			}
+			if got := (WaveNaming{}).FileName(tt.obj); got != tt.wave {
+				t.Errorf("WaveNaming: expected %s, got %s", tt.wave, got)
+			}
+			if got := (GVKNaming{}).FileName(tt.obj); got != filepath.FromSlash(tt.gvk) {
+				t.Errorf("GVKNaming: expected %s, got %s", tt.gvk, got)
+			}
+		})
+	}
+}
+
+func TestWaveNamingOrder(t *testing.T) {
+	var names []string
+	for _, wave := range []string{"-10", "-2", "-1", "0", "1", "10"} {
+		obj := newObject("v1", "ConfigMap", "default", "settings")
+		obj.SetAnnotations(map[string]string{syncWaveAnnotation: wave})
+		names = append(names, WaveNaming{}.FileName(obj))
+	}
+	for i := 1; i < len(names); i++ {
+		if names[i-1] >= names[i] {
+			t.Errorf("Expected %s to sort before %s", names[i-1], names[i])
+		}
+	}
+}
+
+func TestWriteManifests(t *testing.T) {
+	dir := t.TempDir()
+	objects := []*unstructured.Unstructured{
+		newObject("apps/v1", "Deployment", "default", "web"),
+		newObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "reader"),
+		newObject("apps/v1", "Deployment", "staging", "web"),
+	}
+
+	if err := WriteManifests(dir, objects, GVKNaming{}); err != nil {
+		t.Fatalf("WriteManifests failed: %v", err)
+	}
+	for _, path := range []string{"apps/v1/Deployment/default/web.yaml", "apps/v1/Deployment/staging/web.yaml", "rbac.authorization.k8s.io/v1/ClusterRole/reader.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("Expected %s to be written: %v", path, err)
+		}
+	}
+
+	// The flat names of both Deployments are the same, so they share a file
+	if err := WriteManifests(dir, objects, FlatNaming{}); err != nil {
+		t.Fatalf("WriteManifests failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "deployment_web.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	documents, err := decodeObjects(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to parse manifests: %v", err)
+	}
+	if len(documents) != 2 {
+		t.Errorf("Expected both Deployments in one file, got:\n%s", data)
+	}
+}
@@ -0,0 +1,122 @@
+package renderer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+)
+
+// pluginBinaryPrefix is prepended to the plugin name to find the plugin in PATH
+const pluginBinaryPrefix = "argocd-cmp-"
+
+// invalidEnvCharacters are replaced in parameter names to build PARAM_ environment variables
+var invalidEnvCharacters = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// runPlugin runs the config management plugin of the source as argocd-cmp-<name> from PATH
+// in the source path. It sets the environment variables Argo CD passes to plugins and
+// returns the manifests written to stdout and each line written to stderr as a warning.
+func runPlugin(ctx context.Context, q *apiclient.ManifestRequest, appPath string) ([]string, []string, error) {
+	plugin := q.ApplicationSource.Plugin
+	if plugin == nil || plugin.Name == "" {
+		return nil, nil, fmt.Errorf("plugin sources require a plugin name")
+	}
+
+	binary, err := exec.LookPath(pluginBinaryPrefix + plugin.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("plugin %s not found: %w", plugin.Name, err)
+	}
+
+	env, err := pluginEnv(q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary)
+	cmd.Dir = appPath
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, nil, fmt.Errorf("plugin %s failed: %w\nOutput: %s", plugin.Name, err, stderr.String())
+	}
+
+	objects, err := decodeObjects(&stdout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse output of plugin %s: %w", plugin.Name, err)
+	}
+
+	// Argo CD adds the tracking label to the plugin output like to every other source
+	if q.AppLabelKey != "" && q.AppName != "" {
+		for _, obj := range objects {
+			metadata, _ := obj["metadata"].(map[string]interface{})
+			if metadata == nil {
+				metadata = map[string]interface{}{}
+				obj["metadata"] = metadata
+			}
+			labels, _ := metadata["labels"].(map[string]interface{})
+			if labels == nil {
+				labels = map[string]interface{}{}
+				metadata["labels"] = labels
+			}
+			labels[q.AppLabelKey] = q.AppName
+		}
+	}
+
+	manifests, err := marshalObjects(objects)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []string
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			warnings = append(warnings, fmt.Sprintf("plugin %s: %s", plugin.Name, line))
+		}
+	}
+
+	return manifests, warnings, nil
+}
+
+// pluginEnv returns the environment variables Argo CD sets for config management plugins
+func pluginEnv(q *apiclient.ManifestRequest) ([]string, error) {
+	source := q.ApplicationSource
+	env := []string{
+		"ARGOCD_APP_NAME=" + q.AppName,
+		"ARGOCD_APP_NAMESPACE=" + q.Namespace,
+		"ARGOCD_APP_PROJECT_NAME=" + q.ProjectName,
+		"ARGOCD_APP_REVISION=" + q.Revision,
+		"ARGOCD_APP_SOURCE_REPO_URL=" + source.RepoURL,
+		"ARGOCD_APP_SOURCE_PATH=" + source.Path,
+		"ARGOCD_APP_SOURCE_TARGET_REVISION=" + source.TargetRevision,
+		"KUBE_VERSION=" + q.KubeVersion,
+	}
+
+	for _, envEntry := range source.Plugin.Env {
+		env = append(env, "ARGOCD_ENV_"+envEntry.Name+"="+envEntry.Value)
+	}
+
+	if len(source.Plugin.Parameters) > 0 {
+		parameters, err := json.Marshal(source.Plugin.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal plugin parameters: %w", err)
+		}
+		env = append(env, "ARGOCD_APP_PARAMETERS="+string(parameters))
+	}
+	for _, param := range source.Plugin.Parameters {
+		if param.String_ == nil {
+			continue
+		}
+		name := strings.ToUpper(invalidEnvCharacters.ReplaceAllString(param.Name, "_"))
+		env = append(env, "PARAM_"+name+"="+*param.String_)
+	}
+
+	return env, nil
+}
@@ -0,0 +1,116 @@
+package renderer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// fakePluginScript prints a ConfigMap with the environment it was called with
+const fakePluginScript = `#!/bin/sh
+echo "generating in $(basename "$PWD")" >&2
+cat <<MANIFEST
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: $ARGOCD_APP_NAME-config
+data:
+  namespace: "$ARGOCD_APP_NAMESPACE"
+  environment: "$ARGOCD_ENV_ENVIRONMENT"
+  replicas: "$PARAM_REPLICA_COUNT"
+MANIFEST
+`
+
+func TestTemplateFromApplicationPlugin(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required for the fake plugin")
+	}
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "argocd-cmp-fake"), []byte(fakePluginScript), 0755); err != nil {
+		t.Fatalf("Failed to write fake plugin: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+    plugin:
+      name: fake
+      env:
+      - name: ENVIRONMENT
+        value: production
+      parameters:
+      - name: replica-count
+        string: "3"
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: guestbook
+`)
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	if len(result.Objects) != 1 {
+		t.Fatalf("Expected 1 object, got %d", len(result.Objects))
+	}
+	obj := result.Objects[0]
+	if obj.GetName() != "guestbook-config" {
+		t.Errorf("Expected ARGOCD_APP_NAME to be set, got name %s", obj.GetName())
+	}
+	data := obj.Object["data"].(map[string]interface{})
+	expected := map[string]interface{}{"namespace": "guestbook", "environment": "production", "replicas": "3"}
+	for key, value := range expected {
+		if data[key] != value {
+			t.Errorf("Expected data.%s to be %v, got %v", key, value, data[key])
+		}
+	}
+	if obj.GetLabels()["app.kubernetes.io/instance"] != "guestbook" {
+		t.Errorf("Expected the tracking label, got %v", obj.GetLabels())
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Message != "plugin fake: generating in input" {
+		t.Errorf("Expected stderr as a warning, got %v", result.Warnings)
+	}
+}
+
+func TestTemplateFromApplicationPluginNotFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+    plugin:
+      name: missing
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: guestbook
+`)
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+	})
+	renderErr, ok := AsRenderError(err)
+	if !ok || renderErr.Phase != RenderPhaseRender || renderErr.SourceIndex != 0 {
+		t.Errorf("Expected a render error for source 0, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package renderer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+// ReadAppProject reads an AppProject from a YAML file
+func ReadAppProject(path string) (*v1alpha1.AppProject, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project file: %w", err)
+	}
+
+	var project v1alpha1.AppProject
+	if err := yaml.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse project file %s: %w", path, err)
+	}
+	if project.Kind != "AppProject" {
+		return nil, fmt.Errorf("expected kind 'AppProject' in %s, got '%s'", path, project.Kind)
+	}
+	return &project, nil
+}
+
+// DefaultControllerNamespace is the namespace Argo CD is installed in by default
+const DefaultControllerNamespace = "argocd"
+
+// ValidateSourceRepos checks the namespace, the repositories and the destination of the
+// Application against the sourceNamespaces, sourceRepos and destinations the project permits,
+// like Argo CD does before syncing. Applications in controllerNamespace, the namespace Argo CD
+// is installed in, are permitted in every project. It is DefaultControllerNamespace if empty.
+// All of them are checked and the violations are returned together.
+func ValidateSourceRepos(app *v1alpha1.Application, project *v1alpha1.AppProject, controllerNamespace string) error {
+	if controllerNamespace == "" {
+		controllerNamespace = DefaultControllerNamespace
+	}
+	var errs []error
+	if !project.IsAppNamespacePermitted(app, controllerNamespace) {
+		errs = append(errs, fmt.Errorf("application namespace %s is not permitted in project %s", app.Namespace, project.Name))
+	}
+	for i, source := range app.Spec.GetSources() {
+		if !project.IsSourcePermitted(source) {
+			errs = append(errs, fmt.Errorf("source[%d] repository %s is not permitted in project %s", i, source.RepoURL, project.Name))
+		}
+	}
+
+	destination := app.Spec.Destination
+	cluster := &v1alpha1.Cluster{Server: destination.Server, Name: destination.Name}
+	permitted, err := project.IsDestinationPermitted(cluster, destination.Namespace, func(string) ([]*v1alpha1.Cluster, error) {
+		return nil, errors.New("project scoped clusters are not available when rendering locally")
+	})
+	if err != nil {
+		errs = append(errs, err)
+	} else if !permitted {
+		server := destination.Server
+		if server == "" {
+			server = destination.Name
+		}
+		errs = append(errs, fmt.Errorf("destination %s namespace %s is not permitted in project %s", server, destination.Namespace, project.Name))
+	}
+
+	return errors.Join(errs...)
+}
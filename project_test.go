@@ -0,0 +1,211 @@
+package renderer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+func newProjectApplication(namespace string, repoURLs ...string) *v1alpha1.Application {
+	app := &v1alpha1.Application{}
+	for _, repoURL := range repoURLs {
+		app.Spec.Sources = append(app.Spec.Sources, v1alpha1.ApplicationSource{RepoURL: repoURL, Path: "."})
+	}
+	app.Spec.Destination = v1alpha1.ApplicationDestination{Server: "https://kubernetes.default.svc", Namespace: namespace}
+	return app
+}
+
+func newProject(sourceRepos ...string) *v1alpha1.AppProject {
+	project := &v1alpha1.AppProject{}
+	project.Name = "team"
+	project.Spec.SourceRepos = sourceRepos
+	project.Spec.Destinations = []v1alpha1.ApplicationDestination{{Server: "https://kubernetes.default.svc", Namespace: "team-*"}}
+	return project
+}
+
+func TestValidateSourceRepos(t *testing.T) {
+	tests := []struct {
+		name        string
+		app         *v1alpha1.Application
+		project     *v1alpha1.AppProject
+		expectedErr string
+	}{
+		{
+			name:    "exact match",
+			app:     newProjectApplication("team-a", "https://github.com/myorg/myrepo.git"),
+			project: newProject("https://github.com/myorg/myrepo"),
+		},
+		{
+			name:    "glob match",
+			app:     newProjectApplication("team-a", "https://github.com/myorg/myrepo", "https://github.com/otherorg/charts"),
+			project: newProject("https://github.com/*/*"),
+		},
+		{
+			name:    "wildcard",
+			app:     newProjectApplication("team-a", "https://gitlab.example.com/group/repo"),
+			project: newProject("*"),
+		},
+		{
+			name:        "rejected repository",
+			app:         newProjectApplication("team-a", "https://github.com/myorg/myrepo", "https://github.com/evil/repo"),
+			project:     newProject("https://github.com/myorg/*"),
+			expectedErr: "source[1] repository https://github.com/evil/repo is not permitted in project team",
+		},
+		{
+			name:        "denied repository",
+			app:         newProjectApplication("team-a", "https://github.com/myorg/secret"),
+			project:     newProject("https://github.com/myorg/*", "!https://github.com/myorg/secret"),
+			expectedErr: "source[0] repository https://github.com/myorg/secret is not permitted",
+		},
+		{
+			name: "permitted application namespace",
+			app: func() *v1alpha1.Application {
+				app := newProjectApplication("team-a", "https://github.com/myorg/myrepo")
+				app.Namespace = "team-apps"
+				return app
+			}(),
+			project: func() *v1alpha1.AppProject {
+				project := newProject("*")
+				project.Spec.SourceNamespaces = []string{"team-*"}
+				return project
+			}(),
+		},
+		{
+			name: "rejected application namespace",
+			app: func() *v1alpha1.Application {
+				app := newProjectApplication("team-a", "https://github.com/myorg/myrepo")
+				app.Namespace = "other-apps"
+				return app
+			}(),
+			project:     newProject("*"),
+			expectedErr: "application namespace other-apps is not permitted in project team",
+		},
+		{
+			name:        "rejected namespace",
+			app:         newProjectApplication("kube-system", "https://github.com/myorg/myrepo"),
+			project:     newProject("*"),
+			expectedErr: "destination https://kubernetes.default.svc namespace kube-system is not permitted in project team",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSourceRepos(tt.app, tt.project, "")
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Expected the application to be permitted, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected error %q, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestTemplateFromApplicationProject(t *testing.T) {
+	projectFile := filepath.Join(t.TempDir(), "project.yaml")
+	project := `apiVersion: argoproj.io/v1alpha1
+kind: AppProject
+metadata:
+  name: default
+spec:
+  sourceRepos:
+  - https://github.com/otherorg/*
+  destinations:
+  - server: '*'
+    namespace: '*'
+`
+	if err := os.WriteFile(projectFile, []byte(project), 0644); err != nil {
+		t.Fatalf("Failed to write project: %v", err)
+	}
+	appProject, err := ReadAppProject(projectFile)
+	if err != nil {
+		t.Fatalf("ReadAppProject failed: %v", err)
+	}
+
+	_, err = TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/directory/app.yaml",
+		RepoRoot:        ".",
+		Project:         appProject,
+	})
+	renderErr, ok := AsRenderError(err)
+	if !ok || renderErr.Phase != RenderPhaseParse || !strings.Contains(err.Error(), "is not permitted in project default") {
+		t.Errorf("Expected the repository to be rejected, got %v", err)
+	}
+}
+
+func TestTemplateFromApplicationSourceNamespace(t *testing.T) {
+	appFile := writeApplication(t, `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: child-app
+  namespace: argocd
+spec:
+  project: team
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: team-a
+`)
+	project := newProject("*")
+	project.Spec.SourceNamespaces = []string{"production"}
+
+	// Applications in the Argo CD namespace are permitted in every project
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		Project:         project,
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	_, err = TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		Project:         project,
+		SourceNamespace: "production",
+	})
+	if err != nil {
+		t.Errorf("Expected the source namespace to be permitted, got %v", err)
+	}
+
+	_, err = TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		Project:         project,
+		SourceNamespace: "staging",
+	})
+	if err == nil || !strings.Contains(err.Error(), "application namespace staging is not permitted in project team") {
+		t.Errorf("Expected the source namespace to be rejected, got %v", err)
+	}
+
+	// Argo CD installed in another namespace only permits the Applications in that namespace
+	_, err = TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile:     appFile,
+		RepoRoot:            ".",
+		Project:             project,
+		ControllerNamespace: "gitops",
+	})
+	if err == nil || !strings.Contains(err.Error(), "application namespace argocd is not permitted in project team") {
+		t.Errorf("Expected the argocd namespace to be rejected, got %v", err)
+	}
+	_, err = TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile:     appFile,
+		RepoRoot:            ".",
+		Project:             project,
+		SourceNamespace:     "gitops",
+		ControllerNamespace: "gitops",
+	})
+	if err != nil {
+		t.Errorf("Expected the controller namespace to be permitted, got %v", err)
+	}
+}
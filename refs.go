@@ -0,0 +1,80 @@
+package renderer
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/util/git"
+	utilio "github.com/argoproj/argo-cd/v3/util/io"
+)
+
+// refSources returns the sources other sources can reference with $ref, keyed like Argo CD
+// expects them in ManifestRequest.RefSources
+func refSources(sources v1alpha1.ApplicationSources) (map[string]*v1alpha1.RefTarget, error) {
+	refs := map[string]*v1alpha1.RefTarget{}
+	for i, source := range sources {
+		if source.Ref == "" {
+			continue
+		}
+		// Argo CD only resolves references to git repositories
+		if source.Chart != "" {
+			return nil, &RenderError{SourceIndex: i, Phase: RenderPhaseParse, Err: fmt.Errorf("source[%d] with ref %s is a Helm chart, only git sources can be referenced", i, source.Ref)}
+		}
+		refs["$"+source.Ref] = &v1alpha1.RefTarget{
+			Repo:           v1alpha1.Repository{Repo: source.RepoURL},
+			TargetRevision: source.TargetRevision,
+		}
+	}
+	return refs, nil
+}
+
+// isRefOnlySource returns true if the source only provides files to other sources, which
+// Argo CD does not render
+func isRefOnlySource(source v1alpha1.ApplicationSource) bool {
+	return source.Ref != "" && source.Path == "" && source.Chart == ""
+}
+
+// refRepoPaths returns the local directories the referenced repositories are checked out in.
+// Sources are rendered from the local repository, so every reference resolves to the
+// repository root unless SourceRefs points it to another directory.
+func refRepoPaths(refs map[string]*v1alpha1.RefTarget, repoRoot string, sourceRefs map[string]string) (utilio.TempPaths, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	// Argo CD looks the directories up by repository, so refs to the same repository must
+	// resolve to the same directory. The refs are sorted to report conflicts deterministically.
+	refVars := slices.Sorted(maps.Keys(refs))
+	paths := utilio.NewRandomizedTempPaths("")
+	repoRefs := map[string]string{}
+	repoDirs := map[string]string{}
+	for _, refVar := range refVars {
+		absDir, err := filepath.Abs(refDir(refVar, repoRoot, sourceRefs))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the directory of ref %s: %w", refVar[1:], err)
+		}
+		repo := git.NormalizeGitURL(refs[refVar].Repo.Repo)
+		if dir, found := repoDirs[repo]; found {
+			if dir != absDir {
+				return nil, fmt.Errorf("refs %s and %s reference the same repository %s, but resolve to the different directories %s and %s", repoRefs[repo][1:], refVar[1:], refs[refVar].Repo.Repo, dir, absDir)
+			}
+			continue
+		}
+		repoRefs[repo] = refVar
+		repoDirs[repo] = absDir
+		paths.Add(repo, absDir)
+	}
+	return paths, nil
+}
+
+// refDir returns the local directory of the source referenced with refVar, like $values
+func refDir(refVar, repoRoot string, sourceRefs map[string]string) string {
+	if local, found := sourceRefs[strings.TrimPrefix(refVar, "$")]; found {
+		return local
+	}
+	return repoRoot
+}
@@ -0,0 +1,101 @@
+package renderer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+func TestTemplateFromApplicationRefSource(t *testing.T) {
+	logFile := installFakeHelm(t)
+
+	repoRoot, otherRoot := t.TempDir(), t.TempDir()
+	for path, content := range map[string]string{
+		filepath.Join(repoRoot, "charts/app/Chart.yaml"):    "apiVersion: v2\nname: app\nversion: 0.1.0\n",
+		filepath.Join(repoRoot, "config/prod/values.yaml"):  "replicaCount: 3\n",
+		filepath.Join(otherRoot, "config/prod/values.yaml"): "replicaCount: 5\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+spec:
+  project: default
+  sources:
+  - repoURL: https://github.com/myorg/charts
+    path: charts/app
+    helm:
+      valueFiles:
+      - $values/config/prod/values.yaml
+  - repoURL: https://github.com/myorg/config
+    ref: values
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`)
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        repoRoot,
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if result.SourcesProcessed != 1 {
+		t.Errorf("Expected the ref-only source not to be rendered, got %d sources", result.SourcesProcessed)
+	}
+
+	calls := strings.Join(readHelmLog(t, logFile), "\n")
+	if !strings.Contains(calls, "--values "+filepath.Join(repoRoot, "config/prod/values.yaml")) {
+		t.Errorf("Expected the value file of the referenced source, got:\n%s", calls)
+	}
+
+	// The referenced source can be checked out in another directory
+	if err := os.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to reset the fake helm log: %v", err)
+	}
+
+	if _, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        repoRoot,
+		SourceRefs:      map[string]string{"values": otherRoot},
+	}); err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	calls = strings.Join(readHelmLog(t, logFile), "\n")
+	if !strings.Contains(calls, "--values "+filepath.Join(otherRoot, "config/prod/values.yaml")) {
+		t.Errorf("Expected the value file of the local checkout, got:\n%s", calls)
+	}
+}
+
+func TestRefRepoPathsConflictingDirectories(t *testing.T) {
+	repoRoot, otherRoot := t.TempDir(), t.TempDir()
+	refs := map[string]*v1alpha1.RefTarget{
+		"$values":  {Repo: v1alpha1.Repository{Repo: "https://github.com/myorg/config.git"}},
+		"$secrets": {Repo: v1alpha1.Repository{Repo: "https://github.com/myorg/config"}},
+	}
+
+	// Both refs resolve to the repository root
+	if _, err := refRepoPaths(refs, repoRoot, nil); err != nil {
+		t.Fatalf("Expected refs to the same directory to be accepted, got %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		_, err := refRepoPaths(refs, repoRoot, map[string]string{"values": otherRoot})
+		if err == nil || !strings.Contains(err.Error(), "refs secrets and values reference the same repository") {
+			t.Fatalf("Expected an error for refs to different directories, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package renderer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// HelmRegistryAuth contains the credentials of an OCI registry Helm charts are pulled from
+type HelmRegistryAuth struct {
+	// Registry is the registry host the credentials are used for, optionally followed by a
+	// path to limit them to the repositories below it, e.g. "ghcr.io/myorg"
+	Registry string
+	Username string
+	Password string
+	// CACert is the path of a CA certificate used to verify the registry
+	CACert string
+}
+
+// registryConfigEnv points to the Docker config file Helm reads registry credentials from
+const registryConfigEnv = "HELM_REGISTRY_CONFIG"
+
+// findRegistryCredentials returns the credentials for the OCI repository. The auth with the
+// longest matching registry wins, followed by the HELM_OCI_USERNAME and HELM_OCI_PASSWORD
+// environment variables and the HELM_REGISTRY_CONFIG file. It returns nil if none match.
+func findRegistryCredentials(repoURL string, auths []HelmRegistryAuth) (*HelmRegistryAuth, error) {
+	location := strings.TrimSuffix(strings.TrimPrefix(repoURL, "oci://"), "/")
+
+	var match *HelmRegistryAuth
+	for i, auth := range auths {
+		registry := strings.TrimSuffix(strings.TrimPrefix(auth.Registry, "oci://"), "/")
+		if registry == "" || (location != registry && !strings.HasPrefix(location, registry+"/")) {
+			continue
+		}
+		if match == nil || len(registry) > len(strings.TrimPrefix(match.Registry, "oci://")) {
+			match = &auths[i]
+		}
+	}
+	if match != nil {
+		return match, nil
+	}
+
+	registry := ociRegistry(repoURL)
+	username, password := os.Getenv("HELM_OCI_USERNAME"), os.Getenv("HELM_OCI_PASSWORD")
+	if username != "" && password != "" {
+		return &HelmRegistryAuth{Registry: registry, Username: username, Password: password}, nil
+	}
+
+	configFile := os.Getenv(registryConfigEnv)
+	if configFile == "" {
+		return nil, nil
+	}
+	return readRegistryConfig(configFile, registry)
+}
+
+// readRegistryConfig returns the credentials of the registry from a Docker config file,
+// or nil if the file has none
+func readRegistryConfig(path, registry string) (*HelmRegistryAuth, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", registryConfigEnv, err)
+	}
+
+	var config struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", registryConfigEnv, err)
+	}
+
+	entry, found := config.Auths[registry]
+	if !found {
+		return nil, nil
+	}
+	username, password := entry.Username, entry.Password
+	if entry.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth of %s in %s: %w", registry, registryConfigEnv, err)
+		}
+		username, password, _ = strings.Cut(string(decoded), ":")
+	}
+	if username == "" || password == "" {
+		return nil, nil
+	}
+	return &HelmRegistryAuth{Registry: registry, Username: username, Password: password}, nil
+}
@@ -0,0 +1,98 @@
+package renderer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// registryConfigArg returns the value of the --registry-config argument of a logged helm invocation
+func registryConfigArg(t *testing.T, call string) string {
+	t.Helper()
+	_, after, found := strings.Cut(call, "--registry-config ")
+	if !found {
+		t.Fatalf("Expected --registry-config in %s", call)
+	}
+	registryConfig, _, _ := strings.Cut(after, " ")
+	if registryConfig == os.Getenv("HELM_REGISTRY_CONFIG") {
+		t.Errorf("Expected a temporary registry config, got the one of the user %s", registryConfig)
+	}
+	return registryConfig
+}
+
+func TestDownloadHelmChartRegistryAuth(t *testing.T) {
+	logFile := installFakeHelm(t)
+	t.Setenv("HELM_OCI_USERNAME", "")
+	t.Setenv("HELM_OCI_PASSWORD", "")
+
+	auths := []HelmRegistryAuth{
+		{Registry: "ghcr.io", Username: "everyone", Password: "wrong"},
+		{Registry: "oci://ghcr.io/myorg", Username: "robot", Password: "s3cret", CACert: "/etc/ca.pem"},
+		{Registry: "ghcr.io/myorganization", Username: "other", Password: "wrong"},
+	}
+	if _, err := downloadHelmChart(context.Background(), t.TempDir(), "oci://ghcr.io/myorg/charts", "nginx", "1.0.0", DefaultCacheTTL, false, auths, "helm", 0, nil); err != nil {
+		t.Fatalf("downloadHelmChart failed: %v", err)
+	}
+
+	calls := readHelmLog(t, logFile)
+	if len(calls) != 2 {
+		t.Fatalf("Expected a login and a pull without logout, got %v", calls)
+	}
+	registryConfig := registryConfigArg(t, calls[0])
+	if calls[0] != "registry login ghcr.io --registry-config "+registryConfig+" --username robot --password-stdin --ca-file /etc/ca.pem" {
+		t.Errorf("Unexpected login invocation: %s", calls[0])
+	}
+	if !strings.HasPrefix(calls[1], "pull oci://ghcr.io/myorg/charts/nginx ") || !strings.HasSuffix(calls[1], " --registry-config "+registryConfig+" --ca-file /etc/ca.pem") {
+		t.Errorf("Unexpected pull invocation: %s", calls[1])
+	}
+	// The temporary registry config is removed with the credentials after the pull
+	if _, err := os.Stat(registryConfig); !os.IsNotExist(err) {
+		t.Errorf("Expected the temporary registry config %s to be removed, got %v", registryConfig, err)
+	}
+	if strings.Contains(strings.Join(calls, "\n"), "s3cret") {
+		t.Error("Password must not be passed as an argument")
+	}
+}
+
+func TestDownloadHelmChartRegistryConfig(t *testing.T) {
+	logFile := installFakeHelm(t)
+	t.Setenv("HELM_OCI_USERNAME", "")
+	t.Setenv("HELM_OCI_PASSWORD", "")
+
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	// "cm9ib3Q6czNjcmV0" is robot:s3cret
+	config := `{"auths": {"registry.example.com": {"auth": "cm9ib3Q6czNjcmV0"}}}`
+	if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatalf("Failed to write registry config: %v", err)
+	}
+	t.Setenv("HELM_REGISTRY_CONFIG", configFile)
+
+	if _, err := downloadHelmChart(context.Background(), t.TempDir(), "oci://registry.example.com/charts", "nginx", "1.0.0", DefaultCacheTTL, false, nil, "helm", 0, nil); err != nil {
+		t.Fatalf("downloadHelmChart failed: %v", err)
+	}
+
+	calls := readHelmLog(t, logFile)
+	if len(calls) != 2 {
+		t.Fatalf("Expected a login and a pull without logout, got %v", calls)
+	}
+	// The registry config of the user is only read, never logged in to or out of
+	if calls[0] != "registry login registry.example.com --registry-config "+registryConfigArg(t, calls[0])+" --username robot --password-stdin" {
+		t.Errorf("Unexpected login invocation: %s", calls[0])
+	}
+}
+
+func TestFindRegistryCredentialsWithoutMatch(t *testing.T) {
+	t.Setenv("HELM_OCI_USERNAME", "")
+	t.Setenv("HELM_OCI_PASSWORD", "")
+	t.Setenv("HELM_REGISTRY_CONFIG", filepath.Join(t.TempDir(), "missing.json"))
+
+	credentials, err := findRegistryCredentials("oci://docker.io/charts", []HelmRegistryAuth{{Registry: "ghcr.io", Username: "u", Password: "p"}})
+	if err != nil {
+		t.Fatalf("findRegistryCredentials failed: %v", err)
+	}
+	if credentials != nil {
+		t.Errorf("Expected no credentials, got %+v", credentials)
+	}
+}
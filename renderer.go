@@ -1,20 +1,22 @@
 package renderer
 
 import (
+	"bytes"
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
 
+	"golang.org/x/sync/errgroup"
 	"sigs.k8s.io/yaml"
 
 	"github.com/argoproj/argo-cd/v3/controller"
@@ -27,149 +29,765 @@ import (
 // TemplateOptions contains options for the templating process
 type TemplateOptions struct {
 	ApplicationFile string
-	RepoRoot        string
+	// ApplicationURL is fetched when ApplicationFile is empty, e.g. an Application from the
+	// Kubernetes API. It must return the Application as YAML or JSON.
+	ApplicationURL string
+	// ApplicationURLTimeout limits how long fetching ApplicationURL may take. Zero uses
+	// DefaultApplicationURLTimeout.
+	ApplicationURLTimeout time.Duration
+	// ClusterApp is read from the cluster when ApplicationFile and ApplicationURL are empty
+	ClusterApp ClusterAppRef
+	// RepoRoot is the repository root the paths of the sources are relative to. If empty, it
+	// is searched for upwards from ApplicationFile like FindRepoRoot with the default marker,
+	// and the working directory is used for Applications that are not read from a file.
+	RepoRoot string
+	// MaxManifestSize is the maximum combined size of the manifests rendered from all sources
+	// as a Kubernetes quantity, e.g. "10Mi". Empty uses DefaultMaxManifestSize.
 	MaxManifestSize string
+	// MaxManifestCount is the maximum number of manifests rendered from all sources, zero for unlimited
+	MaxManifestCount int
+	Helm             HelmOptions
+	Kustomize        KustomizeOptions
+	Directory        DirectoryOptions
+	// CacheTTL is how long downloaded Helm charts are reused. Zero uses DefaultCacheTTL,
+	// a negative value downloads the chart on every render.
+	CacheTTL time.Duration
+	// HelmPullRetries is how often a helm pull that failed with a network error is retried,
+	// waiting 1s, 2s, 4s and so on in between. Zero uses DefaultHelmPullRetries, a negative
+	// value does not retry.
+	HelmPullRetries int
+	// VerifyCache re-downloads cached Helm charts whose content changed since they were downloaded
+	VerifyCache bool
+	// CacheDir overrides the directory downloaded Helm charts are stored in
+	CacheDir string
+	// HelmRegistryAuths are the credentials used to pull Helm charts from OCI registries
+	HelmRegistryAuths []HelmRegistryAuth
+	// Timeout limits how long rendering the Application may take, including downloading Helm
+	// charts. Zero means no limit.
+	Timeout time.Duration
+	// Concurrency is the number of sources rendered in parallel. Zero or one renders
+	// the sources sequentially, -1 renders all sources at once.
+	Concurrency int
+	// Validate validates the rendered manifests against the JSON schemas in SchemaDir
+	Validate  bool
+	SchemaDir string
+	// KubeconformValidate validates the rendered manifests with kubeconform using KubeconformOptions
+	KubeconformValidate bool
+	KubeconformOptions  KubeconformOptions
+	// KubeVersion is the Kubernetes version passed to Helm and Kustomize, e.g. "1.29"
+	KubeVersion string
+	// NamespaceOverride sets the namespace of every namespaced resource
+	NamespaceOverride string
+	// InjectLabels are added to every rendered resource
+	InjectLabels map[string]string
+	// InjectAnnotations are added to every rendered resource
+	InjectAnnotations map[string]string
+	// InjectOverwrite replaces injected labels and annotations already set on a resource
+	InjectOverwrite bool
+	// StripManagedFields removes metadata.managedFields from every rendered resource
+	StripManagedFields bool
+	// StripClusterFields removes the resourceVersion, uid, generation, creationTimestamp
+	// and status fields set by the cluster from every rendered resource
+	StripClusterFields bool
+	// SortManifests sorts the rendered objects in the order Helm installs them
+	SortManifests bool
+	// SortBySyncWave sorts the rendered objects by sync wave, and in the order Helm installs
+	// them within a wave. It takes precedence over SortManifests.
+	SortBySyncWave bool
+	// SortOrder set to SortOrderGVK sorts the rendered objects by group, version, kind,
+	// namespace and name. It takes precedence over SortBySyncWave and SortManifests.
+	SortOrder string
+	// Selector is a Kubernetes label selector the rendered resources are filtered by
+	Selector string
+	// IncludeKinds limits the rendered resources to these kinds, all kinds are included when empty
+	IncludeKinds []string
+	// ExcludeKinds removes resources of these kinds from the rendered resources
+	ExcludeKinds []string
+	// ExcludeHookResources removes the resources with an argocd.argoproj.io/hook annotation
+	ExcludeHookResources bool
+	// HooksOnly limits the rendered resources to those with an argocd.argoproj.io/hook
+	// annotation. It cannot be combined with ExcludeHookResources.
+	HooksOnly bool
+	// IncludeAnnotations limits the rendered resources to those having all of these annotations
+	IncludeAnnotations map[string]string
+	// ExcludeAnnotations removes resources having any of these annotations from the rendered resources
+	ExcludeAnnotations map[string]string
+	// Verbose writes the detected type, path and equivalent command of every source to VerboseOutput
+	Verbose bool
+	// VerboseOutput receives the verbose output, os.Stderr if nil
+	VerboseOutput io.Writer
+	// Logger receives the events of the templating process, like the detected type of every
+	// source. If nil and Verbose is set, a StderrLogger writing to VerboseOutput is used.
+	Logger Logger
+	// ApplyIgnoreDifferences removes the fields listed in the ignoreDifferences of the
+	// Application from the rendered resources
+	ApplyIgnoreDifferences bool
+	// WarningsAsErrors fails with a WarningsAsErrorsError if any warning remains after
+	// removing the ignored ones
+	WarningsAsErrors bool
+	// IgnoreWarnings removes the warnings containing any of these substrings
+	IgnoreWarnings []string
+	// SourceRefs are the local directories of the sources referenced with $ref in the value
+	// files of Helm sources, keyed by ref name. References default to the repository root.
+	SourceRefs map[string]string
+	// ValueSecrets are Secrets whose keys contain Helm values, merged over the value files and
+	// under the inline values of every Helm source, like value files appended to those of the
+	// source. Reading them requires access to the cluster.
+	ValueSecrets []SecretRef
+	// ValueConfigMaps are ConfigMaps whose keys contain Helm values, merged under the value
+	// files and inline values of every Helm source so the values of the Application take
+	// precedence. Reading them requires access to the cluster.
+	ValueConfigMaps []ConfigMapRef
+	// Project is the AppProject the sources and the destination of the Application are
+	// validated against, nothing is validated if nil
+	Project *v1alpha1.AppProject
+	// AllowEmptyRepoURL renders sources without a repoURL, which are otherwise rejected, as
+	// sources of the local repository
+	AllowEmptyRepoURL bool
+	// SourceNamespace replaces the namespace the Application is in, e.g. for Applications
+	// an app-of-apps creates outside the Argo CD namespace. It is checked against the
+	// sourceNamespaces of Project.
+	SourceNamespace string
+	// ControllerNamespace is the namespace Argo CD is installed in, whose Applications are
+	// permitted in every Project. DefaultControllerNamespace if empty.
+	ControllerNamespace string
+	// AppNameOverride replaces the name of the Application in the tracking label and as
+	// the default Helm release name
+	AppNameOverride string
+	// Revision replaces the targetRevision of every source in the manifest request, e.g. to
+	// render a specific tag. Remote Helm charts are still downloaded in their targetRevision.
+	Revision string
+	// DryRunMode set to DryRunModeClient or DryRunModeServer passes the rendered manifests to
+	// kubectl apply --dry-run. The warnings of kubectl are added to the result, and manifests
+	// rejected by kubectl fail the render.
+	DryRunMode string
+	// KubeconfigPath is the kubeconfig kubectl uses for DryRunMode, the default kubeconfig if empty
+	KubeconfigPath string
+	// DryRun set to DryRunPrint returns the equivalent command of every source in
+	// TemplateResult.Commands without rendering anything. Empty renders the sources.
+	DryRun string
+
+	// applicationData is rendered instead of reading ApplicationFile, e.g. stdin read once by Watch
+	applicationData []byte
 }
 
+// DefaultMaxManifestSize is the combined size rendered manifests may have if MaxManifestSize is empty
+const DefaultMaxManifestSize = "10Mi"
+
+// generateManifests is the Argo CD manifest generation, replaceable in tests
+var generateManifests = repository.GenerateManifests
+
 // TemplateResult contains the results of the templating process
 type TemplateResult struct {
 	Objects          []*unstructured.Unstructured
-	Warnings         []string
+	Warnings         []Warning
 	SourcesProcessed int
+	// SourceTypes contains the detected type of each source, in the order of the sources
+	SourceTypes []v1alpha1.ApplicationSourceType
+	// Duplicates contains the resources rendered more than once, of which only the last one is kept
+	Duplicates []DuplicateResource
+	// Commands contains the equivalent command of each source when DryRun is DryRunPrint
+	Commands []string
+	// SourceDurations contains how long rendering each source took, in the order of the sources
+	SourceDurations []time.Duration
+	// TotalDuration is how long the whole templating process took
+	TotalDuration time.Duration
+	// ServerSideDiff is set if the Application enables server-side diff with the
+	// argocd.argoproj.io/compare-options annotation
+	ServerSideDiff bool
+	// Stats contains the number of rendered objects of each kind
+	Stats map[string]int
 }
 
 // TemplateFromApplication processes an ArgoCD Application and returns templated manifests
 func TemplateFromApplication(ctx context.Context, opts TemplateOptions) (*TemplateResult, error) {
-	requests, err := buildRequestFromApplication(opts.ApplicationFile)
+	start := time.Now()
+	if opts.DryRun != "" && opts.DryRun != DryRunPrint {
+		return nil, fmt.Errorf("invalid dry run mode %q, expected %q", opts.DryRun, DryRunPrint)
+	}
+	if opts.DryRunMode != "" {
+		if err := validateDryRunMode(opts.DryRunMode); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	var requests []*apiclient.ManifestRequest
+	var settings applicationSettings
+	var err error
+	if opts.RepoRoot == "" {
+		opts.RepoRoot, err = discoverRepoRoot(opts.ApplicationFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.applicationData != nil {
+		requests, settings, err = buildRequestFromApplicationBytes(ctx, opts.applicationData, opts)
+	} else if opts.ApplicationFile == "" && opts.ApplicationURL != "" {
+		data, fetchErr := fetchApplication(ctx, opts.ApplicationURL, opts.ApplicationURLTimeout)
+		if fetchErr != nil {
+			return nil, &RenderError{SourceIndex: -1, Phase: RenderPhaseFetch, Err: fetchErr}
+		}
+		requests, settings, err = buildRequestFromApplicationBytes(ctx, data, opts)
+	} else if opts.ApplicationFile == "" && opts.ClusterApp.Name != "" {
+		data, fetchErr := fetchClusterApplication(ctx, opts.ClusterApp)
+		if fetchErr != nil {
+			return nil, &RenderError{SourceIndex: -1, Phase: RenderPhaseFetch, Err: fetchErr}
+		}
+		requests, settings, err = buildRequestFromApplicationBytes(ctx, data, opts)
+	} else {
+		requests, settings, err = buildRequestFromApplicationFile(ctx, opts.ApplicationFile, opts)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error parsing Application CRD: %w", err)
 	}
 
-	var allManifests []string
-	var warnings []string
+	result, err := templateFromRequests(ctx, requests, settings, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Include fetching and parsing the Application and downloading Helm charts
+	result.TotalDuration = time.Since(start)
+	return result, nil
+}
 
-	// Process each source
-	for sourceIndex, q := range requests {
-		appPath := q.ApplicationSource.Path
-		repoRoot := opts.RepoRoot
-		if repoRoot == "" {
-			repoRoot = "."
-		}
+// templateFromRequests renders the manifest requests of an Application and post-processes the objects
+func templateFromRequests(ctx context.Context, requests []*apiclient.ManifestRequest, settings applicationSettings, opts TemplateOptions) (*TemplateResult, error) {
+	start := time.Now()
+	maxSize, err := maxManifestSize(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ExcludeHookResources && opts.HooksOnly {
+		return nil, fmt.Errorf("ExcludeHookResources and HooksOnly cannot be combined")
+	}
+	if opts.SortOrder != "" && opts.SortOrder != SortOrderGVK {
+		return nil, fmt.Errorf("invalid sort order %q, expected %q", opts.SortOrder, SortOrderGVK)
+	}
 
-		appSourceType, err := repository.GetAppSourceType(ctx, q.ApplicationSource, appPath, repoRoot, q.AppName, q.EnabledSourceTypes, []string{}, []string{})
+	if opts.DryRun == DryRunPrint {
+		commands, err := dryRunCommands(ctx, requests, opts)
 		if err != nil {
-			return nil, fmt.Errorf("error getting app source type: %w", err)
+			return nil, err
 		}
+		return &TemplateResult{SourcesProcessed: len(requests), Commands: commands, TotalDuration: time.Since(start)}, nil
+	}
+
+	// The values are fetched once for all sources, opts is a copy
+	opts.Helm.secretValues, err = fetchValueSecrets(ctx, opts.ValueSecrets)
+	if err != nil {
+		return nil, &RenderError{SourceIndex: -1, Phase: RenderPhaseFetch, Err: err}
+	}
+	opts.Helm.defaultValues, err = fetchValueConfigMaps(ctx, opts.ValueConfigMaps)
+	if err != nil {
+		return nil, &RenderError{SourceIndex: -1, Phase: RenderPhaseFetch, Err: err}
+	}
 
-		// For Kustomize sources, create a temporary overlay to avoid modifying the original
-		if appSourceType == v1alpha1.ApplicationSourceTypeKustomize {
-			tempDir, err := os.MkdirTemp(".", "kustomize-overlay-*")
+	var allManifests []string
+	var warnings []Warning
+
+	// Process each source, keeping the manifests in the order of the sources
+	renderedSources := make([]*renderedSource, len(requests))
+	if opts.Concurrency > 1 || opts.Concurrency == -1 {
+		g, gctx := errgroup.WithContext(ctx)
+		if opts.Concurrency > 0 {
+			g.SetLimit(opts.Concurrency)
+		}
+		for sourceIndex, q := range requests {
+			g.Go(func() error {
+				rendered, err := renderSource(gctx, sourceIndex, q, opts)
+				if err != nil {
+					return err
+				}
+				renderedSources[sourceIndex] = rendered
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+	} else {
+		for sourceIndex, q := range requests {
+			rendered, err := renderSource(ctx, sourceIndex, q, opts)
 			if err != nil {
-				return nil, fmt.Errorf("error creating temp directory for Kustomize overlay: %w", err)
+				return nil, err
 			}
-			defer os.RemoveAll(tempDir)
+			renderedSources[sourceIndex] = rendered
+		}
+	}
 
-			relPath, err := filepath.Rel(tempDir, appPath)
+	// Collect manifests from all sources, remembering the source of each manifest
+	var manifestSources []int
+	sourceTypes := make([]v1alpha1.ApplicationSourceType, len(renderedSources))
+	sourceDurations := make([]time.Duration, len(renderedSources))
+	for sourceIndex, rendered := range renderedSources {
+		sourceStart := len(manifestSources)
+		for _, manifest := range rendered.Manifests {
+			// A manifest can contain several YAML documents
+			documents, err := splitManifest(manifest)
 			if err != nil {
-				os.RemoveAll(tempDir)
-				return nil, fmt.Errorf("error calculating relative path: %w", err)
+				warnings = append(warnings, Warning{Severity: WarningSeverityError, Message: fmt.Sprintf("Failed to parse manifest: %v", err), SourceIndex: sourceIndex})
+				continue
 			}
-
-			// Create a kustomization.yaml that references the original path
-			kustomizationContent := fmt.Sprintf(`apiVersion: kustomize.config.k8s.io/v1beta1
-kind: Kustomization
-resources:
-- %s
-`, relPath)
-
-			kustomizationPath := filepath.Join(tempDir, "kustomization.yaml")
-			if err := os.WriteFile(kustomizationPath, []byte(kustomizationContent), 0644); err != nil {
-				os.RemoveAll(tempDir)
-				return nil, fmt.Errorf("error writing kustomization.yaml: %w", err)
+			allManifests = append(allManifests, documents...)
+			for range documents {
+				manifestSources = append(manifestSources, sourceIndex)
 			}
-
-			appPath = tempDir
 		}
-
-		maxSize := resource.MustParse("10Mi")
-		if opts.MaxManifestSize != "" {
-			maxSize = resource.MustParse(opts.MaxManifestSize)
+		if logger := opts.logger(); logger != nil {
+			logger.Log(ManifestCountEvent{SourceIndex: sourceIndex, Count: len(manifestSources) - sourceStart})
 		}
+		sourceTypes[sourceIndex] = rendered.SourceType
+		sourceDurations[sourceIndex] = rendered.Duration
+		warnings = append(warnings, rendered.Warnings...)
+	}
 
-		// Call the core GenerateManifests function directly
-		response, err := repository.GenerateManifests(
-			ctx,
-			appPath,               // app path within repo
-			repoRoot,              // repo root (current directory)
-			"",                    // revision (empty for local files)
-			q,                     // manifest request
-			true,                  // isLocal=true - crucial for local operation!
-			&git.NoopCredsStore{}, // no git credentials needed
-			maxSize,               // max combined manifest size
-			nil,                   // no temp paths needed for local operation
-		)
-
-		if err != nil {
-			return nil, fmt.Errorf("error generating manifests for source %d: %w", sourceIndex+1, err)
-		}
+	if opts.MaxManifestCount > 0 && len(allManifests) > opts.MaxManifestCount {
+		return nil, &RenderError{SourceIndex: -1, Phase: RenderPhaseRender, Err: fmt.Errorf("rendered %d manifests, exceeding the limit of %d", len(allManifests), opts.MaxManifestCount)}
+	}
 
-		// Collect manifests from this source
-		allManifests = append(allManifests, response.Manifests...)
+	var totalSize int64
+	for _, manifest := range allManifests {
+		totalSize += int64(len(manifest))
+	}
+	if totalSize > maxSize.Value() {
+		return nil, &RenderError{SourceIndex: -1, Phase: RenderPhaseRender, Err: &ManifestSizeLimitError{
+			Actual: *resource.NewQuantity(totalSize, resource.BinarySI),
+			Limit:  maxSize,
+		}}
 	}
 
 	// Parse manifests into unstructured objects for deduplication
 	var targetObjects []*unstructured.Unstructured
-	for _, manifest := range allManifests {
+	var objectSources []int
+	for manifestIndex, manifest := range allManifests {
 		var obj unstructured.Unstructured
 		if err := json.Unmarshal([]byte(manifest), &obj); err != nil {
-			warnings = append(warnings, fmt.Sprintf("Failed to parse manifest as JSON: %v", err))
+			warnings = append(warnings, Warning{Severity: WarningSeverityError, Message: fmt.Sprintf("Failed to parse manifest as JSON: %v", err), SourceIndex: manifestSources[manifestIndex]})
 			continue
 		}
 		targetObjects = append(targetObjects, &obj)
+		objectSources = append(objectSources, manifestSources[manifestIndex])
 	}
 
 	// Deduplicate target objects using the library function
 	infoProvider := &resourceInfoProviderStub{}
 	dedupedObjects, conditions, err := controller.DeduplicateTargetObjects(requests[0].Namespace, targetObjects, infoProvider)
 	if err != nil {
-		return nil, fmt.Errorf("error deduplicating target objects: %w", err)
+		return nil, &RenderError{SourceIndex: -1, Phase: RenderPhaseRender, Err: fmt.Errorf("error deduplicating target objects: %w", err)}
+	}
+
+	// Deduplication does not preserve the order, so restore the order the sources rendered them in
+	objectIndex := make(map[*unstructured.Unstructured]int, len(targetObjects))
+	for i, obj := range targetObjects {
+		objectIndex[obj] = i
 	}
+	sort.SliceStable(dedupedObjects, func(i, j int) bool {
+		return objectIndex[dedupedObjects[i]] < objectIndex[dedupedObjects[j]]
+	})
+	sort.SliceStable(conditions, func(i, j int) bool {
+		return conditions[i].Message < conditions[j].Message
+	})
 
 	// Collect duplicate warnings
+	duplicates := findDuplicates(targetObjects, objectSources, conditions)
 	for _, condition := range conditions {
-		warnings = append(warnings, condition.Message)
+		warnings = append(warnings, Warning{
+			Severity:    WarningSeverityWarning,
+			Message:     condition.Message,
+			SourceIndex: -1,
+			ResourceRef: duplicateRef(condition.Message, duplicates),
+		})
+	}
+
+	if opts.NamespaceOverride != "" {
+		warnings = append(warnings, applyNamespaceOverride(dedupedObjects, opts.NamespaceOverride, newScopeInfoProvider(dedupedObjects))...)
+	}
+
+	// Fields ignored by managers have to be removed before the managed fields are stripped
+	if opts.ApplyIgnoreDifferences {
+		warnings = append(warnings, newWarnings(WarningSeverityWarning, -1, applyIgnoreDifferences(dedupedObjects, settings.IgnoreDifferences, requests[0].Namespace))...)
+	}
+
+	stripOpts := StripOptions{
+		ManagedFields:     opts.StripManagedFields,
+		ResourceVersion:   opts.StripClusterFields,
+		UID:               opts.StripClusterFields,
+		Generation:        opts.StripClusterFields,
+		CreationTimestamp: opts.StripClusterFields,
+		Status:            opts.StripClusterFields,
+	}
+	for _, obj := range dedupedObjects {
+		stripClusterFields(obj, stripOpts)
+	}
+
+	applyMetadataInjections(dedupedObjects, InjectOptions{
+		Labels:      opts.InjectLabels,
+		Annotations: opts.InjectAnnotations,
+		Overwrite:   opts.InjectOverwrite,
+	})
+
+	if opts.Selector != "" {
+		dedupedObjects, err = FilterBySelector(dedupedObjects, opts.Selector)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dedupedObjects = FilterByKind(dedupedObjects, opts.IncludeKinds)
+	dedupedObjects = ExcludeByKind(dedupedObjects, opts.ExcludeKinds)
+	dedupedObjects = filterAnnotations(dedupedObjects, opts.IncludeAnnotations, opts.ExcludeAnnotations)
+	if opts.ExcludeHookResources || opts.HooksOnly {
+		dedupedObjects = filterHooks(dedupedObjects, opts.HooksOnly)
+	}
+
+	switch {
+	case opts.SortOrder == SortOrderGVK:
+		SortManifests(dedupedObjects)
+	case opts.SortBySyncWave:
+		dedupedObjects = SortBySyncWave(dedupedObjects)
+	case opts.SortManifests:
+		dedupedObjects = SortByInstallOrder(dedupedObjects)
+	}
+
+	if opts.Validate {
+		for _, validationError := range ValidateManifests(dedupedObjects, ValidateOptions{SchemaDir: opts.SchemaDir}) {
+			warnings = append(warnings, Warning{
+				Severity:    WarningSeverityError,
+				Message:     validationError.Error(),
+				SourceIndex: -1,
+				ResourceRef: resourceRef(validationError.Group, validationError.Version, validationError.Kind, validationError.Namespace, validationError.Name),
+			})
+		}
+	}
+
+	if opts.KubeconformValidate {
+		for _, validationError := range ValidateWithKubeconform(dedupedObjects, opts.KubeconformOptions) {
+			warnings = append(warnings, Warning{
+				Severity:    WarningSeverityError,
+				Message:     validationError.Error(),
+				SourceIndex: -1,
+				ResourceRef: resourceRef(validationError.Group, validationError.Version, validationError.Kind, validationError.Namespace, validationError.Name),
+			})
+		}
+	}
+
+	if opts.DryRunMode != "" {
+		dryRunWarnings, err := KubectlDryRun(ctx, dedupedObjects, opts.DryRunMode, opts.KubeconfigPath)
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, newWarnings(WarningSeverityWarning, -1, dryRunWarnings)...)
+	}
+
+	warnings = ignoreWarnings(warnings, opts.IgnoreWarnings)
+	if opts.WarningsAsErrors && len(warnings) > 0 {
+		return nil, &WarningsAsErrorsError{Warnings: warnings}
 	}
 
 	return &TemplateResult{
 		Objects:          dedupedObjects,
 		Warnings:         warnings,
 		SourcesProcessed: len(requests),
+		SourceTypes:      sourceTypes,
+		Duplicates:       duplicates,
+		SourceDurations:  sourceDurations,
+		TotalDuration:    time.Since(start),
+		ServerSideDiff:   settings.ServerSideDiff,
+		Stats:            ComputeStats(dedupedObjects),
 	}, nil
 }
 
+// renderedSource contains the manifests of a single Application source
+type renderedSource struct {
+	Manifests  []string
+	Warnings   []Warning
+	SourceType v1alpha1.ApplicationSourceType
+	Duration   time.Duration
+}
+
+// renderSource generates the manifests of a single Application source and returns the detected source type
+func renderSource(ctx context.Context, sourceIndex int, q *apiclient.ManifestRequest, opts TemplateOptions) (*renderedSource, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &RenderError{SourceIndex: sourceIndex, Phase: RenderPhaseRender, Err: err}
+	}
+	repoRoot := opts.RepoRoot
+	if repoRoot == "" {
+		repoRoot = "."
+	}
+	appPath := sourcePath(q.ApplicationSource.Path, repoRoot)
+	// Argo CD only reads sources from the repository, a path outside of the root is most
+	// likely resolved against the wrong root
+	var pathWarnings []Warning
+	if q.ApplicationSource.Path != "" && !inRepoRoot(appPath, repoRoot) {
+		pathWarnings = newWarnings(WarningSeverityWarning, sourceIndex, []string{fmt.Sprintf("source path %s is outside the repository root %s", q.ApplicationSource.Path, repoRoot)})
+	}
+
+	appSourceType, err := DetectSourceType(ctx, q.ApplicationSource, appPath, repoRoot, q.AppName)
+	if err != nil {
+		return nil, &RenderError{SourceIndex: sourceIndex, Phase: RenderPhaseDetect, Err: fmt.Errorf("error getting app source type: %w", err)}
+	}
+
+	logger := opts.logger()
+	var command string
+	if logger != nil {
+		command = equivalentCommand(ctx, appSourceType, appPath, q, opts)
+		logger.Log(SourceDetectedEvent{SourceIndex: sourceIndex, SourceType: appSourceType, Path: appPath, Command: command})
+	}
+
+	start := time.Now()
+	var manifests, warnings []string
+	// Argo CD runs plugins in a sidecar, so they are run directly instead. Their stderr is
+	// only informational.
+	severity := WarningSeverityWarning
+	if appSourceType == v1alpha1.ApplicationSourceTypePlugin {
+		severity = WarningSeverityInfo
+		manifests, warnings, err = runPlugin(ctx, q, appPath)
+		if err != nil {
+			return nil, &RenderError{SourceIndex: sourceIndex, SourceType: appSourceType, Phase: RenderPhaseRender, Err: fmt.Errorf("error running plugin for source %d: %w", sourceIndex+1, err)}
+		}
+	} else {
+		manifests, warnings, err = generateSourceManifests(ctx, sourceIndex, appSourceType, appPath, repoRoot, q, opts)
+		if err != nil {
+			return nil, &RenderError{SourceIndex: sourceIndex, SourceType: appSourceType, Phase: RenderPhaseRender, Err: err}
+		}
+	}
+	duration := time.Since(start)
+
+	if logger != nil {
+		logger.Log(CommandExecutedEvent{SourceIndex: sourceIndex, SourceType: appSourceType, Command: command, Duration: duration})
+	}
+
+	return &renderedSource{Manifests: manifests, Warnings: append(pathWarnings, newWarnings(severity, sourceIndex, warnings)...), SourceType: appSourceType, Duration: duration}, nil
+}
+
+// generateSourceManifests generates the manifests of a source of the given type
+func generateSourceManifests(ctx context.Context, sourceIndex int, appSourceType v1alpha1.ApplicationSourceType, appPath, repoRoot string, q *apiclient.ManifestRequest, opts TemplateOptions) ([]string, []string, error) {
+	// Argo CD runs helm and kustomize itself, check for them first to return a helpful error
+	switch appSourceType {
+	case v1alpha1.ApplicationSourceTypeHelm:
+		if err := checkBinaryExists("helm"); err != nil {
+			return nil, nil, err
+		}
+		if err := opts.Helm.checkHelmTemplateBinary(); err != nil {
+			return nil, nil, err
+		}
+	case v1alpha1.ApplicationSourceTypeKustomize:
+		if err := checkBinaryExists("kustomize"); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var warnings []string
+	if appSourceType == v1alpha1.ApplicationSourceTypeHelm {
+		valueFiles := helmValueFiles{chartPath: appPath, repoRoot: repoRoot, refs: q.RefSources, sourceRefs: opts.SourceRefs}
+		if err := applySecretHelmValues(q.ApplicationSource, opts.Helm.secretValues); err != nil {
+			return nil, nil, fmt.Errorf("error applying Helm values to source %d: %w", sourceIndex+1, err)
+		}
+		if opts.Helm.SOPSDecrypt {
+			if err := decryptSOPSHelmValues(ctx, q.ApplicationSource.Helm, valueFiles); err != nil {
+				return nil, nil, fmt.Errorf("error decrypting Helm values for source %d: %w", sourceIndex+1, err)
+			}
+		}
+		if opts.Helm.ExpandEnvInValues {
+			expandWarnings, err := expandEnvInHelmValues(ctx, q.ApplicationSource.Helm, valueFiles)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error expanding environment variables in Helm values for source %d: %w", sourceIndex+1, err)
+			}
+			warnings = append(warnings, expandWarnings...)
+		}
+		if opts.Helm.GenerateName {
+			if q.ApplicationSource.Helm == nil {
+				q.ApplicationSource.Helm = &v1alpha1.ApplicationSourceHelm{}
+			}
+			q.ApplicationSource.Helm.ReleaseName = generateReleaseName(appPath)
+		}
+		if err := applyDefaultHelmValues(ctx, q.ApplicationSource, opts.Helm.defaultValues, valueFiles); err != nil {
+			return nil, nil, fmt.Errorf("error applying Helm values to source %d: %w", sourceIndex+1, err)
+		}
+		if err := applyHelmOptions(q.ApplicationSource, opts.Helm); err != nil {
+			return nil, nil, fmt.Errorf("error applying Helm options to source %d: %w", sourceIndex+1, err)
+		}
+		if opts.Helm.UpdateDependencies {
+			if err := runHelmDependencyUpdate(ctx, opts.Helm.helmBinary(), appPath); err != nil {
+				return nil, nil, fmt.Errorf("error updating Helm dependencies for source %d: %w", sourceIndex+1, err)
+			}
+		} else {
+			warnings = append(warnings, checkHelmDependencies(appPath)...)
+		}
+		// helm lint fails on charts whose dependencies have not been fetched yet
+		if opts.Helm.LintBeforeRender {
+			if err := runHelmLint(ctx, opts.Helm.helmBinary(), valueFiles, q.Namespace, q.ApplicationSource); err != nil {
+				return nil, nil, fmt.Errorf("error linting Helm chart for source %d: %w", sourceIndex+1, err)
+			}
+		}
+	}
+
+	if appSourceType == v1alpha1.ApplicationSourceTypeDirectory {
+		applyDirectoryOptions(q.ApplicationSource, opts.Directory)
+	}
+
+	// For Kustomize sources, create a temporary overlay to avoid modifying the original
+	if appSourceType == v1alpha1.ApplicationSourceTypeKustomize {
+		if err := validateKustomizeOptions(opts.Kustomize); err != nil {
+			return nil, nil, err
+		}
+		kustomizeOpts, reorderWarnings := checkReorderSupported(ctx, opts.Kustomize)
+		warnings = append(warnings, reorderWarnings...)
+		warnings = append(warnings, checkKustomizeHelm(kustomizeOpts)...)
+		// The overlay is outside the original path, which kustomize only loads without restrictions
+		q.KustomizeOptions = kustomizeBuildOptions(kustomizeOpts, true)
+
+		overlayDir, cleanup, err := createKustomizeOverlay(appPath, opts.Kustomize)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer cleanup()
+
+		// kustomize build runs in the repository root, so the overlay path must be absolute
+		appPath = overlayDir
+	}
+
+	maxSize, err := maxManifestSize(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gitRepoPaths, err := refRepoPaths(q.RefSources, repoRoot, opts.SourceRefs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Call the core GenerateManifests function directly
+	response, err := generateManifests(
+		ctx,
+		appPath,               // app path within repo
+		repoRoot,              // repo root (current directory)
+		"",                    // revision (empty for local files)
+		q,                     // manifest request
+		true,                  // isLocal=true - crucial for local operation!
+		&git.NoopCredsStore{}, // no git credentials needed
+		maxSize,               // max combined manifest size
+		gitRepoPaths,          // local directories of the referenced sources
+	)
+
+	if err != nil {
+		// Commands killed because of the context fail with an unrelated error
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, nil, fmt.Errorf("error generating manifests for source %d: %w: %v", sourceIndex+1, ctxErr, err)
+		}
+		return nil, nil, fmt.Errorf("error generating manifests for source %d: %w", sourceIndex+1, err)
+	}
+
+	manifests := response.Manifests
+	if appSourceType == v1alpha1.ApplicationSourceTypeHelm && opts.Helm.PostRenderer != "" {
+		manifests, err = runPostRenderer(ctx, opts.Helm.PostRenderer, opts.Helm.PostRendererArgs, manifests)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error post-rendering source %d: %w", sourceIndex+1, err)
+		}
+	}
+
+	return manifests, warnings, nil
+}
+
+// splitManifest returns each YAML or JSON document of a manifest as JSON, skipping empty documents
+func splitManifest(manifest string) ([]string, error) {
+	var documents []string
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	for {
+		var document json.RawMessage
+		if err := decoder.Decode(&document); err != nil {
+			if err == io.EOF {
+				return documents, nil
+			}
+			return nil, err
+		}
+		if trimmed := bytes.TrimSpace(document); len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+			continue
+		}
+		documents = append(documents, string(document))
+	}
+}
+
+// maxManifestSize parses the MaxManifestSize of the options
+func maxManifestSize(opts TemplateOptions) (resource.Quantity, error) {
+	size := opts.MaxManifestSize
+	if size == "" {
+		size = DefaultMaxManifestSize
+	}
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("invalid max manifest size %q: %w", size, err)
+	}
+	return quantity, nil
+}
+
 // TemplateFromApplicationYAML processes an ArgoCD Application from YAML content
 func TemplateFromApplicationYAML(ctx context.Context, yamlContent string, repoRoot string) (*TemplateResult, error) {
-	// Write YAML to temporary file
-	tempFile, err := os.CreateTemp("", "argocd-app-*.yaml")
+	opts := TemplateOptions{
+		RepoRoot: repoRoot,
+	}
+
+	requests, settings, err := buildRequestFromApplicationBytes(ctx, []byte(yamlContent), opts)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Application CRD: %w", err)
+	}
+
+	return templateFromRequests(ctx, requests, settings, opts)
+}
+
+// TemplateFromApplicationWithOverrides processes an ArgoCD Application from YAML content after
+// setting the fields of overrides, keyed by dot-separated paths like "spec.source.targetRevision"
+func TemplateFromApplicationWithOverrides(ctx context.Context, yamlContent string, overrides map[string]interface{}, repoRoot string) (*TemplateResult, error) {
+	data, err := applyOverrides([]byte(yamlContent), overrides)
 	if err != nil {
-		return nil, fmt.Errorf("error creating temp file: %w", err)
+		return nil, fmt.Errorf("error parsing Application CRD: %w", err)
 	}
-	defer os.Remove(tempFile.Name())
-	defer tempFile.Close()
+	return TemplateFromApplicationYAML(ctx, string(data), repoRoot)
+}
 
-	if _, err := tempFile.WriteString(yamlContent); err != nil {
-		return nil, fmt.Errorf("error writing to temp file: %w", err)
+// applyOverrides sets the fields of overrides in the Application and returns it as JSON
+func applyOverrides(data []byte, overrides map[string]interface{}) ([]byte, error) {
+	var app map[string]interface{}
+	if err := yaml.Unmarshal(data, &app); err != nil {
+		return nil, &RenderError{SourceIndex: -1, Phase: RenderPhaseParse, Err: fmt.Errorf("failed to parse Application YAML: %w", err)}
+	}
+	if app == nil {
+		app = map[string]interface{}{}
 	}
 
-	opts := TemplateOptions{
-		ApplicationFile: tempFile.Name(),
-		RepoRoot:        repoRoot,
+	// Apply the overrides in a stable order so nested overrides of the same field are deterministic
+	paths := make([]string, 0, len(overrides))
+	for path := range overrides {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		// Round-trip the value through JSON so it only contains types SetNestedField can copy
+		encoded, err := json.Marshal(overrides[path])
+		if err != nil {
+			return nil, fmt.Errorf("invalid override for %s: %w", path, err)
+		}
+		var value interface{}
+		if err := json.Unmarshal(encoded, &value); err != nil {
+			return nil, fmt.Errorf("invalid override for %s: %w", path, err)
+		}
+		if err := unstructured.SetNestedField(app, value, strings.Split(path, ".")...); err != nil {
+			return nil, fmt.Errorf("failed to override %s: %w", path, err)
+		}
 	}
 
-	return TemplateFromApplication(ctx, opts)
+	return json.Marshal(app)
 }
 
-func buildRequestFromApplication(filePath string) ([]*apiclient.ManifestRequest, error) {
+// buildRequestFromApplicationFile reads the Application from a file, or from stdin if the path is "-",
+// and returns its manifest requests
+func buildRequestFromApplicationFile(ctx context.Context, filePath string, opts TemplateOptions) ([]*apiclient.ManifestRequest, applicationSettings, error) {
 	var data []byte
 	var err error
 
@@ -179,136 +797,168 @@ func buildRequestFromApplication(filePath string) ([]*apiclient.ManifestRequest,
 		data, err = os.ReadFile(filePath)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read application file: %w", err)
+		return nil, applicationSettings{}, &RenderError{SourceIndex: -1, Phase: RenderPhaseParse, Err: fmt.Errorf("failed to read application file: %w", err)}
 	}
 
+	return buildRequestFromApplicationBytes(ctx, data, opts)
+}
+
+// localRepoURL is the repoURL of sources without one when TemplateOptions.AllowEmptyRepoURL is set
+const localRepoURL = "file://."
+
+// ParseApplication parses an Argo CD Application from YAML or JSON and checks that it is an
+// argoproj.io/v1alpha1 Application with a name and at least one source
+func ParseApplication(data []byte) (*v1alpha1.Application, error) {
 	var app v1alpha1.Application
 	if err := yaml.Unmarshal(data, &app); err != nil {
 		return nil, fmt.Errorf("failed to parse Application YAML: %w", err)
 	}
 
+	if app.APIVersion != "argoproj.io/v1alpha1" {
+		return nil, fmt.Errorf("expected apiVersion 'argoproj.io/v1alpha1', got '%s'", app.APIVersion)
+	}
 	if app.Kind != "Application" {
 		return nil, fmt.Errorf("expected kind 'Application', got '%s'", app.Kind)
 	}
-
-	sources := app.Spec.GetSources()
-	if len(sources) == 0 {
+	if app.Name == "" {
+		return nil, fmt.Errorf("metadata.name is required")
+	}
+	if len(app.Spec.GetSources()) == 0 {
 		return nil, fmt.Errorf("no sources found in application spec")
 	}
+	return &app, nil
+}
 
-	var requests []*apiclient.ManifestRequest
+// buildRequestFromApplicationBytes returns a manifest request for each source of the Application
+// together with the settings of the Application that apply to all sources
+func buildRequestFromApplicationBytes(ctx context.Context, data []byte, opts TemplateOptions) ([]*apiclient.ManifestRequest, applicationSettings, error) {
+	app, err := ParseApplication(data)
+	if err != nil {
+		return nil, applicationSettings{}, &RenderError{SourceIndex: -1, Phase: RenderPhaseParse, Err: err}
+	}
 
-	for i, source := range sources {
-		if source.RepoURL == "" {
-			return nil, fmt.Errorf("source[%d].repoURL is required", i)
+	if opts.SourceNamespace != "" {
+		app.Namespace = opts.SourceNamespace
+	}
+	if opts.Project != nil {
+		if err := ValidateSourceRepos(app, opts.Project, opts.ControllerNamespace); err != nil {
+			return nil, applicationSettings{}, &RenderError{SourceIndex: -1, Phase: RenderPhaseParse, Err: err}
 		}
+	}
 
-		// Handle remote Helm charts by downloading them to a temporary directory
-		modifiedSource := sources[i]
-		if source.IsHelm() {
-			chartDir, err := downloadHelmChart(source.RepoURL, source.Chart, source.TargetRevision)
-			if err != nil {
-				return nil, fmt.Errorf("failed to download Helm chart for source[%d]: %w", i, err)
+	sources := app.Spec.GetSources()
+	if opts.AllowEmptyRepoURL {
+		for i := range sources {
+			if sources[i].RepoURL == "" {
+				sources[i].RepoURL = localRepoURL
 			}
-
-			// Modify the source to point to the local directory
-			modifiedSource = sources[i]
-			modifiedSource.Path = chartDir
-			modifiedSource.Chart = "" // Clear chart field since we're now using a local path
 		}
+	}
 
-		req := &apiclient.ManifestRequest{
-			Repo: &v1alpha1.Repository{
-				Repo: source.RepoURL,
-			},
-			ApplicationSource: &modifiedSource, // Use the potentially modified source
-			AppName:           app.Name,
-			Namespace:         app.Spec.Destination.Namespace,
-			Revision:          source.TargetRevision,
-			EnabledSourceTypes: map[string]bool{
-				string(v1alpha1.ApplicationSourceTypeHelm):      true,
-				string(v1alpha1.ApplicationSourceTypeKustomize): true,
-				string(v1alpha1.ApplicationSourceTypeDirectory): true,
-			},
-			AppLabelKey:        "app.kubernetes.io/instance",
-			TrackingMethod:     string(v1alpha1.TrackingMethodLabel),
-			InstallationID:     "local-cli",
-			ProjectName:        app.Spec.Project,
-			HasMultipleSources: len(sources) > 1,
+	// A digest identifies a single chart, so it cannot pin the charts of several sources
+	if opts.Helm.ChartDigest != "" {
+		if charts := countHelmCharts(sources); charts > 1 {
+			return nil, applicationSettings{}, &RenderError{SourceIndex: -1, Phase: RenderPhaseParse, Err: fmt.Errorf("the chart digest can only pin a single chart, but the Application has %d Helm chart sources", charts)}
 		}
-
-		requests = append(requests, req)
 	}
 
-	return requests, nil
-}
-
-// downloadHelmChart downloads a remote Helm chart to the XDG cache directory with reproducible naming
-func downloadHelmChart(repoURL, chartName, version string) (string, error) {
-	// Get XDG cache directory
-	cacheDir, err := getCacheDir()
+	cacheDir, err := resolveCacheDir(opts.CacheDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to get cache directory: %w", err)
+		return nil, applicationSettings{}, &RenderError{SourceIndex: -1, Phase: RenderPhaseFetch, Err: err}
 	}
 
-	// Create subdirectory for helm charts
-	helmCacheDir := filepath.Join(cacheDir, "local-argocd-renderer")
-	if err := os.MkdirAll(helmCacheDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create helm cache directory: %w", err)
+	if logger := opts.logger(); logger != nil && opts.AppNameOverride != "" && opts.AppNameOverride != app.Name {
+		logger.Log(AppNameOverrideEvent{AppName: app.Name, TrackingName: opts.AppNameOverride})
 	}
 
-	// Generate reproducible filename based on repoURL, chartName, and version
-	hashInput := fmt.Sprintf("%s|%s|%s", repoURL, chartName, version)
-	hash := sha256.Sum256([]byte(hashInput))
-	hashStr := hex.EncodeToString(hash[:])
-	chartDir := filepath.Join(helmCacheDir, fmt.Sprintf("chart-%s", hashStr))
-
-	// Check if chart is already cached
-	if _, err := os.Stat(chartDir); err == nil {
-		return chartDir, nil
+	refs, err := refSources(sources)
+	if err != nil {
+		return nil, applicationSettings{}, err
 	}
 
-	// Download the chart
-	args := []string{"pull", fmt.Sprintf("%s/%s", repoURL, chartName)}
-	if version != "" {
-		args = append(args, "--version", version)
-	}
-	args = append(args, "--destination", helmCacheDir)
-	args = append(args, "--untar")
+	var requests []*apiclient.ManifestRequest
 
-	cmd := exec.Command("helm", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("helm pull failed: %w\nOutput: %s", err, string(output))
-	}
+	for i, source := range sources {
+		if source.RepoURL == "" {
+			return nil, applicationSettings{}, &RenderError{SourceIndex: i, Phase: RenderPhaseParse, Err: fmt.Errorf("source[%d].repoURL is required", i)}
+		}
+		if isRefOnlySource(source) {
+			continue
+		}
+		applyManifestGeneratePaths(&sources[i], app.Annotations)
 
-	// Find the extracted chart directory (helm pull creates a directory with the chart name)
-	extractedDir := filepath.Join(helmCacheDir, chartName)
+		// Handle remote Helm charts by downloading them to a temporary directory. A dry run
+		// keeps the chart reference instead.
+		modifiedSource := sources[i]
+		if source.IsHelm() && opts.DryRun == "" {
+			cacheTTL := opts.CacheTTL
+			if cacheTTL == 0 {
+				cacheTTL = DefaultCacheTTL
+			}
+			retries := opts.HelmPullRetries
+			if retries == 0 {
+				retries = DefaultHelmPullRetries
+			}
+			var retryLog io.Writer
+			if opts.Verbose {
+				retryLog = opts.verboseWriter()
+			}
+			chartDir, err := downloadHelmChart(ctx, cacheDir, source.RepoURL, source.Chart, opts.Helm.chartVersion(source), cacheTTL, opts.VerifyCache, opts.HelmRegistryAuths, opts.Helm.helmBinary(), max(retries, 0), retryLog)
+			if err != nil {
+				return nil, applicationSettings{}, &RenderError{SourceIndex: i, SourceType: v1alpha1.ApplicationSourceTypeHelm, Phase: RenderPhaseFetch, Err: fmt.Errorf("failed to download Helm chart for source[%d]: %w", i, err)}
+			}
+
+			// Modify the source to point to the local directory
+			modifiedSource = sources[i]
+			modifiedSource.Path = chartDir
+			modifiedSource.Chart = "" // Clear chart field since we're now using a local path
+		}
 
-	// Rename to our reproducible name
-	if err := os.Rename(extractedDir, chartDir); err != nil {
-		return "", fmt.Errorf("failed to rename chart directory: %w", err)
+		request := newManifestRequest(app, &modifiedSource, len(sources) > 1, opts)
+		if len(refs) > 0 {
+			request.RefSources = refs
+		}
+		requests = append(requests, request)
 	}
 
-	return chartDir, nil
+	settings := applicationSettings{
+		IgnoreDifferences: app.Spec.IgnoreDifferences,
+		ServerSideDiff:    serverSideDiffEnabled(app.Annotations),
+	}
+	return requests, settings, nil
 }
 
-// getCacheDir returns the XDG cache directory
-func getCacheDir() (string, error) {
-	if cacheDir := os.Getenv("XDG_CACHE_HOME"); cacheDir != "" {
-		return cacheDir, nil
+// newManifestRequest returns the manifest request for a source of the Application
+func newManifestRequest(app *v1alpha1.Application, source *v1alpha1.ApplicationSource, hasMultipleSources bool, opts TemplateOptions) *apiclient.ManifestRequest {
+	appName := app.Name
+	if opts.AppNameOverride != "" {
+		appName = opts.AppNameOverride
 	}
-
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
+	revision := source.TargetRevision
+	if opts.Revision != "" {
+		revision = opts.Revision
 	}
 
-	return filepath.Join(homeDir, ".cache"), nil
+	return &apiclient.ManifestRequest{
+		Repo: &v1alpha1.Repository{
+			Repo: source.RepoURL,
+		},
+		ApplicationSource:  source,
+		AppName:            appName,
+		Namespace:          app.Spec.Destination.Namespace,
+		Revision:           revision,
+		EnabledSourceTypes: enabledSourceTypes(),
+		AppLabelKey:        "app.kubernetes.io/instance",
+		TrackingMethod:     string(v1alpha1.TrackingMethodLabel),
+		InstallationID:     "local-cli",
+		ProjectName:        app.Spec.Project,
+		HasMultipleSources: hasMultipleSources,
+		KubeVersion:        opts.KubeVersion,
+	}
 }
 
 // resourceInfoProviderStub is a simple implementation of kubeutil.ResourceInfoProvider
-// that treats all resources as cluster-scoped (returns false for IsNamespaced)
+// that treats all resources as namespaced (returns true for IsNamespaced)
 type resourceInfoProviderStub struct{}
 
 func (r *resourceInfoProviderStub) IsNamespaced(_ schema.GroupKind) (bool, error) {
@@ -1,19 +1,42 @@
 package renderer
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/yaml"
 
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+	"github.com/argoproj/argo-cd/v3/reposerver/repository"
+	"github.com/argoproj/argo-cd/v3/util/git"
+	utilio "github.com/argoproj/argo-cd/v3/util/io"
 	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
+// stubGenerateManifests replaces the manifest generation of Argo CD with stub for the rest of
+// the test. The stub receives the local path of the source and the manifest request.
+func stubGenerateManifests(t *testing.T, stub func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error)) {
+	t.Helper()
+	original := generateManifests
+	t.Cleanup(func() { generateManifests = original })
+	generateManifests = func(ctx context.Context, appPath, repoRoot, revision string, q *apiclient.ManifestRequest, isLocal bool, gitCredsStore git.CredsStore, maxCombinedManifestQuantity resource.Quantity, gitRepoPaths utilio.TempPaths, opts ...repository.GenerateManifestOpt) (*apiclient.ManifestResponse, error) {
+		return stub(appPath, q)
+	}
+}
+
 // formatOutput formats the TemplateResult the same way as the CLI
 func formatOutput(result *TemplateResult) string {
 	var output strings.Builder
@@ -22,24 +45,10 @@ func formatOutput(result *TemplateResult) string {
 	fmt.Fprintf(&output, "# Generated %d manifests from %d sources (%d after deduplication)\n", len(result.Objects), result.SourcesProcessed, len(result.Objects))
 	output.WriteString("---\n")
 
-	// Sort manifests for consistent ordering (by kind, then by name)
+	// Sort manifests for consistent ordering
 	sortedObjects := make([]*unstructured.Unstructured, len(result.Objects))
 	copy(sortedObjects, result.Objects)
-
-	// Simple sort by kind, then by name for consistency
-	for i := 0; i < len(sortedObjects)-1; i++ {
-		for j := i + 1; j < len(sortedObjects); j++ {
-			kind1 := sortedObjects[i].GetKind()
-			kind2 := sortedObjects[j].GetKind()
-			name1 := sortedObjects[i].GetName()
-			name2 := sortedObjects[j].GetName()
-
-			// Sort by kind first, then by name
-			if kind1 > kind2 || (kind1 == kind2 && name1 > name2) {
-				sortedObjects[i], sortedObjects[j] = sortedObjects[j], sortedObjects[i]
-			}
-		}
-	}
+	SortManifests(sortedObjects)
 
 	// Manifests
 	for i, obj := range sortedObjects {
@@ -110,6 +119,53 @@ spec:
 	}
 }
 
+func TestTemplateFromApplicationYAMLParallel(t *testing.T) {
+	yamlContent := `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: test-app
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/argoproj/argo-cd
+    path: examples/directory/input
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`
+
+	// The Application is no longer written to a temp file, point the temp directory somewhere unusable
+	t.Setenv("TMPDIR", filepath.Join(t.TempDir(), "missing"))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 50)
+	counts := make([]int, 50)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := TemplateFromApplicationYAML(context.Background(), yamlContent, ".")
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			counts[i] = len(result.Objects)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Goroutine %d: TemplateFromApplicationYAML failed: %v", i, err)
+			continue
+		}
+		if counts[i] != 2 {
+			t.Errorf("Goroutine %d: expected 2 objects, got %d", i, counts[i])
+		}
+	}
+}
+
 type goldenTestCase struct {
 	name         string
 	appPath      string
@@ -185,3 +241,503 @@ func TestGoldenExamples(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplateFromApplicationConcurrency(t *testing.T) {
+	yamlContent := `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: concurrent-app
+spec:
+  project: default
+  sources:
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+    directory:
+      include: guestbook-ui-deployment.yaml
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+    directory:
+      include: guestbook-ui-svc.yaml
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/helm/input/templates
+    directory:
+      include: none.yaml
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`
+
+	// Every call waits until all sources are being rendered, which only succeeds when they run concurrently
+	var started sync.WaitGroup
+	started.Add(3)
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+
+	original := generateManifests
+	t.Cleanup(func() { generateManifests = original })
+	generateManifests = func(ctx context.Context, appPath, repoRoot, revision string, q *apiclient.ManifestRequest, isLocal bool, gitCredsStore git.CredsStore, maxCombinedManifestQuantity resource.Quantity, gitRepoPaths utilio.TempPaths, opts ...repository.GenerateManifestOpt) (*apiclient.ManifestResponse, error) {
+		started.Done()
+		select {
+		case <-allStarted:
+		case <-time.After(5 * time.Second):
+			return nil, fmt.Errorf("sources were not rendered concurrently")
+		}
+		return original(ctx, appPath, repoRoot, revision, q, isLocal, gitCredsStore, maxCombinedManifestQuantity, gitRepoPaths, opts...)
+	}
+
+	tempFile := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(tempFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to write application: %v", err)
+	}
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: tempFile,
+		RepoRoot:        ".",
+		Concurrency:     -1,
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	// The manifests keep the order of the sources
+	if len(result.Objects) != 2 {
+		t.Fatalf("Expected 2 objects, got %d", len(result.Objects))
+	}
+	if result.Objects[0].GetKind() != "Deployment" || result.Objects[1].GetKind() != "Service" {
+		t.Errorf("Expected Deployment before Service, got %s and %s", result.Objects[0].GetKind(), result.Objects[1].GetKind())
+	}
+}
+
+func TestTemplateFromApplicationSourceTypes(t *testing.T) {
+	yamlContent := `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: source-types
+spec:
+  project: default
+  sources:
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/helm/input
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`
+
+	// Rendering the chart requires helm, only the detected source type is of interest here
+	installFakeHelm(t)
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		return &apiclient.ManifestResponse{}, nil
+	})
+
+	tempFile := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(tempFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to write application: %v", err)
+	}
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: tempFile,
+		RepoRoot:        ".",
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	expected := []v1alpha1.ApplicationSourceType{v1alpha1.ApplicationSourceTypeHelm, v1alpha1.ApplicationSourceTypeDirectory}
+	if len(result.SourceTypes) != len(expected) {
+		t.Fatalf("Expected %d source types, got %v", len(expected), result.SourceTypes)
+	}
+	for i := range expected {
+		if result.SourceTypes[i] != expected[i] {
+			t.Errorf("Source %d: expected %s, got %s", i, expected[i], result.SourceTypes[i])
+		}
+	}
+}
+
+func TestTemplateFromApplicationAppNameOverride(t *testing.T) {
+	var output bytes.Buffer
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/directory/app.yaml",
+		RepoRoot:        ".",
+		AppNameOverride: "directory-app-green",
+		Verbose:         true,
+		VerboseOutput:   &output,
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	for _, obj := range result.Objects {
+		if label := obj.GetLabels()["app.kubernetes.io/instance"]; label != "directory-app-green" {
+			t.Errorf("Expected the overridden tracking label on %s, got %q", obj.GetName(), label)
+		}
+	}
+	if !strings.Contains(output.String(), `instead of the Application name "directory-app"`) {
+		t.Errorf("Expected a warning about the tracking label, got:\n%s", output.String())
+	}
+
+	logger := &testLogger{}
+	if _, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/directory/app.yaml",
+		RepoRoot:        ".",
+		AppNameOverride: "directory-app-green",
+		Logger:          logger,
+	}); err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	expected := AppNameOverrideEvent{AppName: "directory-app", TrackingName: "directory-app-green"}
+	if len(logger.events) == 0 || logger.events[0] != expected {
+		t.Errorf("Expected the first event to be %+v, got %+v", expected, logger.events)
+	}
+}
+
+func TestTemplateFromApplicationRevision(t *testing.T) {
+	var revisions []string
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		revisions = append(revisions, q.Revision)
+		return &apiclient.ManifestResponse{}, nil
+	})
+
+	appFile := writeApplication(t, `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: revision-app
+spec:
+  sources:
+  - repoURL: https://github.com/myorg/myrepo
+    path: examples/directory/input
+    targetRevision: main
+  - repoURL: https://github.com/myorg/other
+    path: examples/directory/input
+  destination:
+    namespace: default
+`)
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		Revision:        "v1.2.3",
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	if len(revisions) != 2 {
+		t.Fatalf("Expected a manifest request for both sources, got %v", revisions)
+	}
+	for i, revision := range revisions {
+		if revision != "v1.2.3" {
+			t.Errorf("Expected revision v1.2.3 for source %d, got %q", i, revision)
+		}
+	}
+}
+
+func TestTemplateFromApplicationMaxManifestCount(t *testing.T) {
+	repoRoot := t.TempDir()
+	manifestDir := filepath.Join(repoRoot, "manifests")
+	if err := os.Mkdir(manifestDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for i := range 5 {
+		manifest := fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config-%d\n", i)
+		if err := os.WriteFile(filepath.Join(manifestDir, fmt.Sprintf("config-%d.yaml", i)), []byte(manifest), 0644); err != nil {
+			t.Fatalf("Failed to write manifest: %v", err)
+		}
+	}
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: configs
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: manifests
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`)
+	t.Chdir(repoRoot)
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile:  appFile,
+		RepoRoot:         ".",
+		MaxManifestCount: 3,
+	})
+	if err == nil {
+		t.Fatal("Expected an error when exceeding the manifest count")
+	}
+	if !strings.Contains(err.Error(), "rendered 5 manifests, exceeding the limit of 3") {
+		t.Errorf("Expected the count and limit in the error, got %v", err)
+	}
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile:  appFile,
+		RepoRoot:         ".",
+		MaxManifestCount: 5,
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if len(result.Objects) != 5 {
+		t.Errorf("Expected 5 objects, got %d", len(result.Objects))
+	}
+}
+
+func TestTemplateFromApplicationMaxManifestSize(t *testing.T) {
+	repoRoot := t.TempDir()
+	manifestDir := filepath.Join(repoRoot, "manifests")
+	if err := os.Mkdir(manifestDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	manifest := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"
+	if err := os.WriteFile(filepath.Join(manifestDir, "config.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: configs
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: manifests
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`)
+	t.Chdir(repoRoot)
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		MaxManifestSize: "ten megabytes",
+	})
+	if err == nil || !strings.Contains(err.Error(), `invalid max manifest size "ten megabytes"`) {
+		t.Errorf("Expected an invalid max manifest size error, got %v", err)
+	}
+
+	// The manifest generated by Argo CD, which includes the tracking label
+	size := len(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"labels":{"app.kubernetes.io/instance":"configs"},"name":"config"}}`)
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		MaxManifestSize: strconv.Itoa(size),
+	})
+	if err != nil {
+		t.Fatalf("Expected manifests exactly at the limit to render, got %v", err)
+	}
+	if len(result.Objects) != 1 {
+		t.Errorf("Expected 1 object, got %d", len(result.Objects))
+	}
+
+	_, err = TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		MaxManifestSize: strconv.Itoa(size - 1),
+	})
+	var sizeErr *ManifestSizeLimitError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("Expected a ManifestSizeLimitError, got %v", err)
+	}
+	if sizeErr.Actual.Value() != int64(size) || sizeErr.Limit.Value() != int64(size-1) {
+		t.Errorf("Expected actual %d and limit %d, got %s and %s", size, size-1, sizeErr.Actual.String(), sizeErr.Limit.String())
+	}
+}
+
+func TestSplitManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		expected []string
+	}{
+		{
+			name:     "json",
+			manifest: `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a"}}`,
+			expected: []string{`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a"}}`},
+		},
+		{
+			name:     "multiple yaml documents",
+			manifest: "---\nkind: ConfigMap\nmetadata:\n  name: a\n---\n# empty\n---\nkind: Secret\nmetadata:\n  name: b\n",
+			expected: []string{`{"kind":"ConfigMap","metadata":{"name":"a"}}`, `{"kind":"Secret","metadata":{"name":"b"}}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents, err := splitManifest(tt.manifest)
+			if err != nil {
+				t.Fatalf("splitManifest failed: %v", err)
+			}
+			if strings.Join(documents, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Expected %v, got %v", tt.expected, documents)
+			}
+		})
+	}
+
+	if _, err := splitManifest("kind: [unterminated"); err == nil {
+		t.Error("Expected an error for invalid YAML")
+	}
+}
+
+func TestTemplateFromApplicationWithOverrides(t *testing.T) {
+	installFakeHelm(t)
+	yamlContent, err := os.ReadFile("examples/helm/app.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read application: %v", err)
+	}
+
+	var source *v1alpha1.ApplicationSource
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		source = q.ApplicationSource
+		return &apiclient.ManifestResponse{}, nil
+	})
+
+	_, err = TemplateFromApplicationWithOverrides(context.Background(), string(yamlContent), map[string]interface{}{
+		"spec.source.targetRevision":   "feature-branch",
+		"spec.source.helm.releaseName": "preview",
+	}, ".")
+	if err != nil {
+		t.Fatalf("TemplateFromApplicationWithOverrides failed: %v", err)
+	}
+
+	if source.TargetRevision != "feature-branch" {
+		t.Errorf("Expected the overridden target revision, got %q", source.TargetRevision)
+	}
+	if source.Helm.ReleaseName != "preview" {
+		t.Errorf("Expected the overridden release name, got %q", source.Helm.ReleaseName)
+	}
+	if len(source.Helm.Parameters) != 2 {
+		t.Errorf("Expected the other Helm fields to be preserved, got %v", source.Helm.Parameters)
+	}
+
+	_, err = TemplateFromApplicationWithOverrides(context.Background(), string(yamlContent), map[string]interface{}{
+		"spec.source.path.nested": "value",
+	}, ".")
+	if err == nil || !strings.Contains(err.Error(), "failed to override spec.source.path.nested") {
+		t.Errorf("Expected an error when overriding a field inside a string, got %v", err)
+	}
+}
+
+func TestParseApplication(t *testing.T) {
+	app, err := ParseApplication([]byte(`apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+spec:
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: guestbook
+`))
+	if err != nil {
+		t.Fatalf("ParseApplication failed: %v", err)
+	}
+	if app.Name != "guestbook" || app.Spec.GetSource().Path != "guestbook" {
+		t.Errorf("Unexpected application: %+v", app)
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{
+			name:     "invalid YAML",
+			data:     "kind: [Application",
+			expected: "failed to parse Application YAML",
+		},
+		{
+			name:     "wrong apiVersion",
+			data:     "apiVersion: argoproj.io/v1beta1\nkind: Application\nmetadata:\n  name: guestbook\n",
+			expected: "expected apiVersion 'argoproj.io/v1alpha1', got 'argoproj.io/v1beta1'",
+		},
+		{
+			name:     "wrong kind",
+			data:     "apiVersion: argoproj.io/v1alpha1\nkind: ApplicationSet\nmetadata:\n  name: guestbook\n",
+			expected: "expected kind 'Application', got 'ApplicationSet'",
+		},
+		{
+			name:     "missing name",
+			data:     "apiVersion: argoproj.io/v1alpha1\nkind: Application\nspec:\n  source:\n    repoURL: https://github.com/myorg/myrepo\n",
+			expected: "metadata.name is required",
+		},
+		{
+			name:     "no sources",
+			data:     "apiVersion: argoproj.io/v1alpha1\nkind: Application\nmetadata:\n  name: guestbook\n",
+			expected: "no sources found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseApplication([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestDetectSourceType(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   v1alpha1.ApplicationSource
+		expected v1alpha1.ApplicationSourceType
+	}{
+		{name: "helm", source: v1alpha1.ApplicationSource{Path: "examples/helm/input"}, expected: v1alpha1.ApplicationSourceTypeHelm},
+		{name: "kustomize", source: v1alpha1.ApplicationSource{Path: "examples/kustomize/input"}, expected: v1alpha1.ApplicationSourceTypeKustomize},
+		{name: "directory", source: v1alpha1.ApplicationSource{Path: "examples/directory/input"}, expected: v1alpha1.ApplicationSourceTypeDirectory},
+		{
+			name:     "explicit directory",
+			source:   v1alpha1.ApplicationSource{Path: "examples/helm/input", Directory: &v1alpha1.ApplicationSourceDirectory{Recurse: true}},
+			expected: v1alpha1.ApplicationSourceTypeDirectory,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceType, err := DetectSourceType(context.Background(), &tt.source, tt.source.Path, ".", "app")
+			if err != nil {
+				t.Fatalf("DetectSourceType failed: %v", err)
+			}
+			if sourceType != tt.expected {
+				t.Errorf("Expected source type %s, got %s", tt.expected, sourceType)
+			}
+		})
+	}
+}
+
+func TestTemplateFromApplicationAllowEmptyRepoURL(t *testing.T) {
+	appFile := writeApplication(t, `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: stub
+spec:
+  source:
+    path: examples/directory/input
+  destination:
+    namespace: default
+`)
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{ApplicationFile: appFile, RepoRoot: "."})
+	if err == nil || !strings.Contains(err.Error(), "source[0].repoURL is required") {
+		t.Errorf("Expected an error for the missing repoURL, got %v", err)
+	}
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{ApplicationFile: appFile, RepoRoot: ".", AllowEmptyRepoURL: true})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if len(result.Objects) != 2 {
+		t.Errorf("Expected the 2 manifests of the directory, got %v", objectKeys(result.Objects))
+	}
+}
@@ -0,0 +1,82 @@
+package renderer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultRepoRootMarker is the file that marks the repository root in addition to .git
+const DefaultRepoRootMarker = ".argocd-repo-root"
+
+// FindRepoRoot walks up from startPath to the first directory containing .git or the marker
+// file and returns its absolute path. An empty marker uses DefaultRepoRootMarker. If no such
+// directory exists, startPath itself is returned.
+func FindRepoRoot(startPath, marker string) (string, error) {
+	if marker == "" {
+		marker = DefaultRepoRootMarker
+	}
+
+	start, err := filepath.Abs(startPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", startPath, err)
+	}
+
+	for dir := start; ; {
+		for _, name := range []string{".git", marker} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir, nil
+			} else if !os.IsNotExist(err) {
+				return "", fmt.Errorf("failed to check for %s in %s: %w", name, dir, err)
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return start, nil
+		}
+		dir = parent
+	}
+}
+
+// discoverRepoRoot returns the repository root of an Application file with the default marker,
+// used when TemplateOptions.RepoRoot is empty. The working directory is used for stdin and
+// Applications that are not read from a file.
+func discoverRepoRoot(applicationFile string) (string, error) {
+	if applicationFile == "" || applicationFile == "-" {
+		return ".", nil
+	}
+
+	root, err := FindRepoRoot(filepath.Dir(applicationFile), "")
+	if err != nil {
+		return "", err
+	}
+	// Keep paths relative when rendering from the repository root
+	if cwd, err := os.Getwd(); err == nil && cwd == root {
+		return ".", nil
+	}
+	return root, nil
+}
+
+// inRepoRoot reports whether the path is the repository root or inside it
+func inRepoRoot(path, repoRoot string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absRoot, err := filepath.Abs(repoRoot)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// sourcePath returns the path of a source, resolving relative paths against the repository root
+func sourcePath(path, repoRoot string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(repoRoot, path)
+}
@@ -0,0 +1,134 @@
+package renderer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindRepoRoot(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "apps", "team", "frontend")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+
+	found, err := FindRepoRoot(nested, "")
+	if err != nil {
+		t.Fatalf("FindRepoRoot failed: %v", err)
+	}
+	if found != root {
+		t.Errorf("Expected %s, got %s", root, found)
+	}
+
+	// A marker file below the git root wins because it is found first
+	if err := os.WriteFile(filepath.Join(root, "apps", "repo.root"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write marker: %v", err)
+	}
+	found, err = FindRepoRoot(nested, "repo.root")
+	if err != nil {
+		t.Fatalf("FindRepoRoot failed: %v", err)
+	}
+	if expected := filepath.Join(root, "apps"); found != expected {
+		t.Errorf("Expected the marker directory %s, got %s", expected, found)
+	}
+}
+
+func TestFindRepoRootFallback(t *testing.T) {
+	start := t.TempDir()
+	if _, err := os.Stat(filepath.Join(filepath.Dir(start), ".git")); err == nil {
+		t.Skip("the temporary directory is inside a git repository")
+	}
+
+	found, err := FindRepoRoot(start, "no-such-marker")
+	if err != nil {
+		t.Fatalf("FindRepoRoot failed: %v", err)
+	}
+	if found != start {
+		t.Errorf("Expected the start directory %s, got %s", start, found)
+	}
+}
+
+// writeNestedRepository writes a git repository with an Application three levels below the
+// root whose source path is relative to the root, and returns the root and the Application file
+func writeNestedRepository(t *testing.T, path string) (string, string) {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"manifests/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: nested\n",
+		"apps/team/prod/app.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: nested
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: ` + path + `
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`,
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+	return root, filepath.Join(root, "apps", "team", "prod", "app.yaml")
+}
+
+func TestTemplateFromApplicationDiscoversRepoRoot(t *testing.T) {
+	_, appFile := writeNestedRepository(t, "manifests")
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{ApplicationFile: appFile})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if len(result.Objects) != 1 || result.Objects[0].GetName() != "nested" {
+		t.Fatalf("Expected the ConfigMap of the repository root, got %d objects", len(result.Objects))
+	}
+	for _, warning := range result.Warnings {
+		if strings.Contains(warning.Message, "outside the repository root") {
+			t.Errorf("Expected no warning for a source inside the root, got %q", warning.Message)
+		}
+	}
+
+	// An explicit RepoRoot takes precedence over the discovered root
+	_, err = TemplateFromApplication(context.Background(), TemplateOptions{ApplicationFile: appFile, RepoRoot: filepath.Dir(appFile)})
+	if err == nil {
+		t.Error("Expected the source path to be resolved against the explicit RepoRoot")
+	}
+}
+
+func TestTemplateFromApplicationSourceOutsideRepoRoot(t *testing.T) {
+	root, appFile := writeNestedRepository(t, "../manifests")
+
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{ApplicationFile: appFile, RepoRoot: filepath.Join(root, "apps")})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if len(result.Objects) != 1 {
+		t.Errorf("Expected the source outside the root to be rendered anyway, got %d objects", len(result.Objects))
+	}
+	var found bool
+	for _, warning := range result.Warnings {
+		if strings.Contains(warning.Message, "source path ../manifests is outside the repository root") {
+			found = warning.Severity == WarningSeverityWarning && warning.SourceIndex == 0
+		}
+	}
+	if !found {
+		t.Errorf("Expected a warning for the source outside the root, got %+v", result.Warnings)
+	}
+}
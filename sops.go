@@ -0,0 +1,55 @@
+package renderer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+)
+
+// decryptSOPSHelmValues decrypts the SOPS encrypted value files of the source in memory, so
+// the plaintext is never written to disk. sops inherits the environment, so keys like
+// SOPS_AGE_KEY_FILE or SOPS_KMS_ARN apply.
+func decryptSOPSHelmValues(ctx context.Context, helm *v1alpha1.ApplicationSourceHelm, files helmValueFiles) error {
+	return transformHelmValueFiles(ctx, helm, files, func(valueFile, path string, data []byte) ([]byte, bool, error) {
+		if !isSOPSEncrypted(data) {
+			return data, false, nil
+		}
+		decrypted, err := runSOPSDecrypt(ctx, path)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to decrypt values file %s: %w", valueFile, err)
+		}
+		return decrypted, true, nil
+	})
+}
+
+// isSOPSEncrypted reports whether the values have the top-level sops key SOPS adds to
+// encrypted files
+func isSOPSEncrypted(data []byte) bool {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return false
+	}
+	_, found := values["sops"]
+	return found
+}
+
+// runSOPSDecrypt returns the decrypted content of the file
+func runSOPSDecrypt(ctx context.Context, path string) ([]byte, error) {
+	if err := checkBinaryExists("sops"); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sops", "--decrypt", path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("sops --decrypt failed: %w\nOutput: %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,168 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+)
+
+// fakeSOPSScript prints the decrypted values and records the key file it was given
+const fakeSOPSScript = `#!/bin/sh
+echo "$SOPS_AGE_KEY_FILE" > "$FAKE_SOPS_LOG"
+printf 'password: decrypted\n'
+`
+
+func TestTemplateFromApplicationSOPSDecrypt(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required for the fake sops binary")
+	}
+	installFakeHelm(t)
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "sops"), []byte(fakeSOPSScript), 0755); err != nil {
+		t.Fatalf("Failed to write fake sops: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	logFile := filepath.Join(t.TempDir(), "sops.log")
+	t.Setenv("FAKE_SOPS_LOG", logFile)
+	t.Setenv("SOPS_AGE_KEY_FILE", "/keys/age.txt")
+
+	repoRoot := t.TempDir()
+	t.Chdir(repoRoot)
+	files := map[string]string{
+		"chart/Chart.yaml":         "apiVersion: v2\nname: secret\nversion: 0.1.0\n",
+		"chart/values.yaml":        "replicaCount: 1\n",
+		"chart/values-secret.yaml": "password: ENC[AES256_GCM,data:abc=,type:str]\nsops:\n  version: 3.9.0\n",
+		"chart/values-prod.yaml":   "replicaCount: 3\nregion: us\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: secret
+spec:
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: chart
+    helm:
+      valueFiles:
+      - values.yaml
+      - values-secret.yaml
+      - values-prod.yaml
+      values: |
+        region: eu
+  destination:
+    namespace: default
+`)
+
+	var valueFiles []string
+	var values map[string]interface{}
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		for _, valueFile := range q.ApplicationSource.Helm.ValueFiles {
+			data, err := os.ReadFile(filepath.Join(appPath, valueFile))
+			if err != nil {
+				return nil, err
+			}
+			valueFiles = append(valueFiles, string(data))
+		}
+		var err error
+		values, err = helmValues(q.ApplicationSource.Helm)
+		if err != nil {
+			return nil, err
+		}
+		return &apiclient.ManifestResponse{}, nil
+	})
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		Helm:            HelmOptions{SOPSDecrypt: true},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	// The value files from the encrypted one on are merged below the inline values
+	if len(valueFiles) != 1 || valueFiles[0] != "replicaCount: 1\n" {
+		t.Errorf("Expected only the plain values file before the encrypted one, got %q", valueFiles)
+	}
+	expected := map[string]interface{}{"password": "decrypted", "replicaCount": float64(3), "region": "eu"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected inline values %v, got %v", expected, values)
+	}
+	if data, err := os.ReadFile(logFile); err != nil || string(data) != "/keys/age.txt\n" {
+		t.Errorf("Expected sops to get SOPS_AGE_KEY_FILE from the environment, got %q, %v", data, err)
+	}
+	entries, err := os.ReadDir("chart")
+	if err != nil || len(entries) != 4 {
+		t.Errorf("Expected no decrypted values to be written to the chart, got %v, %v", entries, err)
+	}
+}
+
+func TestDecryptSOPSHelmValuesKeepsValueFileOrder(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required for the fake sops binary")
+	}
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "sops"), []byte(fakeSOPSScript), 0755); err != nil {
+		t.Fatalf("Failed to write fake sops: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_SOPS_LOG", filepath.Join(t.TempDir(), "sops.log"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "password: remote\nregion: us\n")
+	}))
+	defer server.Close()
+
+	chartPath := t.TempDir()
+	valuesDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(chartPath, "secrets.yaml"), []byte("password: ENC[AES256_GCM,data:abc=,type:str]\nsops:\n  version: 3.9.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write secrets.yaml: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(valuesDir, "override.yaml"), []byte("region: eu\n"), 0644); err != nil {
+		t.Fatalf("Failed to write override.yaml: %v", err)
+	}
+
+	helm := &v1alpha1.ApplicationSourceHelm{
+		ValueFiles: []string{"secrets.yaml", server.URL + "/override.yaml", "$values/override.yaml"},
+	}
+	files := helmValueFiles{
+		chartPath:  chartPath,
+		repoRoot:   t.TempDir(),
+		refs:       map[string]*v1alpha1.RefTarget{"$values": {}},
+		sourceRefs: map[string]string{"values": valuesDir},
+	}
+	if err := decryptSOPSHelmValues(context.Background(), helm, files); err != nil {
+		t.Fatalf("decryptSOPSHelmValues failed: %v", err)
+	}
+
+	// The value files after the encrypted one still override it and each other in order
+	values, err := helmValues(helm)
+	if err != nil {
+		t.Fatalf("Failed to read Helm values: %v", err)
+	}
+	expected := map[string]interface{}{"password": "remote", "region": "eu"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected inline values %v, got %v", expected, values)
+	}
+	if len(helm.ValueFiles) != 0 {
+		t.Errorf("Expected all value files to be merged into the inline values, got %v", helm.ValueFiles)
+	}
+}
@@ -0,0 +1,163 @@
+package renderer
+
+import (
+	"cmp"
+	"sort"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// installOrder is the order Helm installs resources in, see InstallOrder in helm/pkg/releaseutil
+var installOrder = []string{
+	"PriorityClass",
+	"Namespace",
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"SecretList",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleList",
+	"ClusterRoleBinding",
+	"ClusterRoleBindingList",
+	"Role",
+	"RoleList",
+	"RoleBinding",
+	"RoleBindingList",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"IngressClass",
+	"Ingress",
+	"APIService",
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+}
+
+var installOrderIndex = func() map[string]int {
+	index := make(map[string]int, len(installOrder))
+	for i, kind := range installOrder {
+		index[kind] = i
+	}
+	return index
+}()
+
+// SortByInstallOrder returns the objects sorted in the order Helm installs them. Kinds that
+// are not part of the install order come last, sorted by kind. Objects of the same kind are
+// sorted by namespace and name.
+func SortByInstallOrder(objects []*unstructured.Unstructured) []*unstructured.Unstructured {
+	sorted := make([]*unstructured.Unstructured, len(objects))
+	copy(sorted, objects)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareInstallOrder(sorted[i], sorted[j]) < 0
+	})
+
+	return sorted
+}
+
+// SortOrderGVK is the TemplateOptions.SortOrder that sorts the objects with SortManifests
+const SortOrderGVK = "gvk"
+
+// SortManifests sorts the objects in place by group, version, kind, namespace and name. Unlike
+// SortByInstallOrder the order does not depend on the kinds Helm knows, which makes it suited
+// for comparing output.
+func SortManifests(objects []*unstructured.Unstructured) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		return compareManifests(objects[i], objects[j]) < 0
+	})
+}
+
+// compareManifests compares two objects by group, version, kind, namespace and name
+func compareManifests(a, b *unstructured.Unstructured) int {
+	gvkA, gvkB := a.GroupVersionKind(), b.GroupVersionKind()
+	return cmp.Or(
+		cmp.Compare(gvkA.Group, gvkB.Group),
+		cmp.Compare(gvkA.Version, gvkB.Version),
+		cmp.Compare(gvkA.Kind, gvkB.Kind),
+		cmp.Compare(a.GetNamespace(), b.GetNamespace()),
+		cmp.Compare(a.GetName(), b.GetName()),
+	)
+}
+
+// syncWaveAnnotation is the annotation Argo CD orders the resources of a sync by
+const syncWaveAnnotation = "argocd.argoproj.io/sync-wave"
+
+// SortBySyncWave returns the objects sorted by sync wave like Argo CD applies them. Objects
+// in the same wave are sorted in the order Helm installs them, then by namespace and name.
+func SortBySyncWave(objects []*unstructured.Unstructured) []*unstructured.Unstructured {
+	sorted := make([]*unstructured.Unstructured, len(objects))
+	copy(sorted, objects)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		waveA, waveB := syncWave(sorted[i]), syncWave(sorted[j])
+		if waveA != waveB {
+			return waveA < waveB
+		}
+		return compareInstallOrder(sorted[i], sorted[j]) < 0
+	})
+
+	return sorted
+}
+
+// syncWave returns the sync wave of an object. Like Argo CD, a missing or invalid
+// annotation is wave 0.
+func syncWave(obj *unstructured.Unstructured) int {
+	wave, err := strconv.Atoi(obj.GetAnnotations()[syncWaveAnnotation])
+	if err != nil {
+		return 0
+	}
+	return wave
+}
+
+// compareInstallOrder compares two objects by install order, namespace and name
+func compareInstallOrder(a, b *unstructured.Unstructured) int {
+	kindA, kindB := a.GetKind(), b.GetKind()
+	if kindA != kindB {
+		indexA, knownA := installOrderIndex[kindA]
+		indexB, knownB := installOrderIndex[kindB]
+		switch {
+		case knownA && knownB:
+			return indexA - indexB
+		case knownA:
+			return -1
+		case knownB:
+			return 1
+		case kindA < kindB:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	if a.GetNamespace() != b.GetNamespace() {
+		if a.GetNamespace() < b.GetNamespace() {
+			return -1
+		}
+		return 1
+	}
+
+	if a.GetName() < b.GetName() {
+		return -1
+	}
+	if a.GetName() > b.GetName() {
+		return 1
+	}
+	return 0
+}
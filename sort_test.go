@@ -0,0 +1,220 @@
+package renderer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newObject(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func objectKeys(objects []*unstructured.Unstructured) []string {
+	var keys []string
+	for _, obj := range objects {
+		key := obj.GetKind() + "/" + obj.GetName()
+		if obj.GetNamespace() != "" {
+			key = obj.GetKind() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestSortByInstallOrder(t *testing.T) {
+	objects := []*unstructured.Unstructured{
+		newObject("example.com/v1", "Widget", "default", "widget"),
+		newObject("apps/v1", "Deployment", "default", "web"),
+		newObject("v1", "Service", "default", "web"),
+		newObject("rbac.authorization.k8s.io/v1", "RoleBinding", "default", "web"),
+		newObject("apps/v1", "Deployment", "b", "api"),
+		newObject("apps/v1", "Deployment", "a", "worker"),
+		newObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "widgets.example.com"),
+		newObject("example.com/v1", "Gadget", "default", "gadget"),
+		newObject("v1", "Namespace", "", "default"),
+		newObject("v1", "ConfigMap", "default", "config"),
+	}
+
+	sorted := SortByInstallOrder(objects)
+
+	expected := []string{
+		"Namespace/default",
+		"ConfigMap/default/config",
+		"CustomResourceDefinition/widgets.example.com",
+		"RoleBinding/default/web",
+		"Service/default/web",
+		"Deployment/a/worker",
+		"Deployment/b/api",
+		"Deployment/default/web",
+		"Gadget/default/gadget",
+		"Widget/default/widget",
+	}
+
+	keys := objectKeys(sorted)
+	if len(keys) != len(expected) {
+		t.Fatalf("Expected %d objects, got %d", len(expected), len(keys))
+	}
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("Position %d: expected %s, got %s", i, expected[i], keys[i])
+		}
+	}
+
+	if objects[0].GetKind() != "Widget" {
+		t.Error("Expected the input slice to be left unchanged")
+	}
+}
+
+func TestSortBySyncWave(t *testing.T) {
+	withWave := func(obj *unstructured.Unstructured, wave string) *unstructured.Unstructured {
+		obj.SetAnnotations(map[string]string{syncWaveAnnotation: wave})
+		return obj
+	}
+	objects := []*unstructured.Unstructured{
+		withWave(newObject("apps/v1", "Deployment", "default", "web"), "5"),
+		newObject("apps/v1", "Deployment", "default", "api"),
+		withWave(newObject("example.com/v1", "Widget", "default", "widget"), "1"),
+		withWave(newObject("v1", "Service", "default", "web"), "5"),
+		withWave(newObject("v1", "Namespace", "", "default"), "-1"),
+		newObject("v1", "ConfigMap", "default", "config"),
+		withWave(newObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "widgets.example.com"), "0"),
+		withWave(newObject("v1", "ConfigMap", "default", "invalid"), "first"),
+		withWave(newObject("v1", "Secret", "default", "credentials"), "1"),
+	}
+
+	sorted := SortBySyncWave(objects)
+
+	expected := []string{
+		"Namespace/default",
+		"ConfigMap/default/config",
+		"ConfigMap/default/invalid",
+		"CustomResourceDefinition/widgets.example.com",
+		"Deployment/default/api",
+		"Secret/default/credentials",
+		"Widget/default/widget",
+		"Service/default/web",
+		"Deployment/default/web",
+	}
+
+	keys := objectKeys(sorted)
+	if len(keys) != len(expected) {
+		t.Fatalf("Expected %d objects, got %d", len(expected), len(keys))
+	}
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("Position %d: expected %s, got %s", i, expected[i], keys[i])
+		}
+	}
+}
+
+func TestSortManifests(t *testing.T) {
+	objects := []*unstructured.Unstructured{
+		newObject("apps/v1", "Deployment", "default", "web"),
+		newObject("v1", "Service", "default", "web"),
+		newObject("example.com/v1beta1", "Widget", "default", "widget"),
+		newObject("v1", "ConfigMap", "b", "config"),
+		newObject("example.com/v1", "Widget", "default", "widget"),
+		newObject("v1", "ConfigMap", "a", "config"),
+		newObject("apps/v1", "Deployment", "default", "api"),
+	}
+	SortManifests(objects)
+
+	expected := []string{
+		"ConfigMap/a/config",
+		"ConfigMap/b/config",
+		"Service/default/web",
+		"Deployment/default/api",
+		"Deployment/default/web",
+		"Widget/default/widget",
+		"Widget/default/widget",
+	}
+	if !slices.Equal(objectKeys(objects), expected) {
+		t.Errorf("Expected %v, got %v", expected, objectKeys(objects))
+	}
+	if objects[5].GetAPIVersion() != "example.com/v1" {
+		t.Errorf("Expected v1 before v1beta1, got %s", objects[5].GetAPIVersion())
+	}
+}
+
+func TestSortManifestsIdempotent(t *testing.T) {
+	// The small alphabets make equal fields likely, so every comparison step is exercised
+	apiVersions := []string{"v1", "apps/v1", "example.com/v1", "example.com/v1beta1"}
+	kinds := []string{"ConfigMap", "Deployment", "Widget"}
+	names := []string{"", "a", "b"}
+
+	sortTwice := func(fields [][4]uint8) bool {
+		objects := make([]*unstructured.Unstructured, 0, len(fields))
+		for _, f := range fields {
+			objects = append(objects, newObject(
+				apiVersions[int(f[0])%len(apiVersions)],
+				kinds[int(f[1])%len(kinds)],
+				names[int(f[2])%len(names)],
+				names[int(f[3])%len(names)],
+			))
+		}
+
+		SortManifests(objects)
+		once := slices.Clone(objects)
+		SortManifests(objects)
+		return slices.Equal(once, objects)
+	}
+	if err := quick.Check(sortTwice, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTemplateFromApplicationSortOrder(t *testing.T) {
+	repoRoot := t.TempDir()
+	manifests := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n---\napiVersion: v1\nkind: Endpoints\nmetadata:\n  name: web\n"
+	if err := os.WriteFile(filepath.Join(repoRoot, "manifests.yaml"), []byte(manifests), 0644); err != nil {
+		t.Fatalf("Failed to write manifests: %v", err)
+	}
+	appFile := writeApplication(t, `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: sorted
+spec:
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: .
+  destination:
+    namespace: default
+`)
+
+	// Endpoints are not part of the Helm install order, which would put them last
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        repoRoot,
+		SortManifests:   true,
+		SortOrder:       SortOrderGVK,
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	expected := []string{"Endpoints/default/web", "Deployment/default/web"}
+	if !slices.Equal(objectKeys(result.Objects), expected) {
+		t.Errorf("Expected %v, got %v", expected, objectKeys(result.Objects))
+	}
+
+	_, err = TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        repoRoot,
+		SortOrder:       "name",
+	})
+	if err == nil || !strings.Contains(err.Error(), `invalid sort order "name"`) {
+		t.Errorf("Expected an error for an unsupported sort order, got %v", err)
+	}
+}
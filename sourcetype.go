@@ -0,0 +1,25 @@
+package renderer
+
+import (
+	"context"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/reposerver/repository"
+)
+
+// enabledSourceTypes are the source types Argo CD detects from the files of a source
+func enabledSourceTypes() map[string]bool {
+	return map[string]bool{
+		string(v1alpha1.ApplicationSourceTypeHelm):      true,
+		string(v1alpha1.ApplicationSourceTypeKustomize): true,
+		string(v1alpha1.ApplicationSourceTypeDirectory): true,
+	}
+}
+
+// DetectSourceType returns the type Argo CD renders the source as. A type set explicitly in
+// the source wins, otherwise it is detected from the files in appPath, which must be inside
+// repoRoot: a Chart.yaml is Helm, a kustomization file is Kustomize and anything else is a
+// directory of manifests.
+func DetectSourceType(ctx context.Context, source *v1alpha1.ApplicationSource, appPath, repoRoot, appName string) (v1alpha1.ApplicationSourceType, error) {
+	return repository.GetAppSourceType(ctx, source, appPath, repoRoot, appName, enabledSourceTypes(), []string{}, []string{})
+}
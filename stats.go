@@ -0,0 +1,39 @@
+package renderer
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ComputeStats returns the number of objects of each kind
+func ComputeStats(objects []*unstructured.Unstructured) map[string]int {
+	stats := make(map[string]int)
+	for _, obj := range objects {
+		stats[obj.GetKind()]++
+	}
+	return stats
+}
+
+// FormatStats writes the stats as a KIND and COUNT table, sorted by count in descending
+// order and then by kind
+func FormatStats(stats map[string]int, w io.Writer) {
+	kinds := slices.SortedFunc(maps.Keys(stats), func(a, b string) int {
+		if c := cmp.Compare(stats[b], stats[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tCOUNT")
+	for _, kind := range kinds {
+		fmt.Fprintf(tw, "%s\t%d\n", kind, stats[kind])
+	}
+	tw.Flush()
+}
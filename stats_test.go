@@ -0,0 +1,33 @@
+package renderer
+
+import (
+	"bytes"
+	"maps"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestComputeStats(t *testing.T) {
+	objects := []*unstructured.Unstructured{
+		newObject("apps/v1", "Deployment", "default", "web"),
+		newObject("v1", "Service", "default", "web"),
+		newObject("apps/v1", "Deployment", "default", "api"),
+		newObject("v1", "Service", "default", "api"),
+		newObject("apps/v1", "Deployment", "default", "worker"),
+	}
+
+	stats := ComputeStats(objects)
+	expected := map[string]int{"Deployment": 3, "Service": 2}
+	if !maps.Equal(stats, expected) {
+		t.Errorf("Expected stats %v, got %v", expected, stats)
+	}
+
+	stats["ConfigMap"] = 2
+	var buf bytes.Buffer
+	FormatStats(stats, &buf)
+	expectedTable := "KIND         COUNT\nDeployment   3\nConfigMap    2\nService      2\n"
+	if buf.String() != expectedTable {
+		t.Errorf("Expected table:\n%s\ngot:\n%s", expectedTable, buf.String())
+	}
+}
@@ -0,0 +1,37 @@
+package renderer
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// StripOptions selects the fields set by the cluster that are removed from rendered resources
+type StripOptions struct {
+	ManagedFields     bool
+	ResourceVersion   bool
+	UID               bool
+	Generation        bool
+	CreationTimestamp bool
+	Status            bool
+}
+
+// stripClusterFields removes the selected fields from the object
+func stripClusterFields(obj *unstructured.Unstructured, opts StripOptions) {
+	if opts.ManagedFields {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
+	}
+	if opts.ResourceVersion {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "resourceVersion")
+	}
+	if opts.UID {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "uid")
+	}
+	if opts.Generation {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "generation")
+	}
+	if opts.CreationTimestamp {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "creationTimestamp")
+	}
+	if opts.Status {
+		unstructured.RemoveNestedField(obj.Object, "status")
+	}
+}
@@ -0,0 +1,51 @@
+package renderer
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestStripClusterFields(t *testing.T) {
+	newClusterObject := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":              "config",
+				"managedFields":     []interface{}{map[string]interface{}{"manager": "kubectl"}},
+				"resourceVersion":   "12345",
+				"uid":               "0b6f8a2e-1d2c-4f3b-9a7e-000000000000",
+				"generation":        int64(3),
+				"creationTimestamp": "2024-01-01T00:00:00Z",
+			},
+			"status": map[string]interface{}{"phase": "Active"},
+		}}
+	}
+
+	obj := newClusterObject()
+	stripClusterFields(obj, StripOptions{ManagedFields: true})
+	if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "metadata", "managedFields"); found {
+		t.Error("Expected managedFields to be removed")
+	}
+	if obj.GetResourceVersion() != "12345" {
+		t.Error("Expected resourceVersion to be kept")
+	}
+
+	obj = newClusterObject()
+	stripClusterFields(obj, StripOptions{
+		ManagedFields:     true,
+		ResourceVersion:   true,
+		UID:               true,
+		Generation:        true,
+		CreationTimestamp: true,
+		Status:            true,
+	})
+	metadata := obj.Object["metadata"].(map[string]interface{})
+	if len(metadata) != 1 || metadata["name"] != "config" {
+		t.Errorf("Expected only the name to remain in metadata, got %v", metadata)
+	}
+	if _, found := obj.Object["status"]; found {
+		t.Error("Expected status to be removed")
+	}
+}
@@ -0,0 +1,112 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ValidateOptions contains options for validating rendered manifests
+type ValidateOptions struct {
+	// SchemaDir is a directory of JSON schemas using the yannh/kubernetes-json-schema
+	// layout, e.g. deployment-apps-v1.json. Resources without a schema are not validated.
+	SchemaDir string
+}
+
+// ValidationError describes a resource that does not match its schema
+type ValidationError struct {
+	Group     string
+	Version   string
+	Kind      string
+	Namespace string
+	Name      string
+	Message   string
+}
+
+func (e ValidationError) Error() string {
+	gv := e.Version
+	if e.Group != "" {
+		gv = e.Group + "/" + e.Version
+	}
+
+	name := e.Name
+	if e.Namespace != "" {
+		name = e.Namespace + "/" + e.Name
+	}
+
+	return fmt.Sprintf("%s %s %s is invalid: %s", gv, e.Kind, name, e.Message)
+}
+
+// ValidateManifests validates the objects against the JSON schemas in the schema directory
+func ValidateManifests(objects []*unstructured.Unstructured, opts ValidateOptions) []ValidationError {
+	var validationErrors []ValidationError
+	compiler := jsonschema.NewCompiler()
+	schemas := map[string]*jsonschema.Schema{}
+
+	for _, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		newError := func(format string, args ...interface{}) ValidationError {
+			return ValidationError{
+				Group:     gvk.Group,
+				Version:   gvk.Version,
+				Kind:      gvk.Kind,
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				Message:   fmt.Sprintf(format, args...),
+			}
+		}
+
+		schemaPath := filepath.Join(opts.SchemaDir, schemaFileName(gvk.Group, gvk.Version, gvk.Kind))
+		schema, found := schemas[schemaPath]
+		if !found {
+			if _, err := os.Stat(schemaPath); err != nil {
+				schemas[schemaPath] = nil
+				continue
+			}
+
+			var err error
+			schema, err = compiler.Compile(schemaPath)
+			if err != nil {
+				validationErrors = append(validationErrors, newError("failed to compile schema %s: %v", schemaPath, err))
+				continue
+			}
+			schemas[schemaPath] = schema
+		}
+		if schema == nil {
+			continue
+		}
+
+		data, err := json.Marshal(obj.Object)
+		if err != nil {
+			validationErrors = append(validationErrors, newError("failed to marshal resource: %v", err))
+			continue
+		}
+		instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+		if err != nil {
+			validationErrors = append(validationErrors, newError("failed to parse resource: %v", err))
+			continue
+		}
+
+		if err := schema.Validate(instance); err != nil {
+			validationErrors = append(validationErrors, newError("%v", err))
+		}
+	}
+
+	return validationErrors
+}
+
+// schemaFileName returns the file name of the schema for the given resource type,
+// e.g. deployment-apps-v1.json or service-v1.json
+func schemaFileName(group, version, kind string) string {
+	name := strings.ToLower(kind)
+	if group != "" {
+		name += "-" + strings.ToLower(strings.Split(group, ".")[0])
+	}
+	return name + "-" + strings.ToLower(version) + ".json"
+}
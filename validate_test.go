@@ -0,0 +1,145 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const deploymentSchema = `{
+  "type": "object",
+  "properties": {
+    "apiVersion": {"type": "string"},
+    "kind": {"type": "string"},
+    "metadata": {"type": "object"},
+    "spec": {
+      "type": "object",
+      "properties": {
+        "replicas": {"type": "integer"}
+      }
+    }
+  },
+  "additionalProperties": false
+}`
+
+func TestValidateManifests(t *testing.T) {
+	schemaDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(schemaDir, "deployment-apps-v1.json"), []byte(deploymentSchema), 0644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	valid := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "valid"},
+		"spec":       map[string]interface{}{"replicas": int64(2)},
+	}}
+	typo := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "typo", "namespace": "default"},
+		"specc":      map[string]interface{}{"replicas": int64(2)},
+	}}
+	withoutSchema := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   map[string]interface{}{"name": "unknown"},
+		"specc":      map[string]interface{}{},
+	}}
+
+	errs := ValidateManifests([]*unstructured.Unstructured{valid, typo, withoutSchema}, ValidateOptions{SchemaDir: schemaDir})
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 validation error, got %d: %v", len(errs), errs)
+	}
+
+	if errs[0].Kind != "Deployment" || errs[0].Name != "typo" || errs[0].Group != "apps" || errs[0].Version != "v1" {
+		t.Errorf("Unexpected resource in validation error: %+v", errs[0])
+	}
+	if !strings.Contains(errs[0].Message, "specc") {
+		t.Errorf("Expected message to mention the invalid field, got: %s", errs[0].Message)
+	}
+	if !strings.HasPrefix(errs[0].Error(), "apps/v1 Deployment default/typo is invalid") {
+		t.Errorf("Unexpected error string: %s", errs[0].Error())
+	}
+}
+
+func TestSchemaFileName(t *testing.T) {
+	tests := map[string][3]string{
+		"deployment-apps-v1.json":    {"apps", "v1", "Deployment"},
+		"service-v1.json":            {"", "v1", "Service"},
+		"ingress-networking-v1.json": {"networking.k8s.io", "v1", "Ingress"},
+		"cronjob-batch-v1beta1.json": {"batch", "v1beta1", "CronJob"},
+	}
+
+	for expected, gvk := range tests {
+		if got := schemaFileName(gvk[0], gvk[1], gvk[2]); got != expected {
+			t.Errorf("Expected %s, got %s", expected, got)
+		}
+	}
+}
+
+const strictDeploymentSchema = `{
+  "type": "object",
+  "properties": {
+    "apiVersion": {"type": "string"},
+    "kind": {"type": "string"},
+    "metadata": {"type": "object"},
+    "spec": {
+      "type": "object",
+      "properties": {
+        "replicas": {"type": "integer"}
+      },
+      "additionalProperties": false
+    }
+  }
+}`
+
+func TestValidateWithKubeconform(t *testing.T) {
+	schemaDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(schemaDir, "deployment-apps-v1.json"), []byte(strictDeploymentSchema), 0644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	valid := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "valid"},
+		"spec":       map[string]interface{}{"replicas": int64(2)},
+	}}
+	typo := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "typo", "namespace": "default"},
+		"spec":       map[string]interface{}{"replicass": int64(2)},
+	}}
+	withoutSchema := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   map[string]interface{}{"name": "unknown"},
+	}}
+
+	opts := KubeconformOptions{
+		Strict:               true,
+		IgnoreMissingSchemas: true,
+		SchemaLocations:      []string{filepath.Join(schemaDir, "{{ .ResourceKind }}{{ .KindSuffix }}.json")},
+	}
+	errs := ValidateWithKubeconform([]*unstructured.Unstructured{valid, typo, withoutSchema}, opts)
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 validation error, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Kind != "Deployment" || errs[0].Name != "typo" || errs[0].Namespace != "default" {
+		t.Errorf("Unexpected resource in validation error: %+v", errs[0])
+	}
+	if !strings.Contains(errs[0].Message, "replicass") {
+		t.Errorf("Expected message to mention the invalid field, got: %s", errs[0].Message)
+	}
+
+	opts.IgnoreMissingSchemas = false
+	errs = ValidateWithKubeconform([]*unstructured.Unstructured{withoutSchema}, opts)
+	if len(errs) != 1 || errs[0].Kind != "Service" {
+		t.Errorf("Expected an error for the resource without a schema, got: %v", errs)
+	}
+}
@@ -0,0 +1,84 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SecretRef is a key of a Kubernetes Secret containing Helm values
+type SecretRef struct {
+	Name      string
+	Namespace string
+	Key       string
+	// KubeconfigPath is the kubeconfig used to read the Secret, the default loading rules
+	// are used if empty
+	KubeconfigPath string
+}
+
+// ConfigMapRef is a key of a Kubernetes ConfigMap containing Helm values
+type ConfigMapRef struct {
+	Name      string
+	Namespace string
+	Key       string
+	// KubeconfigPath is the kubeconfig used to read the ConfigMap, the default loading rules
+	// are used if empty
+	KubeconfigPath string
+}
+
+// newKubernetesClient returns a client for the cluster of the kubeconfig, replaceable in tests
+var newKubernetesClient = func(kubeconfigPath string) (kubernetes.Interface, error) {
+	config, err := loadKubeconfig(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	return client, nil
+}
+
+// fetchValueSecrets returns the Helm values stored in the keys of the Secrets, in order
+func fetchValueSecrets(ctx context.Context, refs []SecretRef) ([][]byte, error) {
+	var documents [][]byte
+	for _, ref := range refs {
+		client, err := newKubernetesClient(ref.KubeconfigPath)
+		if err != nil {
+			return nil, err
+		}
+		secret, err := client.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get values secret %s/%s: %w", ref.Namespace, ref.Name, err)
+		}
+		data, found := secret.Data[ref.Key]
+		if !found {
+			return nil, fmt.Errorf("values secret %s/%s has no key %s", ref.Namespace, ref.Name, ref.Key)
+		}
+		documents = append(documents, data)
+	}
+	return documents, nil
+}
+
+// fetchValueConfigMaps returns the Helm values stored in the keys of the ConfigMaps, in order
+func fetchValueConfigMaps(ctx context.Context, refs []ConfigMapRef) ([][]byte, error) {
+	var documents [][]byte
+	for _, ref := range refs {
+		client, err := newKubernetesClient(ref.KubeconfigPath)
+		if err != nil {
+			return nil, err
+		}
+		configMap, err := client.CoreV1().ConfigMaps(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get values configmap %s/%s: %w", ref.Namespace, ref.Name, err)
+		}
+		data, found := configMap.Data[ref.Key]
+		if !found {
+			return nil, fmt.Errorf("values configmap %s/%s has no key %s", ref.Namespace, ref.Name, ref.Key)
+		}
+		documents = append(documents, []byte(data))
+	}
+	return documents, nil
+}
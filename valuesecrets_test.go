@@ -0,0 +1,275 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+)
+
+func TestTemplateFromApplicationValueSecrets(t *testing.T) {
+	installFakeHelm(t)
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "helm-values", Namespace: "team"},
+		Data:       map[string][]byte{"values.yaml": []byte("replicaCount: 7\nimage:\n  tag: \"1.25\"\n")},
+	})
+	originalClient := newKubernetesClient
+	t.Cleanup(func() { newKubernetesClient = originalClient })
+	var kubeconfigPaths []string
+	newKubernetesClient = func(kubeconfigPath string) (kubernetes.Interface, error) {
+		kubeconfigPaths = append(kubeconfigPaths, kubeconfigPath)
+		return client, nil
+	}
+
+	var values string
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		values = string(q.ApplicationSource.Helm.ValuesYAML())
+		return &apiclient.ManifestResponse{}, nil
+	})
+
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/helm/app.yaml",
+		RepoRoot:        ".",
+		ValueSecrets:    []SecretRef{{Name: "helm-values", Namespace: "team", Key: "values.yaml", KubeconfigPath: "/tmp/kubeconfig"}},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	if !strings.Contains(values, "replicaCount: 7") || !strings.Contains(values, `tag: "1.25"`) {
+		t.Errorf("Expected the values of the secret, got:\n%s", values)
+	}
+	if len(kubeconfigPaths) != 1 || kubeconfigPaths[0] != "/tmp/kubeconfig" {
+		t.Errorf("Expected one client for the kubeconfig, got %v", kubeconfigPaths)
+	}
+
+	_, err = TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/helm/app.yaml",
+		RepoRoot:        ".",
+		ValueSecrets:    []SecretRef{{Name: "helm-values", Namespace: "team", Key: "missing.yaml"}},
+	})
+	renderErr, ok := AsRenderError(err)
+	if !ok || renderErr.Phase != RenderPhaseFetch || !strings.Contains(err.Error(), "has no key missing.yaml") {
+		t.Errorf("Expected a fetch error for the missing key, got %v", err)
+	}
+}
+
+func TestTemplateFromApplicationValueSecretsPrecedence(t *testing.T) {
+	installFakeHelm(t)
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "helm-values", Namespace: "team"},
+		Data:       map[string][]byte{"values.yaml": []byte("replicaCount: 7\nregion: us\npassword: secret\n")},
+	})
+	originalClient := newKubernetesClient
+	t.Cleanup(func() { newKubernetesClient = originalClient })
+	newKubernetesClient = func(kubeconfigPath string) (kubernetes.Interface, error) {
+		return client, nil
+	}
+
+	repoRoot := t.TempDir()
+	t.Chdir(repoRoot)
+	files := map[string]string{
+		"chart/Chart.yaml":       "apiVersion: v2\nname: secrets\nversion: 0.1.0\n",
+		"chart/values-prod.yaml": "replicaCount: 3\nregion: eu\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	var valueFiles []string
+	var values map[string]interface{}
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		valueFiles = q.ApplicationSource.Helm.ValueFiles
+		var err error
+		values, err = helmValues(q.ApplicationSource.Helm)
+		return &apiclient.ManifestResponse{}, err
+	})
+
+	appFile := writeApplication(t, `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: secrets
+spec:
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: chart
+    helm:
+      valueFiles:
+      - values-prod.yaml
+      valuesObject:
+        region: ap
+  destination:
+    namespace: default
+`)
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		ValueSecrets:    []SecretRef{{Name: "helm-values", Namespace: "team", Key: "values.yaml"}},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	// The Secret values override the value files, the inline values override the Secret values
+	if len(valueFiles) != 1 || valueFiles[0] != "values-prod.yaml" {
+		t.Errorf("Expected the value files to be left in place, got %v", valueFiles)
+	}
+	expected := map[string]interface{}{"replicaCount": float64(7), "region": "ap", "password": "secret"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected inline values %v, got %v", expected, values)
+	}
+}
+
+func TestTemplateFromApplicationValueConfigMaps(t *testing.T) {
+	installFakeHelm(t)
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "helm-defaults", Namespace: "team"},
+		Data:       map[string]string{"values.yaml": "replicaCount: 7\nimage:\n  tag: \"1.25\"\n  pullPolicy: Always\n"},
+	})
+	originalClient := newKubernetesClient
+	t.Cleanup(func() { newKubernetesClient = originalClient })
+	newKubernetesClient = func(kubeconfigPath string) (kubernetes.Interface, error) {
+		return client, nil
+	}
+
+	var values string
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		values = string(q.ApplicationSource.Helm.ValuesYAML())
+		return &apiclient.ManifestResponse{}, nil
+	})
+
+	appFile := writeApplication(t, `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: helm-example-app
+spec:
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: examples/helm/input
+    helm:
+      valuesObject:
+        image:
+          tag: "1.21"
+  destination:
+    namespace: helm-namespace
+`)
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		ValueConfigMaps: []ConfigMapRef{{Name: "helm-defaults", Namespace: "team", Key: "values.yaml"}},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	// The values of the Application take precedence over the values of the ConfigMap
+	for _, expected := range []string{"replicaCount: 7", `tag: "1.21"`, "pullPolicy: Always"} {
+		if !strings.Contains(values, expected) {
+			t.Errorf("Expected %q in the values, got:\n%s", expected, values)
+		}
+	}
+
+	_, err = TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		ValueConfigMaps: []ConfigMapRef{{Name: "missing", Namespace: "team", Key: "values.yaml"}},
+	})
+	renderErr, ok := AsRenderError(err)
+	if !ok || renderErr.Phase != RenderPhaseFetch || !strings.Contains(err.Error(), "failed to get values configmap team/missing") {
+		t.Errorf("Expected a fetch error for the missing configmap, got %v", err)
+	}
+}
+
+func TestTemplateFromApplicationValueConfigMapsUnderValueFiles(t *testing.T) {
+	installFakeHelm(t)
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "helm-defaults", Namespace: "team"},
+		Data:       map[string]string{"values.yaml": "replicaCount: 7\npullPolicy: Always\nregion: us\ntier: gold\n"},
+	})
+	originalClient := newKubernetesClient
+	t.Cleanup(func() { newKubernetesClient = originalClient })
+	newKubernetesClient = func(kubeconfigPath string) (kubernetes.Interface, error) {
+		return client, nil
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "region: eu\n")
+	}))
+	defer server.Close()
+
+	repoRoot := t.TempDir()
+	t.Chdir(repoRoot)
+	files := map[string]string{
+		"chart/Chart.yaml":       "apiVersion: v2\nname: defaults\nversion: 0.1.0\n",
+		"chart/values-prod.yaml": "replicaCount: 3\n",
+		"env/values.yaml":        "tier: silver\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(repoRoot, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	var valueFiles []string
+	var values map[string]interface{}
+	stubGenerateManifests(t, func(appPath string, q *apiclient.ManifestRequest) (*apiclient.ManifestResponse, error) {
+		valueFiles = q.ApplicationSource.Helm.ValueFiles
+		var err error
+		values, err = helmValues(q.ApplicationSource.Helm)
+		return &apiclient.ManifestResponse{}, err
+	})
+
+	appFile := writeApplication(t, `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: defaults
+spec:
+  sources:
+  - repoURL: https://github.com/myorg/myrepo
+    path: chart
+    helm:
+      valueFiles:
+      - values-prod.yaml
+      - `+server.URL+`/values.yaml
+      - $values/env/values.yaml
+  - repoURL: https://github.com/myorg/myrepo
+    ref: values
+  destination:
+    namespace: default
+`)
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: appFile,
+		RepoRoot:        ".",
+		ValueConfigMaps: []ConfigMapRef{{Name: "helm-defaults", Namespace: "team", Key: "values.yaml"}},
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+	// The local, remote and $ref value files of the Application are left in place and take
+	// precedence over the values of the ConfigMap
+	expectedValueFiles := []string{"values-prod.yaml", server.URL + "/values.yaml", "$values/env/values.yaml"}
+	if !reflect.DeepEqual(valueFiles, expectedValueFiles) {
+		t.Errorf("Expected value files %v, got %v", expectedValueFiles, valueFiles)
+	}
+	expected := map[string]interface{}{"pullPolicy": "Always"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected only the ConfigMap values no value file sets, got %v", values)
+	}
+}
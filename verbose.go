@@ -0,0 +1,138 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+)
+
+// verboseWriter returns the writer verbose output is written to
+func (opts TemplateOptions) verboseWriter() io.Writer {
+	if opts.VerboseOutput != nil {
+		return opts.VerboseOutput
+	}
+	return os.Stderr
+}
+
+// logger returns the logger the events are passed to, or nil if they are not logged
+func (opts TemplateOptions) logger() Logger {
+	if opts.Logger != nil {
+		return opts.Logger
+	}
+	if opts.Verbose {
+		return StderrLogger{Output: opts.VerboseOutput}
+	}
+	return nil
+}
+
+// equivalentCommand returns the command line that renders the source like Argo CD does
+func equivalentCommand(ctx context.Context, appSourceType v1alpha1.ApplicationSourceType, appPath string, q *apiclient.ManifestRequest, opts TemplateOptions) string {
+	source := q.ApplicationSource
+	switch appSourceType {
+	case v1alpha1.ApplicationSourceTypeHelm:
+		return strings.Join(helmTemplateCommand(appPath, q, opts), " ")
+	case v1alpha1.ApplicationSourceTypeKustomize:
+		// The same options as the real build, which goes through the temporary overlay
+		kustomizeOpts, _ := checkReorderSupported(ctx, opts.Kustomize)
+		args := append([]string{"kustomize", "build", appPath}, buildKustomizeArgs(kustomizeOpts, true)...)
+		return strings.Join(args, " ")
+	case v1alpha1.ApplicationSourceTypePlugin:
+		if source.Plugin != nil {
+			return pluginBinaryPrefix + source.Plugin.Name
+		}
+	case v1alpha1.ApplicationSourceTypeDirectory:
+		directory := source.Directory
+		if directory == nil {
+			directory = &v1alpha1.ApplicationSourceDirectory{}
+		}
+		include, exclude := directory.Include, directory.Exclude
+		if opts.Directory.Include != "" {
+			include = opts.Directory.Include
+		}
+		if opts.Directory.Exclude != "" {
+			exclude = opts.Directory.Exclude
+		}
+		return fmt.Sprintf("read manifests from %s (recurse=%t, include=%q, exclude=%q)", appPath, directory.Recurse || opts.Directory.Recurse, include, exclude)
+	}
+	return ""
+}
+
+// helmTemplateCommand returns the helm template invocation of a source, including the
+// overrides of the options
+func helmTemplateCommand(appPath string, q *apiclient.ManifestRequest, opts TemplateOptions) []string {
+	args := []string{opts.Helm.helmBinary(), "template", appPath}
+	releaseName := q.AppName
+	helm := q.ApplicationSource.Helm
+	if helm != nil && helm.ReleaseName != "" {
+		releaseName = helm.ReleaseName
+	}
+	if opts.Helm.GenerateName {
+		args = append(args, "--generate-name")
+	} else if releaseName != "" {
+		args = append(args, "--name-template", releaseName)
+	}
+	if q.Namespace != "" {
+		args = append(args, "--namespace", q.Namespace)
+	}
+	if q.KubeVersion != "" {
+		args = append(args, "--kube-version", q.KubeVersion)
+	}
+
+	if helm == nil || !helm.SkipCrds || opts.Helm.IncludeCrds {
+		args = append(args, "--include-crds")
+	}
+	if helm != nil {
+		for _, apiVersion := range helm.APIVersions {
+			args = append(args, "--api-versions", apiVersion)
+		}
+		for _, valueFile := range helm.ValueFiles {
+			args = append(args, "--values", valueFile)
+		}
+		if !helm.ValuesIsEmpty() {
+			args = append(args, "--values", "<inline values>")
+		}
+		for _, param := range helm.Parameters {
+			flag := "--set"
+			if param.ForceString || opts.Helm.ForceStringParameters {
+				flag = "--set-string"
+			}
+			args = append(args, flag, param.Name+"="+param.Value)
+		}
+		for _, param := range helm.FileParameters {
+			args = append(args, "--set-file", param.Name+"="+param.Path)
+		}
+	}
+
+	for _, apiVersion := range opts.Helm.APIVersions {
+		args = append(args, "--api-versions", apiVersion)
+	}
+	for _, valueFile := range opts.Helm.ExtraValueFiles {
+		args = append(args, "--values", valueFile)
+	}
+	for _, param := range opts.Helm.ExtraFileParameters {
+		args = append(args, "--set-file", param.Name+"="+param.Path)
+	}
+	for _, param := range opts.Helm.Parameters {
+		flag := "--set"
+		switch {
+		case param.ForceJSON:
+			flag = "--set-json"
+		case param.ForceLiteral:
+			flag = "--set-literal"
+		case param.ForceString || opts.Helm.ForceStringParameters:
+			flag = "--set-string"
+		}
+		args = append(args, flag, param.Name+"="+param.Value)
+	}
+
+	if opts.Helm.PostRenderer != "" {
+		args = append(args, "|", opts.Helm.PostRenderer)
+		args = append(args, opts.Helm.PostRendererArgs...)
+	}
+	return args
+}
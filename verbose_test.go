@@ -0,0 +1,122 @@
+package renderer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+)
+
+func TestTemplateFromApplicationVerbose(t *testing.T) {
+	var output bytes.Buffer
+	_, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/directory/app.yaml",
+		RepoRoot:        ".",
+		Verbose:         true,
+		VerboseOutput:   &output,
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	for _, expected := range []string{
+		"Source 1:\n",
+		"  Source Type: Directory\n",
+		"  Path: examples/directory/input\n",
+		"  Command: read manifests from examples/directory/input",
+	} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("Expected verbose output to contain %q, got:\n%s", expected, output.String())
+		}
+	}
+}
+
+func TestHelmTemplateCommand(t *testing.T) {
+	q := &apiclient.ManifestRequest{
+		AppName:     "guestbook",
+		Namespace:   "default",
+		KubeVersion: "1.29",
+		ApplicationSource: &v1alpha1.ApplicationSource{
+			Helm: &v1alpha1.ApplicationSourceHelm{
+				ValueFiles: []string{"values-prod.yaml"},
+				Parameters: []v1alpha1.HelmParameter{{Name: "replicaCount", Value: "2"}},
+			},
+		},
+	}
+	opts := TemplateOptions{
+		Helm: HelmOptions{
+			ExtraValueFiles: []string{"local.yaml"},
+			Parameters: []HelmParameter{
+				{Name: "image.tag", Value: "dev"},
+				{Name: "config", Value: `{"a":1}`, ForceJSON: true},
+			},
+			PostRenderer: "./post-render.sh",
+		},
+	}
+
+	expected := "helm template charts/guestbook --name-template guestbook --namespace default --kube-version 1.29 --include-crds " +
+		"--values values-prod.yaml --set replicaCount=2 --values local.yaml --set image.tag=dev --set-json config={\"a\":1} | ./post-render.sh"
+	if got := strings.Join(helmTemplateCommand("charts/guestbook", q, opts), " "); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestHelmTemplateCommandGenerateName(t *testing.T) {
+	q := &apiclient.ManifestRequest{
+		AppName:           "guestbook",
+		Namespace:         "default",
+		ApplicationSource: &v1alpha1.ApplicationSource{Helm: &v1alpha1.ApplicationSourceHelm{ReleaseName: "guestbook-release"}},
+	}
+
+	args := helmTemplateCommand("charts/guestbook", q, TemplateOptions{Helm: HelmOptions{GenerateName: true}})
+	expected := "helm template charts/guestbook --generate-name --namespace default --include-crds"
+	if got := strings.Join(args, " "); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestTemplateFromApplicationDurations(t *testing.T) {
+	var output bytes.Buffer
+	result, err := TemplateFromApplication(context.Background(), TemplateOptions{
+		ApplicationFile: "examples/directory/app.yaml",
+		RepoRoot:        ".",
+		Verbose:         true,
+		VerboseOutput:   &output,
+	})
+	if err != nil {
+		t.Fatalf("TemplateFromApplication failed: %v", err)
+	}
+
+	if result.TotalDuration <= 0 {
+		t.Errorf("Expected a positive total duration, got %s", result.TotalDuration)
+	}
+	if len(result.SourceDurations) != 1 || result.SourceDurations[0] <= 0 {
+		t.Errorf("Expected a positive duration for the source, got %v", result.SourceDurations)
+	}
+	if result.SourceDurations[0] > result.TotalDuration {
+		t.Errorf("Expected the source duration %s to be part of the total duration %s", result.SourceDurations[0], result.TotalDuration)
+	}
+	if !strings.Contains(output.String(), "Source 1 (Directory) rendered in ") {
+		t.Errorf("Expected the render time in the verbose output, got:\n%s", output.String())
+	}
+}
+
+func TestEquivalentCommandKustomize(t *testing.T) {
+	original := kustomizeVersion
+	t.Cleanup(func() { kustomizeVersion = original })
+	kustomizeVersion = func(ctx context.Context) (string, error) {
+		return "v4.5.7\n", nil
+	}
+
+	q := &apiclient.ManifestRequest{ApplicationSource: &v1alpha1.ApplicationSource{Path: "overlays/prod"}}
+	opts := TemplateOptions{Kustomize: KustomizeOptions{Reorder: "none", EnableHelm: true}}
+
+	// Like the real build, the reorder option is dropped for kustomize v4 and the overlay lifts the load restriction
+	expected := "kustomize build overlays/prod --load-restrictor LoadRestrictionsNone --enable-helm"
+	if got := equivalentCommand(context.Background(), v1alpha1.ApplicationSourceTypeKustomize, "overlays/prod", q, opts); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
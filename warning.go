@@ -0,0 +1,90 @@
+package renderer
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kubeutil "github.com/argoproj/gitops-engine/pkg/utils/kube"
+)
+
+// Severities of a Warning
+const (
+	// WarningSeverityInfo is output of a tool that does not indicate a problem
+	WarningSeverityInfo = "info"
+	// WarningSeverityWarning is a possible problem that did not prevent rendering
+	WarningSeverityWarning = "warning"
+	// WarningSeverityError is a problem with the rendered manifests, e.g. an invalid resource
+	WarningSeverityError = "error"
+)
+
+// Warning is a problem found while rendering that did not make rendering fail
+type Warning struct {
+	// Severity is one of the WarningSeverity constants
+	Severity string
+	Message  string
+	// SourceIndex is the zero-based index of the source the warning is about, or -1 if the
+	// warning is not specific to a source
+	SourceIndex int
+	// ResourceRef identifies the resource the warning is about as group/version/kind/namespace/name,
+	// without the group of core resources and the namespace of cluster-scoped resources. It is
+	// empty if the warning is not about a single resource.
+	ResourceRef string
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+// WarningStrings returns the messages of the warnings
+func (r *TemplateResult) WarningStrings() []string {
+	return warningMessages(r.Warnings)
+}
+
+// warningMessages returns the messages of the warnings
+func warningMessages(warnings []Warning) []string {
+	var messages []string
+	for _, warning := range warnings {
+		messages = append(messages, warning.Message)
+	}
+	return messages
+}
+
+// newWarnings returns a warning of the given severity for each message
+func newWarnings(severity string, sourceIndex int, messages []string) []Warning {
+	var warnings []Warning
+	for _, message := range messages {
+		warnings = append(warnings, Warning{Severity: severity, Message: message, SourceIndex: sourceIndex})
+	}
+	return warnings
+}
+
+// resourceRef returns the reference of a resource used in Warning.ResourceRef
+func resourceRef(group, version, kind, namespace, name string) string {
+	parts := []string{group, version, kind, namespace, name}
+	if group == "" {
+		parts = parts[1:]
+	}
+	if namespace == "" {
+		parts = append(parts[:len(parts)-2], name)
+	}
+	return strings.Join(parts, "/")
+}
+
+// objectRef returns the reference of an object used in Warning.ResourceRef
+func objectRef(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	return resourceRef(gvk.Group, gvk.Version, gvk.Kind, obj.GetNamespace(), obj.GetName())
+}
+
+// duplicateRef returns the reference of the duplicate resource a deduplication condition
+// message reports, or an empty string if it reports none of them
+func duplicateRef(message string, duplicates []DuplicateResource) string {
+	for _, duplicate := range duplicates {
+		key := kubeutil.ResourceKey{Group: duplicate.Group, Kind: duplicate.Kind, Namespace: duplicate.Namespace, Name: duplicate.Name}
+		if strings.HasPrefix(message, "Resource "+key.String()+" ") {
+			return resourceRef(duplicate.Group, duplicate.Version, duplicate.Kind, duplicate.Namespace, duplicate.Name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultWatchDebounce is how long Watch waits for further changes before rendering again
+const DefaultWatchDebounce = 300 * time.Millisecond
+
+// WatchOptions configures Watch
+type WatchOptions struct {
+	// Debounce is how long to wait after a change for further changes. Zero uses DefaultWatchDebounce.
+	Debounce time.Duration
+}
+
+// Watch renders the Application and renders it again whenever a file in the repository or the
+// Application file changes. Each render is written to w, separated by a comment with the time
+// it was rendered at. Render errors are written to w as a comment and do not stop watching.
+// Watch returns when ctx is cancelled.
+func Watch(ctx context.Context, opts TemplateOptions, watchOpts WatchOptions, w io.Writer) error {
+	debounce := watchOpts.Debounce
+	if debounce == 0 {
+		debounce = DefaultWatchDebounce
+	}
+
+	if opts.RepoRoot == "" {
+		var err error
+		opts.RepoRoot, err = discoverRepoRoot(opts.ApplicationFile)
+		if err != nil {
+			return err
+		}
+	}
+	repoRoot := opts.RepoRoot
+	// Stdin can only be read once, so the same Application is rendered on every change
+	if opts.ApplicationFile == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read application from stdin: %w", err)
+		}
+		opts.applicationData = data
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watchDirectoryTree(watcher, repoRoot); err != nil {
+		return err
+	}
+	if opts.ApplicationFile != "" && opts.ApplicationFile != "-" {
+		if err := watcher.Add(filepath.Dir(opts.ApplicationFile)); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", opts.ApplicationFile, err)
+		}
+	}
+
+	renderTo(ctx, opts, w)
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if ignoreWatchEvent(event) {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := watchDirectoryTree(watcher, event.Name); err != nil {
+						fmt.Fprintf(w, "# Error: %v\n", err)
+					}
+				}
+			}
+			timer.Reset(debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(w, "# Error: %v\n", err)
+		case <-timer.C:
+			renderTo(ctx, opts, w)
+		}
+	}
+}
+
+// watchDirectoryTree adds the directory and all its subdirectories to the watcher
+func watchDirectoryTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && ignoredWatchPath(path) {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// ignoreWatchEvent reports whether the event does not require rendering again
+func ignoreWatchEvent(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+		return true
+	}
+	return ignoredWatchPath(event.Name)
+}
+
+// ignoredWatchPath reports whether changes to the path are ignored, like the .git directory
+// and the temporary Kustomize overlays created while rendering
+func ignoredWatchPath(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if part == ".git" || strings.HasPrefix(part, "kustomize-overlay-") {
+			return true
+		}
+	}
+	return false
+}
+
+// renderTo renders the Application and writes the manifests or the error to w
+func renderTo(ctx context.Context, opts TemplateOptions, w io.Writer) {
+	fmt.Fprintf(w, "--- # Rendered at %s\n", time.Now().Format(time.RFC3339))
+
+	result, err := TemplateFromApplication(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(w, "# Error: %v\n", err)
+		return
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(w, "# Warning: %s\n", warning.Message)
+	}
+	if err := writeManifests(w, result.Objects); err != nil {
+		fmt.Fprintf(w, "# Error: %v\n", err)
+	}
+}
+
+// writeManifests writes the objects as a multi-document YAML stream
+func writeManifests(w io.Writer, objects []*unstructured.Unstructured) error {
+	for i, obj := range objects {
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package renderer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatch(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoRoot, "app"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	writeConfigMap := func(name string) {
+		manifest := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + name + "\n"
+		if err := os.WriteFile(filepath.Join(repoRoot, "app", name+".yaml"), []byte(manifest), 0644); err != nil {
+			t.Fatalf("Failed to write manifest: %v", err)
+		}
+	}
+	writeConfigMap("first")
+
+	application := `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: watch-app
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: app
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`
+	if err := os.WriteFile(filepath.Join(repoRoot, "application.yaml"), []byte(application), 0644); err != nil {
+		t.Fatalf("Failed to write application: %v", err)
+	}
+	t.Chdir(repoRoot)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var output syncBuffer
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, TemplateOptions{ApplicationFile: "application.yaml", RepoRoot: "."}, WatchOptions{Debounce: 50 * time.Millisecond}, &output)
+	}()
+
+	waitFor := func(condition func(string) bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !condition(output.String()) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for render, output:\n%s", output.String())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitFor(func(s string) bool { return strings.Contains(s, "name: first") })
+	writeConfigMap("second")
+	waitFor(func(s string) bool { return strings.Contains(s, "name: second") })
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch returned error: %v", err)
+	}
+
+	if renders := strings.Count(output.String(), "--- # Rendered at "); renders < 2 {
+		t.Errorf("Expected at least 2 renders, got %d", renders)
+	}
+}
+
+func TestWatchStdin(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoRoot, "app"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	writeConfigMap := func(name string) {
+		manifest := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + name + "\n"
+		if err := os.WriteFile(filepath.Join(repoRoot, "app", name+".yaml"), []byte(manifest), 0644); err != nil {
+			t.Fatalf("Failed to write manifest: %v", err)
+		}
+	}
+	writeConfigMap("first")
+
+	application := `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: watch-app
+spec:
+  project: default
+  source:
+    repoURL: https://github.com/myorg/myrepo
+    path: app
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: default
+`
+	stdinFile := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(stdinFile, []byte(application), 0644); err != nil {
+		t.Fatalf("Failed to write application: %v", err)
+	}
+	stdin, err := os.Open(stdinFile)
+	if err != nil {
+		t.Fatalf("Failed to open application: %v", err)
+	}
+	defer stdin.Close()
+	originalStdin := os.Stdin
+	os.Stdin = stdin
+	t.Cleanup(func() { os.Stdin = originalStdin })
+	t.Chdir(repoRoot)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var output syncBuffer
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, TemplateOptions{ApplicationFile: "-", RepoRoot: "."}, WatchOptions{Debounce: 50 * time.Millisecond}, &output)
+	}()
+
+	waitFor := func(condition func(string) bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !condition(output.String()) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for render, output:\n%s", output.String())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitFor(func(s string) bool { return strings.Contains(s, "name: first") })
+	// The second render would fail if the Application were read from the consumed stdin again
+	writeConfigMap("second")
+	waitFor(func(s string) bool { return strings.Contains(s, "name: second") })
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch returned error: %v", err)
+	}
+	if strings.Contains(output.String(), "# Error:") {
+		t.Errorf("Expected every render to succeed, got:\n%s", output.String())
+	}
+}